@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 	"log"
-	"time"
 
 	"github.com/pappi/ctrader-go/pkg/ctrader"
 )
@@ -22,10 +21,16 @@ func main() {
 		Username:     "YOUR_USERNAME",  // Replace with your actual username
 		Password:     "YOUR_PASSWORD",  // Replace with your actual password
 		HeartBeat:    30,
+		ResetSeqNum:  true, // every logon starts both sequences at 1
 	}
 
 	// Create client with SSL/TLS encryption
-	client := ctrader.NewClient("demo-uk-eqx-01.p.c-trader.com", 5212, config, ctrader.WithSSL(true)) // FIXED: Port 5212 for TRADE
+	// and heartbeats and test request replies sent automatically
+	client := ctrader.NewClient("demo-uk-eqx-01.p.c-trader.com", 5212, config, // FIXED: Port 5212 for TRADE
+		ctrader.WithSSL(true),
+		ctrader.WithAutoHeartbeat(true),
+		ctrader.WithAutoTestRequestReply(true),
+	)
 
 	// Set callbacks
 	client.SetConnectedCallback(func() {
@@ -33,8 +38,6 @@ func main() {
 		
 		// Send logon message
 		logonMsg := ctrader.NewLogonRequest(config)
-		logonMsg.ResetSeqNum = true
-		
 		if err := client.Send(logonMsg); err != nil {
 			log.Printf("Failed to send logon: %v", err)
 		} else {
@@ -64,12 +67,7 @@ func main() {
 			
 		case "1": // Test Request
 			testReqID := message.GetFieldValue(112)
-			fmt.Printf("Test request received: %v\n", testReqID)
-			
-			// Respond with heartbeat
-			heartbeat := ctrader.NewHeartbeat(config)
-			heartbeat.TestReqID = fmt.Sprintf("%v", testReqID)
-			client.Send(heartbeat)
+			fmt.Printf("Test request received: %v (answered automatically)\n", testReqID)
 			
 		default:
 			fmt.Printf("Unhandled message type: %s\n", message.GetMessageType())
@@ -82,16 +80,7 @@ func main() {
 		log.Fatalf("Failed to connect: %v", err)
 	}
 
-	// Listen for messages and errors
-	go func() {
-		for message := range client.Messages() {
-			protocol := ctrader.NewProtocol("\x01")
-			fmt.Println("=== Received Message ===")
-			fmt.Print(protocol.FormatMessage(message.GetMessage()))
-			fmt.Println("========================")
-		}
-	}()
-
+	// Messages arrive on the callback; listen for errors
 	go func() {
 		for err := range client.Errors() {
 			log.Printf("Error: %v", err)
@@ -101,19 +90,5 @@ func main() {
 	// Keep the application running
 	fmt.Println("Client is running. Press Ctrl+C to stop.")
 	
-	// Send periodic heartbeats if needed
-	ticker := time.NewTicker(25 * time.Second)
-	defer ticker.Stop()
-	
-	for {
-		select {
-		case <-ticker.C:
-			if client.IsConnected() {
-				heartbeat := ctrader.NewHeartbeat(config)
-				if err := client.Send(heartbeat); err != nil {
-					log.Printf("Failed to send heartbeat: %v", err)
-				}
-			}
-		}
-	}
+	<-client.Done()
 }
@@ -23,6 +23,7 @@ func main() {
 		Username:     os.Getenv("CTRADER_USERNAME"),
 		Password:     os.Getenv("CTRADER_PASSWORD"),
 		HeartBeat:    30,
+		ResetSeqNum:  true, // every logon starts both sequences at 1
 	}
 
 	fmt.Printf("Configuration:\n")
@@ -37,8 +38,6 @@ func main() {
 		fmt.Println("✅ Connected to cTrader server")
 		
 		logonMsg := ctrader.NewLogonRequest(config)
-		logonMsg.ResetSeqNum = true
-		
 		if err := client.Send(logonMsg); err != nil {
 			log.Printf("❌ Failed to send logon: %v", err)
 		} else {
@@ -66,8 +65,7 @@ func main() {
 			fmt.Printf("🧪 Test request: %v\n", testReqID)
 			
 			// Respond with heartbeat
-			heartbeat := ctrader.NewHeartbeat(config)
-			heartbeat.TestReqID = fmt.Sprintf("%v", testReqID)
+			heartbeat := ctrader.NewTestRequestReply(config, message)
 			if err := client.Send(heartbeat); err != nil {
 				fmt.Printf("❌ Failed to send heartbeat: %v\n", err)
 			} else {
@@ -14,25 +14,16 @@ func main() {
 	fmt.Println("=================================")
 	
 	// Configuration for QUOTE session only
-	config := &ctrader.Config{
-		BeginString:  "FIX.4.4",
-		SenderCompID: os.Getenv("SENDER_COMP_ID"),
-		TargetCompID: "cServer",
-		TargetSubID:  "QUOTE",
-		SenderSubID:  "QUOTE",
-		Username:     os.Getenv("CTRADER_USERNAME"),
-		Password:     os.Getenv("CTRADER_PASSWORD"),
-		HeartBeat:    30,
-	}
+	config := ctrader.NewDemoConfig(os.Getenv("SENDER_COMP_ID"), os.Getenv("CTRADER_USERNAME"), os.Getenv("CTRADER_PASSWORD"))
+	config.ResetSeqNum = true // every logon starts both sequences at 1
 
-	client := ctrader.NewClient("demo-uk-eqx-01.p.c-trader.com", 5211, config, ctrader.WithSSL(true))
+	client := ctrader.NewQuoteClient(ctrader.DemoHost, config)
+	config = client.Config() // carries the QUOTE sub-IDs
 
 	client.SetConnectedCallback(func() {
 		fmt.Println("✅ Connected to cTrader QUOTE server")
 		
 		logonMsg := ctrader.NewLogonRequest(config)
-		logonMsg.ResetSeqNum = true
-		
 		if err := client.Send(logonMsg); err != nil {
 			log.Printf("❌ Failed to send logon: %v", err)
 		} else {
@@ -52,11 +43,13 @@ func main() {
 		case "A": // Logon
 			fmt.Println("✅ Logon successful!")
 			
-			// Wait a moment then request market data
-			go func() {
-				time.Sleep(1 * time.Second)
-				requestMarketData(client, config)
-			}()
+			// Learn the symbol IDs before requesting market data
+			if err := client.RequestAllSymbols(); err != nil {
+				fmt.Printf("❌ Failed to request symbols: %v\n", err)
+			}
+			
+		case "y": // Security List
+			requestMarketData(client, config)
 			
 		case "0": // Heartbeat
 			fmt.Println("💓 Heartbeat received")
@@ -66,8 +59,7 @@ func main() {
 			fmt.Printf("🧪 Test request: %v\n", testReqID)
 			
 			// Respond with heartbeat
-			heartbeat := ctrader.NewHeartbeat(config)
-			heartbeat.TestReqID = fmt.Sprintf("%v", testReqID)
+			heartbeat := ctrader.NewTestRequestReply(config, message)
 			if err := client.Send(heartbeat); err != nil {
 				fmt.Printf("❌ Failed to send heartbeat: %v\n", err)
 			} else {
@@ -76,9 +68,9 @@ func main() {
 			
 		case "W": // Market Data
 			fmt.Println("📊 Market data received")
-			bid := message.GetFieldValue(126)
-			ask := message.GetFieldValue(127)
-			fmt.Printf("   Bid: %v, Ask: %v\n", bid, ask)
+			if bid, ask, ok := message.BestBidAsk(); ok {
+				fmt.Printf("   Bid: %.5f, Ask: %.5f\n", bid, ask)
+			}
 		}
 	})
 
@@ -87,14 +79,7 @@ func main() {
 		log.Fatalf("❌ Failed to connect: %v", err)
 	}
 
-	// Handle messages and errors
-	go func() {
-		for message := range client.Messages() {
-			// Messages handled by callback
-			_ = message
-		}
-	}()
-
+	// Messages arrive on the callback; handle errors
 	go func() {
 		for err := range client.Errors() {
 			fmt.Printf("❌ Error: %v\n", err)
@@ -120,16 +105,19 @@ func main() {
 }
 
 func requestMarketData(client *ctrader.Client, config *ctrader.Config) {
-	fmt.Println("📊 Requesting EURUSD market data...")
+	symbolID, ok := client.ResolveSymbolID("EURUSD")
+	if !ok {
+		fmt.Println("❌ EURUSD not found in the security list")
+		return
+	}
+	fmt.Printf("📊 Requesting EURUSD market data (SymbolID: %s)...\n", symbolID)
 	
 	mdReq := ctrader.NewMarketDataRequest(config)
 	mdReq.MDReqID = "MD_REQ_EURUSD"
 	mdReq.SubscriptionRequestType = "1" // Snapshot + Updates
 	mdReq.MarketDepth = 0
-	mdReq.NoMDEntryTypes = 2
-	mdReq.MDEntryType = "0"  // Bid
-	mdReq.NoRelatedSym = 1
-	mdReq.Symbol = "1" // EURUSD symbol ID
+	mdReq.MDEntryTypes = []string{"0", "1"} // Bid and Ask
+	mdReq.Symbol = symbolID
 	
 	if err := client.Send(mdReq); err != nil {
 		fmt.Printf("❌ Failed to request market data: %v\n", err)
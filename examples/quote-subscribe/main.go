@@ -18,8 +18,6 @@ import (
 // Common forex symbols: EURUSD, GBPUSD, USDJPY, AUDUSD
 // Crypto symbols may not be available on demo servers
 
-var messageSequence int = 1
-
 func main() {
 	fmt.Println("📊 cTrader Quote & Market Data Subscription Example")
 	fmt.Println("====================================================")
@@ -34,24 +32,17 @@ func main() {
 		Username:     os.Getenv("CTRADER_USERNAME"),
 		Password:     os.Getenv("CTRADER_PASSWORD"),
 		HeartBeat:    30,
+		ResetSeqNum:  true, // every logon starts both sequences at 1
 	}
 
 	var securityID string // Store the security ID we get from the server
 
-	client := ctrader.NewClient("demo-uk-eqx-01.p.c-trader.com", 5211, config, ctrader.WithSSL(true))
+	client := ctrader.NewClient("demo-uk-eqx-01.p.c-trader.com", 5211, config, ctrader.WithSSL(true), ctrader.WithLogger(ctrader.NewStdoutLogger("")))
 
 	client.SetConnectedCallback(func() {
 		fmt.Println("✅ Connected to QUOTE server")
 		
 		logonMsg := ctrader.NewLogonRequest(config)
-		logonMsg.ResetSeqNum = true
-		
-		// Log the raw logon message being sent
-		protocol := ctrader.NewProtocol("\x01")
-		rawLogon := protocol.FormatMessage(logonMsg.GetMessage(messageSequence))
-		fmt.Printf("🔤 SENDING Logon Message (Seq: %d):\n%s\n", messageSequence, rawLogon)
-		messageSequence++
-		
 		if err := client.Send(logonMsg); err != nil {
 			log.Printf("❌ Failed to send logon: %v", err)
 		} else {
@@ -67,11 +58,6 @@ func main() {
 		msgType := message.GetMessageType()
 		fmt.Printf("📨 Quote message: %s\n", msgType)
 		
-		// Log raw FIX message for all responses
-		protocol := ctrader.NewProtocol("\x01")
-		rawMessage := protocol.FormatMessage(message.GetMessage())
-		fmt.Printf("🔤 RECEIVED Raw FIX Message:\n%s\n", rawMessage)
-		
 		switch msgType {
 		case "A": // Logon
 			fmt.Println("✅ Quote logon successful!")
@@ -90,8 +76,7 @@ func main() {
 			fmt.Printf("🧪 Test request: %v\n", testReqID)
 			
 			// Respond with heartbeat
-			heartbeat := ctrader.NewHeartbeat(config)
-			heartbeat.TestReqID = fmt.Sprintf("%v", testReqID)
+			heartbeat := ctrader.NewTestRequestReply(config, message)
 			if err := client.Send(heartbeat); err != nil {
 				fmt.Printf("❌ Failed to send heartbeat: %v\n", err)
 			} else {
@@ -147,12 +132,6 @@ func requestSecurityList(client *ctrader.Client, config *ctrader.Config) {
 	securityReq.SecurityListRequestType = "0" // Symbol
 	securityReq.Symbol = "EURUSD" // Request by symbol name
 	
-	// Log the raw FIX message being sent
-	protocol := ctrader.NewProtocol("\x01")
-	rawMessage := protocol.FormatMessage(securityReq.GetMessage(messageSequence))
-	fmt.Printf("🔤 SENDING Security List Request (Seq: %d):\n%s\n", messageSequence, rawMessage)
-	messageSequence++
-	
 	if err := client.Send(securityReq); err != nil {
 		fmt.Printf("❌ Failed to send security list: %v\n", err)
 	} else {
@@ -161,24 +140,21 @@ func requestSecurityList(client *ctrader.Client, config *ctrader.Config) {
 }
 
 func handleSecurityListResponse(message *ctrader.ResponseMessage) string {
-	securityReqID := message.GetFieldValue(320)
-	symbol := message.GetFieldValue(55)
-	securityID := message.GetFieldValue(48)
+	securityReqID := message.GetFirst(320)
+	securities := ctrader.ParseSecurityList(message)
 	
 	fmt.Printf("📋 Security List Response:\n")
 	fmt.Printf("   RequestID: %v\n", securityReqID)
-	fmt.Printf("   Symbol: %v\n", symbol)
-	fmt.Printf("   SecurityID: %v\n", securityID)
+	fmt.Printf("   Securities: %d\n", len(securities))
 	
-	// Convert securityID to string if it's not already
-	var secID string
-	if securityID != nil {
-		secID = fmt.Sprintf("%v", securityID)
-		fmt.Printf("✅ Found EURUSD SecurityID: %s\n", secID)
-		return secID
+	for _, security := range securities {
+		if security.SymbolName == "EURUSD" {
+			fmt.Printf("✅ Found EURUSD SymbolID: %s\n", security.SymbolID)
+			return security.SymbolID
+		}
 	}
 	
-	fmt.Println("❌ Could not find EURUSD SecurityID")
+	fmt.Println("❌ Could not find EURUSD SymbolID")
 	return ""
 }
 
@@ -189,10 +165,7 @@ func subscribeToMarketData(client *ctrader.Client, config *ctrader.Config, secur
 	mdReq.MDReqID = "MD_EURUSD_001"
 	mdReq.SubscriptionRequestType = "1" // Snapshot + Updates
 	mdReq.MarketDepth = 0
-	mdReq.NoMDEntryTypes = 2 // Bid and Ask
-	mdReq.MDEntryType = "0"  // Bid
-	mdReq.MDEntryType = "1"  // Ask
-	mdReq.NoRelatedSym = 1
+	mdReq.MDEntryTypes = []string{"0", "1"} // Bid and Ask
 	mdReq.Symbol = securityID // Use the security ID from the server
 	
 	if err := client.Send(mdReq); err != nil {
@@ -205,11 +178,11 @@ func subscribeToMarketData(client *ctrader.Client, config *ctrader.Config, secur
 func handleMarketData(message *ctrader.ResponseMessage) {
 	mdReqID := message.GetFieldValue(262)
 	
-	if bid := message.GetFieldValue(126); bid != nil {
-		fmt.Printf("📈 EURUSD [%v] Bid: %v\n", mdReqID, bid)
+	bid, ask, ok := message.BestBidAsk()
+	if !ok {
+		return
 	}
 	
-	if ask := message.GetFieldValue(127); ask != nil {
-		fmt.Printf("📉 EURUSD [%v] Ask: %v\n", mdReqID, ask)
-	}
+	fmt.Printf("📈 EURUSD [%v] Bid: %.5f\n", mdReqID, bid)
+	fmt.Printf("📉 EURUSD [%v] Ask: %.5f\n", mdReqID, ask)
 }
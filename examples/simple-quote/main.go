@@ -23,6 +23,7 @@ func main() {
 		Username:     os.Getenv("CTRADER_USERNAME"),
 		Password:     os.Getenv("CTRADER_PASSWORD"),
 		HeartBeat:    30,
+		ResetSeqNum:  true, // every logon starts both sequences at 1
 	}
 
 	client := ctrader.NewClient("demo-uk-eqx-01.p.c-trader.com", 5211, config, ctrader.WithSSL(true))
@@ -31,8 +32,6 @@ func main() {
 		fmt.Println("✅ Connected to QUOTE server")
 		
 		logonMsg := ctrader.NewLogonRequest(config)
-		logonMsg.ResetSeqNum = true
-		
 		if err := client.Send(logonMsg); err != nil {
 			log.Printf("❌ Failed to send logon: %v", err)
 		} else {
@@ -52,11 +51,13 @@ func main() {
 		case "A": // Logon
 			fmt.Println("✅ Quote logon successful!")
 			
-			// Subscribe to market data after logon
-			go func() {
-				time.Sleep(2 * time.Second)
-				subscribeToMarketData(client, config)
-			}()
+			// Learn the symbol IDs, then subscribe once the list arrives
+			if err := client.RequestAllSymbols(); err != nil {
+				fmt.Printf("❌ Failed to request symbols: %v\n", err)
+			}
+			
+		case "y": // Security List
+			subscribeToMarketData(client, config)
 			
 		case "0": // Heartbeat
 			fmt.Println("💓 Heartbeat received")
@@ -66,8 +67,7 @@ func main() {
 			fmt.Printf("🧪 Test request: %v\n", testReqID)
 			
 			// Respond with heartbeat
-			heartbeat := ctrader.NewHeartbeat(config)
-			heartbeat.TestReqID = fmt.Sprintf("%v", testReqID)
+			heartbeat := ctrader.NewTestRequestReply(config, message)
 			if err := client.Send(heartbeat); err != nil {
 				fmt.Printf("❌ Failed to send heartbeat: %v\n", err)
 			} else {
@@ -105,14 +105,13 @@ func main() {
 }
 
 func subscribeToMarketData(client *ctrader.Client, config *ctrader.Config) {
-	// Known symbol IDs for cTrader demo:
-	// "1" = EURUSD
-	// "2" = GBPUSD  
-	// "3" = USDJPY
 	// Note: Crypto symbols like BTCUSD may not be available on demo
-	
-	symbolID := "1" // EURUSD
 	symbolName := "EURUSD"
+	symbolID, ok := client.ResolveSymbolID(symbolName)
+	if !ok {
+		fmt.Printf("❌ %s not found in the security list\n", symbolName)
+		return
+	}
 	
 	fmt.Printf("📊 Subscribing to %s market data with SymbolID: %s\n", symbolName, symbolID)
 	
@@ -120,11 +119,8 @@ func subscribeToMarketData(client *ctrader.Client, config *ctrader.Config) {
 	mdReq.MDReqID = "MD_" + symbolName + "_001"
 	mdReq.SubscriptionRequestType = "1" // Snapshot + Updates
 	mdReq.MarketDepth = 0
-	mdReq.NoMDEntryTypes = 2 // Bid and Ask
-	mdReq.MDEntryType = "0"  // Bid
-	mdReq.MDEntryType = "1"  // Ask
-	mdReq.NoRelatedSym = 1
-	mdReq.Symbol = symbolID // Use the known symbol ID
+	mdReq.MDEntryTypes = []string{"0", "1"} // Bid and Ask
+	mdReq.Symbol = symbolID // Resolved from the security list
 	
 	if err := client.Send(mdReq); err != nil {
 		fmt.Printf("❌ Failed to subscribe: %v\n", err)
@@ -136,19 +132,12 @@ func subscribeToMarketData(client *ctrader.Client, config *ctrader.Config) {
 func handleMarketData(message *ctrader.ResponseMessage) {
 	mdReqID := message.GetFieldValue(262)
 	
-	if bid := message.GetFieldValue(126); bid != nil {
-		fmt.Printf("📈 EURUSD [%v] Bid: %v\n", mdReqID, bid)
-	}
-	
-	if ask := message.GetFieldValue(127); ask != nil {
-		fmt.Printf("📉 EURUSD [%v] Ask: %v\n", mdReqID, ask)
+	bid, ask, ok := message.BestBidAsk()
+	if !ok {
+		return
 	}
 	
-	// Show spread if both bid and ask are available
-	bid := message.GetFieldValue(126)
-	ask := message.GetFieldValue(127)
-	if bid != nil && ask != nil {
-		spread := ask.(float64) - bid.(float64)
-		fmt.Printf("📊 EURUSD Spread: %.5f\n", spread)
-	}
+	fmt.Printf("📈 EURUSD [%v] Bid: %.5f\n", mdReqID, bid)
+	fmt.Printf("📉 EURUSD [%v] Ask: %.5f\n", mdReqID, ask)
+	fmt.Printf("📊 EURUSD Spread: %.5f\n", ask-bid)
 }
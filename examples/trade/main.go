@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -23,6 +24,7 @@ func main() {
 		Username:     os.Getenv("CTRADER_USERNAME"),
 		Password:     os.Getenv("CTRADER_PASSWORD"),
 		HeartBeat:    30,
+		ResetSeqNum:  true, // every logon starts both sequences at 1
 	}
 
 	client := ctrader.NewClient("demo-uk-eqx-01.p.c-trader.com", 5212, config, ctrader.WithSSL(true))
@@ -31,8 +33,6 @@ func main() {
 		fmt.Println("✅ Connected to TRADE server")
 		
 		logonMsg := ctrader.NewLogonRequest(config)
-		logonMsg.ResetSeqNum = true
-		
 		if err := client.Send(logonMsg); err != nil {
 			log.Printf("❌ Failed to send logon: %v", err)
 		} else {
@@ -66,8 +66,7 @@ func main() {
 			fmt.Printf("🧪 Test request: %v\n", testReqID)
 			
 			// Respond with heartbeat
-			heartbeat := ctrader.NewHeartbeat(config)
-			heartbeat.TestReqID = fmt.Sprintf("%v", testReqID)
+			heartbeat := ctrader.NewTestRequestReply(config, message)
 			if err := client.Send(heartbeat); err != nil {
 				fmt.Printf("❌ Failed to send heartbeat: %v\n", err)
 			} else {
@@ -80,9 +79,6 @@ func main() {
 		case "3": // Order Reject
 			handleOrderReject(message)
 			
-		case "AO": // Position Report
-			handlePositionReport(message)
-			
 		case "AP": // Trade Capture Report
 			handleTradeCaptureReport(message)
 		}
@@ -117,25 +113,27 @@ func startTradeOperations(client *ctrader.Client, config *ctrader.Config) {
 	fmt.Println("🚀 Starting trade operations...")
 	
 	// 1. Request positions
-	requestPositions(client, config)
+	requestPositions(client)
 	
-	// 2. Place a test order (small size)
-	go func() {
-		time.Sleep(3 * time.Second)
-		placeTestOrder(client, config)
-	}()
+	// 2. Place a test order (small size) once the positions have arrived
+	placeTestOrder(client, config)
 }
 
-func requestPositions(client *ctrader.Client, config *ctrader.Config) {
+func requestPositions(client *ctrader.Client) {
 	fmt.Println("📋 Requesting open positions...")
 	
-	posReq := ctrader.NewRequestForPositions(config)
-	posReq.PosReqID = "POS_REQ_001"
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 	
-	if err := client.Send(posReq); err != nil {
+	// Returns once the last position report has arrived
+	reports, err := client.RequestPositions(ctx)
+	if err != nil {
 		fmt.Printf("❌ Failed to request positions: %v\n", err)
-	} else {
-		fmt.Println("✅ Positions request sent")
+		return
+	}
+	fmt.Printf("✅ %d open positions\n", len(reports))
+	for _, report := range reports {
+		handlePositionReport(report)
 	}
 }
 
@@ -149,11 +147,16 @@ func placeTestOrder(client *ctrader.Client, config *ctrader.Config) {
 	order.OrderQty = 0.001 // Micro lot (1000 units)
 	order.OrdType = "1"   // Market order
 	
-	if err := client.Send(order); err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	
+	// ClOrdID (11) links the execution report to the order
+	report, err := client.Request(ctx, order, 11)
+	if err != nil {
 		fmt.Printf("❌ Failed to place order: %v\n", err)
-	} else {
-		fmt.Println("✅ Test BUY order sent")
+		return
 	}
+	handleExecutionReport(report)
 }
 
 func handleExecutionReport(message *ctrader.ResponseMessage) {
@@ -175,6 +178,10 @@ func handleExecutionReport(message *ctrader.ResponseMessage) {
 	if avgPx := message.GetFieldValue(6); avgPx != nil {
 		fmt.Printf("   Avg Price: %v\n", avgPx)
 	}
+	
+	if reason, ok := message.GetString(103); ok { // OrdRejReason
+		fmt.Printf("   Reject Reason: %s (%s)\n", ctrader.OrdRejReasonText(reason), reason)
+	}
 }
 
 func handleOrderReject(message *ctrader.ResponseMessage) {
@@ -186,13 +193,11 @@ func handleOrderReject(message *ctrader.ResponseMessage) {
 	fmt.Printf("   Reason: %v\n", reason)
 }
 
-func handlePositionReport(message *ctrader.ResponseMessage) {
-	symbol := message.GetFieldValue(55)
-	posQty := message.GetFieldValue(703)
-	
+func handlePositionReport(report *ctrader.PositionReport) {
 	fmt.Printf("📊 Position Report:\n")
-	fmt.Printf("   Symbol: %v\n", symbol)
-	fmt.Printf("   Quantity: %v\n", posQty)
+	fmt.Printf("   Symbol: %v\n", report.Symbol)
+	fmt.Printf("   Long: %v\n", report.LongQty)
+	fmt.Printf("   Short: %v\n", report.ShortQty)
 }
 
 func handleTradeCaptureReport(message *ctrader.ResponseMessage) {
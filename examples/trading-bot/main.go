@@ -8,7 +8,6 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
-	"strings"
 	"syscall"
 	"time"
 
@@ -164,33 +163,20 @@ func calculateSMA(prices []float64, period int) float64 {
 }
 
 func NewTradingBot() *TradingBot {
-	// Configuration for QUOTE session
-	quoteConfig := &ctrader.Config{
-		BeginString:  "FIX.4.4",
-		SenderCompID: getEnv("SENDER_COMP_ID", "demo.ctrader.YOUR_ID"),
-		TargetCompID: getEnv("TARGET_COMP_ID", "cServer"),
-		TargetSubID:  "QUOTE",
-		SenderSubID:  "QUOTE",
-		Username:     getEnv("CTRADER_USERNAME", "YOUR_USERNAME"),
-		Password:     getEnv("CTRADER_PASSWORD", "YOUR_PASSWORD"),
-		HeartBeat:    30,
-	}
-
-	// Configuration for TRADE session
-	tradeConfig := &ctrader.Config{
-		BeginString:  "FIX.4.4",
-		SenderCompID: getEnv("SENDER_COMP_ID", "demo.ctrader.YOUR_ID"),
-		TargetCompID: getEnv("TARGET_COMP_ID", "cServer"),
-		TargetSubID:  "TRADE",
-		SenderSubID:  "TRADE",
-		Username:     getEnv("CTRADER_USERNAME", "YOUR_USERNAME"),
-		Password:     getEnv("CTRADER_PASSWORD", "YOUR_PASSWORD"),
-		HeartBeat:    30,
-	}
-
-	// Create separate clients
-	quoteClient := ctrader.NewClient("demo-uk-eqx-01.p.c-trader.com", 5211, quoteConfig, ctrader.WithSSL(true))
-	tradeClient := ctrader.NewClient("demo-uk-eqx-01.p.c-trader.com", 5212, tradeConfig, ctrader.WithSSL(true))
+	// One account configuration for both sessions
+	config := ctrader.NewDemoConfig(
+		getEnv("SENDER_COMP_ID", "demo.ctrader.YOUR_ID"),
+		getEnv("CTRADER_USERNAME", "YOUR_USERNAME"),
+		getEnv("CTRADER_PASSWORD", "YOUR_PASSWORD"),
+	)
+	config.TargetCompID = getEnv("TARGET_COMP_ID", "cServer")
+	config.ResetSeqNum = true // every logon starts both sequences at 1
+
+	// Create separate clients; each copies config with its session's sub-IDs.
+	// Orders are capped well below the server's throttle.
+	quoteClient := ctrader.NewQuoteClient(ctrader.DemoHost, config)
+	tradeClient := ctrader.NewTradeClient(ctrader.DemoHost, config, ctrader.WithRateLimit(5, time.Second))
+	quoteConfig := quoteClient.Config()
 
 	// Initialize strategy
 	strategy := &MAStrategy{
@@ -266,23 +252,7 @@ func (bot *TradingBot) Start() error {
 		}
 	}()
 
-	// Start message processing for both clients
-	go func() {
-		fmt.Println("🔄 Starting quote message processor...")
-		for message := range bot.quoteClient.Messages() {
-			fmt.Printf("📨 Quote channel message: %s\n", message.GetMessageType())
-			bot.processQuoteMessage(message)
-		}
-	}()
-
-	go func() {
-		fmt.Println("🔄 Starting trade message processor...")
-		for message := range bot.tradeClient.Messages() {
-			fmt.Printf("📨 Trade channel message: %s\n", message.GetMessageType())
-			bot.processTradeMessage(message)
-		}
-	}()
-
+	// Messages arrive on the callbacks set above; process errors
 	go func() {
 		fmt.Println("🔄 Starting quote error processor...")
 		for err := range bot.quoteClient.Errors() {
@@ -315,15 +285,15 @@ func (bot *TradingBot) Stop() {
 	
 	// Disconnect both clients
 	if bot.quoteClient.IsConnected() {
-		logoutMsg := ctrader.NewLogoutRequest(bot.config)
-		bot.quoteClient.Send(logoutMsg)
-		bot.quoteClient.Disconnect()
+		if err := bot.quoteClient.Logout(5 * time.Second); err != nil {
+			log.Printf("Quote logout: %v", err)
+		}
 	}
 	
 	if bot.tradeClient.IsConnected() {
-		logoutMsg := ctrader.NewLogoutRequest(bot.config)
-		bot.tradeClient.Send(logoutMsg)
-		bot.tradeClient.Disconnect()
+		if err := bot.tradeClient.Logout(5 * time.Second); err != nil {
+			log.Printf("Trade logout: %v", err)
+		}
 	}
 	
 	fmt.Println("Trading bot stopped - both QUOTE/TRADE sessions closed")
@@ -333,8 +303,6 @@ func (bot *TradingBot) onQuoteConnected() {
 	fmt.Println("✅ Connected to cTrader QUOTE server")
 	
 	logonMsg := ctrader.NewLogonRequest(bot.config)
-	logonMsg.ResetSeqNum = true
-	
 	if err := bot.quoteClient.Send(logonMsg); err != nil {
 		log.Printf("Failed to send quote logon: %v", err)
 	} else {
@@ -345,20 +313,9 @@ func (bot *TradingBot) onQuoteConnected() {
 func (bot *TradingBot) onTradeConnected() {
 	fmt.Println("✅ Connected to cTrader TRADE server")
 	
-	tradeConfig := &ctrader.Config{
-		BeginString:  "FIX.4.4",
-		SenderCompID: bot.config.SenderCompID,
-		TargetCompID: "cServer",
-		TargetSubID:  "TRADE",
-		SenderSubID:  "TRADE",
-		Username:     bot.config.Username,
-		Password:     bot.config.Password,
-		HeartBeat:    30,
-	}
+	tradeConfig := bot.tradeClient.Config()
 	
 	logonMsg := ctrader.NewLogonRequest(tradeConfig)
-	logonMsg.ResetSeqNum = true
-	
 	if err := bot.tradeClient.Send(logonMsg); err != nil {
 		log.Printf("Failed to send trade logon: %v", err)
 	} else {
@@ -392,8 +349,7 @@ func (bot *TradingBot) onQuoteMessage(message *ctrader.ResponseMessage) {
 		fmt.Printf("🧪 Quote test request: %v\n", testReqID)
 		
 		// Respond with heartbeat
-		heartbeat := ctrader.NewHeartbeat(bot.config)
-		heartbeat.TestReqID = fmt.Sprintf("%v", testReqID)
+		heartbeat := ctrader.NewTestRequestReply(bot.config, message)
 		if err := bot.quoteClient.Send(heartbeat); err != nil {
 			fmt.Printf("❌ Failed to send quote heartbeat: %v\n", err)
 		} else {
@@ -421,20 +377,10 @@ func (bot *TradingBot) onTradeMessage(message *ctrader.ResponseMessage) {
 		testReqID := message.GetFieldValue(112)
 		fmt.Printf("🧪 Trade test request: %v\n", testReqID)
 		
-		tradeConfig := &ctrader.Config{
-			BeginString:  "FIX.4.4",
-			SenderCompID: bot.config.SenderCompID,
-			TargetCompID: "cServer",
-			TargetSubID:  "TRADE",
-			SenderSubID:  "TRADE",
-			Username:     bot.config.Username,
-			Password:     bot.config.Password,
-			HeartBeat:    30,
-		}
+		tradeConfig := bot.tradeClient.Config()
 		
 		// Respond with heartbeat
-		heartbeat := ctrader.NewHeartbeat(tradeConfig)
-		heartbeat.TestReqID = fmt.Sprintf("%v", testReqID)
+		heartbeat := ctrader.NewTestRequestReply(tradeConfig, message)
 		if err := bot.tradeClient.Send(heartbeat); err != nil {
 			fmt.Printf("❌ Failed to send trade heartbeat: %v\n", err)
 		} else {
@@ -450,16 +396,6 @@ func (bot *TradingBot) onTradeMessage(message *ctrader.ResponseMessage) {
 	}
 }
 
-func (bot *TradingBot) processQuoteMessage(message *ctrader.ResponseMessage) {
-	// Call the quote message callback
-	bot.onQuoteMessage(message)
-}
-
-func (bot *TradingBot) processTradeMessage(message *ctrader.ResponseMessage) {
-	// Call the trade message callback
-	bot.onTradeMessage(message)
-}
-
 func (bot *TradingBot) requestSecurityList() {
 	fmt.Println("📋 Requesting available trading symbols...")
 	
@@ -479,17 +415,18 @@ func (bot *TradingBot) requestSecurityList() {
 func (bot *TradingBot) handleSecurityListResponse(message *ctrader.ResponseMessage) {
 	fmt.Println("=== Security List Response ===")
 	
-	securityReqID := message.GetFieldValue(320)
-	symbolID := message.GetFieldValue(55)
-	symbolDesc := message.GetFieldValue(1007)
-	
+	securityReqID, _ := message.GetString(320)
 	fmt.Printf("Security Req ID: %v\n", securityReqID)
-	fmt.Printf("Symbol ID: %v\n", symbolID)
-	fmt.Printf("Symbol Description: %v\n", symbolDesc)
 	
-	// Use EURUSD for trading (BTCUSD not available on this demo server)
-	if symbolDesc != nil && strings.Contains(strings.ToUpper(symbolDesc.(string)), "EUR") {
-		bot.symbolID = fmt.Sprintf("%v", symbolID)
+	for _, security := range ctrader.ParseSecurityList(message) {
+		fmt.Printf("Symbol ID: %v, Name: %v, Digits: %d\n", security.SymbolID, security.SymbolName, security.Digits)
+		
+		// Use EURUSD for trading (BTCUSD not available on this demo server)
+		if security.SymbolName != "EURUSD" || bot.symbolID != "" {
+			continue
+		}
+		bot.symbolID = security.SymbolID
+		bot.tradeClient.SetSecurity(security) // contract size and digits for PnL
 		fmt.Printf("✅ Using EURUSD (Symbol ID: %s)", bot.symbolID)
 		
 		// Update market data symbol for display
@@ -510,12 +447,12 @@ func (bot *TradingBot) handleSecurityListReject(message *ctrader.ResponseMessage
 func (bot *TradingBot) handleOrderReject(message *ctrader.ResponseMessage) {
 	fmt.Println("=== Order Reject Details ===")
 	
-	orderID := message.GetFieldValue(11)
-	rejectReason := message.GetFieldValue(102)
-	text := message.GetFieldValue(58)
+	orderID, _ := message.GetString(11)
+	rejectReason, _ := message.GetString(103) // OrdRejReason
+	text, _ := message.GetString(58)
 	
 	fmt.Printf("Order ID: %v\n", orderID)
-	fmt.Printf("Reject Reason: %v\n", rejectReason)
+	fmt.Printf("Reject Reason: %s (%s)\n", ctrader.OrdRejReasonText(rejectReason), rejectReason)
 	fmt.Printf("Text: %v\n", text)
 }
 
@@ -530,9 +467,7 @@ func (bot *TradingBot) requestMarketData() {
 	mdReq.MDReqID = "MD_REQ_001"
 	mdReq.SubscriptionRequestType = "1" // Snapshot + Updates
 	mdReq.MarketDepth = 0
-	mdReq.NoMDEntryTypes = 1 // Just request one type
-	mdReq.MDEntryType = "0"  // Bid
-	mdReq.NoRelatedSym = 1
+	mdReq.MDEntryTypes = []string{"0", "1"} // Bid and Ask
 	mdReq.Symbol = bot.symbolID
 	
 	if err := bot.quoteClient.Send(mdReq); err != nil {
@@ -545,16 +480,7 @@ func (bot *TradingBot) requestMarketData() {
 func (bot *TradingBot) requestPositions() {
 	fmt.Println("📋 Requesting positions...")
 	
-	tradeConfig := &ctrader.Config{
-		BeginString:  "FIX.4.4",
-		SenderCompID: bot.config.SenderCompID,
-		TargetCompID: "cServer",
-		TargetSubID:  "TRADE",
-		SenderSubID:  "TRADE",
-		Username:     bot.config.Username,
-		Password:     bot.config.Password,
-		HeartBeat:    30,
-	}
+	tradeConfig := bot.tradeClient.Config()
 	
 	posReq := ctrader.NewRequestForPositions(tradeConfig)
 	posReq.PosReqID = "POS_REQ_001"
@@ -573,12 +499,11 @@ func (bot *TradingBot) handleTestRequest(message *ctrader.ResponseMessage) {
 	fmt.Printf("Test request received: %v\n", testReqID)
 	
 	// Respond with heartbeat - this will be called from appropriate message handler
-	heartbeat := ctrader.NewHeartbeat(bot.config)
-	heartbeat.TestReqID = fmt.Sprintf("%v", testReqID)
+	heartbeat := ctrader.NewTestRequestReply(bot.config, message)
 	
 	// Send response using the appropriate client (this function is called from specific handlers)
 	// The actual send will happen in the calling function
-	fmt.Printf("Heartbeat response prepared for TestReqID: %v\n", testReqID)
+	fmt.Printf("Heartbeat response prepared for TestReqID: %s\n", heartbeat.TestReqID)
 }
 
 func (bot *TradingBot) handleSecurityList(message *ctrader.ResponseMessage) {
@@ -587,25 +512,28 @@ func (bot *TradingBot) handleSecurityList(message *ctrader.ResponseMessage) {
 }
 
 func (bot *TradingBot) handleExecutionReport(message *ctrader.ResponseMessage) {
-	orderID := message.GetFieldValue(11).(string)
-	orderStatus := message.GetFieldValue(39).(string)
-	symbol := message.GetFieldValue(55).(string)
-	side := message.GetFieldValue(54).(string)
-	orderQty := message.GetFieldValue(38).(string)
-	priceStr := message.GetFieldValue(44).(string)
+	report, err := ctrader.ParseExecutionReport(message)
+	if err != nil {
+		log.Printf("Invalid execution report: %v", err)
+		return
+	}
+	orderID, symbol, side := report.ClOrdID, report.Symbol, report.Side
+	price := report.AvgPx
 	
-	price, _ := strconv.ParseFloat(priceStr, 64)
+	fmt.Printf("📋 Execution Report - Order: %v, Status: %v, Symbol: %v, Side: %v, Qty: %v, Filled: %v @ %v\n",
+		orderID, report.OrdStatus, symbol, side, report.OrderQty, report.CumQty, price)
 	
-	fmt.Printf("📋 Execution Report - Order: %v, Status: %v, Symbol: %v, Side: %v, Qty: %v, Price: %v\n",
-		orderID, orderStatus, symbol, side, orderQty, price)
+	if report.OrdStatus == "8" { // Rejected
+		bot.handleOrderReject(message)
+	}
 	
 	// Update order status
 	if order, exists := bot.activeOrders[orderID]; exists {
-		order.Status = orderStatus
+		order.Status = report.OrdStatus
 		order.UpdateTime = time.Now()
 		
 		// If order is filled, create position
-		if orderStatus == "2" { // Filled
+		if report.OrdStatus == "2" { // Filled
 			position := &Position{
 				Symbol:     symbol,
 				Side:       side,
@@ -628,17 +556,17 @@ func (bot *TradingBot) handleExecutionReport(message *ctrader.ResponseMessage) {
 		}
 		
 		// Remove completed orders
-		if orderStatus == "2" || orderStatus == "4" || orderStatus == "8" { // Filled, Canceled, Rejected
+		if report.IsTerminal() {
 			delete(bot.activeOrders, orderID)
 		}
 	}
 }
 
 func (bot *TradingBot) handleTradeCaptureReport(message *ctrader.ResponseMessage) {
-	symbol := message.GetFieldValue(55).(string)
-	side := message.GetFieldValue(54).(string)
-	orderQty := message.GetFieldValue(32).(string)
-	priceStr := message.GetFieldValue(31).(string)
+	symbol, _ := message.GetString(55)
+	side, _ := message.GetString(54)
+	orderQty, _ := message.GetString(32)
+	priceStr, _ := message.GetString(31)
 	
 	fmt.Printf("💰 Trade Capture - Symbol: %v, Side: %v, Qty: %v, Price: %v\n",
 		symbol, side, orderQty, priceStr)
@@ -652,71 +580,62 @@ func (bot *TradingBot) handleTradeCaptureReport(message *ctrader.ResponseMessage
 }
 
 func (bot *TradingBot) handlePositionReport(message *ctrader.ResponseMessage) {
-	symbol := message.GetFieldValue(55).(string)
-	longQty := message.GetFieldValue(704).(string)
-	shortQty := message.GetFieldValue(705).(string)
+	report, err := ctrader.ParsePositionReport(message)
+	if err != nil {
+		log.Printf("Invalid position report: %v", err)
+		return
+	}
+	symbol := report.Symbol
 	
-	fmt.Printf("📊 Position Report - Symbol: %v, Long: %v, Short: %v\n",
-		symbol, longQty, shortQty)
+	fmt.Printf("📊 Position Report - Symbol: %v, Long: %v, Short: %v, Price: %v\n",
+		symbol, report.LongQty, report.ShortQty, report.SettlPrice)
 	
 	// Sync with server positions
-	if longQty != "0" {
-		if qty, err := strconv.ParseFloat(longQty, 64); err == nil && qty > 0 {
-			position := &Position{
-				Symbol:       symbol,
-				Side:         "1", // Long
-				Size:         qty,
-				EntryPrice:   bot.marketData.Bid, // Approximate
-				CurrentPrice: bot.marketData.Bid,
-				PnL:          0.0,
-				OpenTime:     time.Now(),
-			}
-			bot.openPositions[symbol+"_1"] = position
+	if report.LongQty > 0 {
+		position := &Position{
+			Symbol:       symbol,
+			Side:         "1", // Long
+			Size:         report.LongQty,
+			EntryPrice:   report.SettlPrice,
+			CurrentPrice: bot.marketData.Bid,
+			PnL:          0.0,
+			OpenTime:     time.Now(),
 		}
+		bot.openPositions[symbol+"_1"] = position
 	}
 	
-	if shortQty != "0" {
-		if qty, err := strconv.ParseFloat(shortQty, 64); err == nil && qty > 0 {
-			position := &Position{
-				Symbol:       symbol,
-				Side:         "2", // Short
-				Size:         qty,
-				EntryPrice:   bot.marketData.Ask, // Approximate
-				CurrentPrice: bot.marketData.Ask,
-				PnL:          0.0,
-				OpenTime:     time.Now(),
-			}
-			bot.openPositions[symbol+"_2"] = position
+	if report.ShortQty > 0 {
+		position := &Position{
+			Symbol:       symbol,
+			Side:         "2", // Short
+			Size:         report.ShortQty,
+			EntryPrice:   report.SettlPrice,
+			CurrentPrice: bot.marketData.Ask,
+			PnL:          0.0,
+			OpenTime:     time.Now(),
 		}
+		bot.openPositions[symbol+"_2"] = position
 	}
 }
 
 func (bot *TradingBot) handleMarketData(message *ctrader.ResponseMessage) {
 	// Process real market data from server
 	// Extract bid/ask prices from market data message
-	bidStr := message.GetFieldValue(126) // Bid price
-	askStr := message.GetFieldValue(127) // Ask price
+	bidPrice, askPrice, ok := message.BestBidAsk()
+	if !ok {
+		return
+	}
 	
-	bid, bidOk := bidStr.(string)
-	ask, askOk := askStr.(string)
+	bot.marketData.Bid = bidPrice
+	bot.marketData.Ask = askPrice
+	bot.marketData.Spread = (askPrice - bidPrice) * 10000 // Convert to pips
+	bot.marketData.LastUpdate = time.Now()
 	
-	if bidOk && askOk {
-		bidPrice, err1 := strconv.ParseFloat(bid, 64)
-		askPrice, err2 := strconv.ParseFloat(ask, 64)
-		
-		if err1 == nil && err2 == nil {
-			bot.marketData.Bid = bidPrice
-			bot.marketData.Ask = askPrice
-			bot.marketData.Spread = (askPrice - bidPrice) * 10000 // Convert to pips
-			bot.marketData.LastUpdate = time.Now()
-			
-			// Update price history
-			currentPrice := (bidPrice + askPrice) / 2
-			bot.priceHistory = append(bot.priceHistory, currentPrice)
-			if len(bot.priceHistory) > 100 {
-				bot.priceHistory = bot.priceHistory[1:]
-			}
-		}
+	// Update price history
+	currentPrice := (bidPrice + askPrice) / 2
+	bot.priceHistory = append(bot.priceHistory, currentPrice)
+	if len(bot.priceHistory) > 100 {
+		bot.priceHistory = bot.priceHistory[1:]
 	}
 }
 
@@ -742,10 +661,13 @@ func (bot *TradingBot) tradingLoop() {
 	ticker := time.NewTicker(5 * time.Second) // Check every 5 seconds
 	defer ticker.Stop()
 
-	for bot.isRunning && bot.tradeClient.IsConnected() {
+	done := bot.tradeClient.Done()
+	for bot.isRunning {
 		select {
 		case <-ticker.C:
 			bot.executeStrategy()
+		case <-done:
+			return
 		}
 	}
 }
@@ -756,6 +678,11 @@ func (bot *TradingBot) executeStrategy() {
 		return // No market data available yet
 	}
 	
+	// Don't trade on a frozen feed
+	if _, _, age, ok := bot.quoteClient.BestPrices(bot.symbolID); !ok || age > 30*time.Second {
+		return
+	}
+	
 	// Check if we have enough price history for strategy
 	if len(bot.priceHistory) < bot.strategy.(*MAStrategy).LongPeriod {
 		return // Not enough data for strategy calculations
@@ -790,11 +717,14 @@ func (bot *TradingBot) riskManagementLoop() {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
-	for bot.isRunning && bot.tradeClient.IsConnected() {
+	done := bot.tradeClient.Done()
+	for bot.isRunning {
 		select {
 		case <-ticker.C:
 			bot.checkRiskLimits()
 			bot.updateEquity()
+		case <-done:
+			return
 		}
 	}
 }
@@ -803,10 +733,13 @@ func (bot *TradingBot) marketDataLoop() {
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 
-	for bot.isRunning && bot.quoteClient.IsConnected() {
+	done := bot.quoteClient.Done()
+	for bot.isRunning {
 		select {
 		case <-ticker.C:
 			bot.displayMarketStatus()
+		case <-done:
+			return
 		}
 	}
 }
@@ -841,16 +774,7 @@ func (bot *TradingBot) openLongPosition() {
 	bot.orderID++
 	clOrdID := fmt.Sprintf("LONG_%d", bot.orderID)
 	
-	tradeConfig := &ctrader.Config{
-		BeginString:  "FIX.4.4",
-		SenderCompID: bot.config.SenderCompID,
-		TargetCompID: "cServer",
-		TargetSubID:  "TRADE",
-		SenderSubID:  "TRADE",
-		Username:     bot.config.Username,
-		Password:     bot.config.Password,
-		HeartBeat:    30,
-	}
+	tradeConfig := bot.tradeClient.Config()
 	
 	order := ctrader.NewOrderMsg(tradeConfig)
 	order.ClOrdID = clOrdID
@@ -898,16 +822,7 @@ func (bot *TradingBot) openShortPosition() {
 	bot.orderID++
 	clOrdID := fmt.Sprintf("SHORT_%d", bot.orderID)
 	
-	tradeConfig := &ctrader.Config{
-		BeginString:  "FIX.4.4",
-		SenderCompID: bot.config.SenderCompID,
-		TargetCompID: "cServer",
-		TargetSubID:  "TRADE",
-		SenderSubID:  "TRADE",
-		Username:     bot.config.Username,
-		Password:     bot.config.Password,
-		HeartBeat:    30,
-	}
+	tradeConfig := bot.tradeClient.Config()
 	
 	order := ctrader.NewOrderMsg(tradeConfig)
 	order.ClOrdID = clOrdID
@@ -951,16 +866,7 @@ func (bot *TradingBot) closePosition(position *Position) {
 		price = bot.marketData.Ask
 	}
 	
-	tradeConfig := &ctrader.Config{
-		BeginString:  "FIX.4.4",
-		SenderCompID: bot.config.SenderCompID,
-		TargetCompID: "cServer",
-		TargetSubID:  "TRADE",
-		SenderSubID:  "TRADE",
-		Username:     bot.config.Username,
-		Password:     bot.config.Password,
-		HeartBeat:    30,
-	}
+	tradeConfig := bot.tradeClient.Config()
 	
 	order := ctrader.NewOrderMsg(tradeConfig)
 	order.ClOrdID = clOrdID
@@ -1003,14 +909,18 @@ func (bot *TradingBot) checkRiskLimits() {
 }
 
 func (bot *TradingBot) updateEquity() {
+	// Contract size and digits come from the security list; standard
+	// forex lots until it has arrived
+	security, known := bot.tradeClient.Security(bot.symbolID)
+	if !known || security.ContractSize == 0 {
+		security.ContractSize = 100000
+	}
+	
 	// Calculate unrealized PnL
 	unrealizedPnL := 0.0
 	for _, position := range bot.openPositions {
-		if position.Side == "1" { // Long
-			position.PnL = (bot.marketData.Bid - position.EntryPrice) * position.Size * 100000 // Assuming standard lot
-		} else { // Short
-			position.PnL = (position.EntryPrice - bot.marketData.Ask) * position.Size * 100000
-		}
+		open := ctrader.Position{Symbol: position.Symbol, Side: position.Side, Quantity: position.Size, EntryPrice: position.EntryPrice}
+		position.PnL = open.SecurityPnL(bot.marketData.Bid, bot.marketData.Ask, security)
 		unrealizedPnL += position.PnL
 	}
 	
@@ -0,0 +1,137 @@
+package ctrader
+
+import "time"
+
+// OrderBuilder constructs an OrderMsg fluently:
+//
+//	order, err := ctrader.NewOrder(config).
+//		Symbol("1").
+//		Buy().
+//		Quantity(1000).
+//		Limit(1.10500).
+//		TimeInForce(ctrader.TimeInForceGTC).
+//		StopLoss(1.10000).
+//		Build()
+//
+// Orders are market orders unless Limit, Stop or StopLimit is called.
+type OrderBuilder struct {
+	order *OrderMsg
+}
+
+// NewOrder starts a market order with config's defaults.
+func NewOrder(config *Config) *OrderBuilder {
+	order := NewOrderMsg(config)
+	order.OrdType = "1"
+	return &OrderBuilder{order: order}
+}
+
+// ClOrdID sets the client order ID. PlaceOrder generates one if it is left
+// empty.
+func (b *OrderBuilder) ClOrdID(id string) *OrderBuilder {
+	b.order.ClOrdID = id
+	return b
+}
+
+// Symbol sets the cTrader symbol ID.
+func (b *OrderBuilder) Symbol(symbol string) *OrderBuilder {
+	b.order.Symbol = symbol
+	return b
+}
+
+// Buy makes the order a buy.
+func (b *OrderBuilder) Buy() *OrderBuilder {
+	b.order.Side = "1"
+	return b
+}
+
+// Sell makes the order a sell.
+func (b *OrderBuilder) Sell() *OrderBuilder {
+	b.order.Side = "2"
+	return b
+}
+
+// Quantity sets the order quantity in units.
+func (b *OrderBuilder) Quantity(qty float64) *OrderBuilder {
+	b.order.OrderQty = qty
+	return b
+}
+
+// Market makes the order a market order, clearing any limit or stop price.
+func (b *OrderBuilder) Market() *OrderBuilder {
+	b.order.OrdType = "1"
+	b.order.Price = 0
+	b.order.StopPx = 0
+	return b
+}
+
+// Limit makes the order a limit order at price, clearing any stop price.
+func (b *OrderBuilder) Limit(price float64) *OrderBuilder {
+	b.order.OrdType = "2"
+	b.order.Price = price
+	b.order.StopPx = 0
+	return b
+}
+
+// Stop makes the order a stop order triggered at stopPx, clearing any limit
+// price.
+func (b *OrderBuilder) Stop(stopPx float64) *OrderBuilder {
+	b.order.OrdType = "3"
+	b.order.StopPx = stopPx
+	b.order.Price = 0
+	return b
+}
+
+// StopLimit makes the order a stop-limit order that becomes a limit order at
+// price once stopPx is reached.
+func (b *OrderBuilder) StopLimit(stopPx, price float64) *OrderBuilder {
+	b.order.OrdType = "4"
+	b.order.StopPx = stopPx
+	b.order.Price = price
+	return b
+}
+
+// TimeInForce sets the order's time in force, e.g. TimeInForceGTC.
+func (b *OrderBuilder) TimeInForce(tif string) *OrderBuilder {
+	b.order.TimeInForce = tif
+	return b
+}
+
+// GoodTill makes the order GTD, expiring at expireTime.
+func (b *OrderBuilder) GoodTill(expireTime time.Time) *OrderBuilder {
+	b.order.TimeInForce = TimeInForceGTD
+	b.order.ExpireTime = expireTime
+	return b
+}
+
+// StopLoss attaches a stop-loss to the order's bracket, submitted by
+// PlaceBracketOrder once the order fills.
+func (b *OrderBuilder) StopLoss(price float64) *OrderBuilder {
+	if b.order.Bracket == nil {
+		b.order.Bracket = &Bracket{}
+	}
+	b.order.Bracket.StopLoss = price
+	return b
+}
+
+// TakeProfit attaches a take-profit to the order's bracket, submitted by
+// PlaceBracketOrder once the order fills.
+func (b *OrderBuilder) TakeProfit(price float64) *OrderBuilder {
+	if b.order.Bracket == nil {
+		b.order.Bracket = &Bracket{}
+	}
+	b.order.Bracket.TakeProfit = price
+	return b
+}
+
+// Build validates and returns the order. An empty ClOrdID is accepted and left
+// for PlaceOrder to generate.
+func (b *OrderBuilder) Build() (*OrderMsg, error) {
+	validate := b.order.Validate
+	if b.order.ClOrdID == "" {
+		validate = b.order.validateTerms
+	}
+	if err := validate(); err != nil {
+		return nil, err
+	}
+	return b.order, nil
+}
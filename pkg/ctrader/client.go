@@ -3,33 +3,94 @@ package ctrader
 import (
 	"crypto/tls"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
 type Client struct {
-	host               string
-	port               int
-	ssl                bool
-	delimiter          string
-	config             *Config
-	conn               net.Conn
-	messageSequenceNum int
-	isConnected        bool
-	mu                 sync.RWMutex
-	onConnected        func()
-	onDisconnected     func(error)
-	onMessage          func(*ResponseMessage)
-	messageChan        chan *ResponseMessage
-	errorChan          chan error
-	stopChan           chan struct{}
-	ctx                context.Context
-	cancel             context.CancelFunc
-	useTLS             bool
-	tlsConfig          *tls.Config
+	host                 string
+	port                 int
+	ssl                  bool
+	delimiter            string
+	config               *Config
+	conn                 net.Conn
+	messageSequenceNum   int
+	inboundSeqNum        int
+	isConnected          bool
+	mu                   sync.RWMutex
+	onConnected          func()
+	onDisconnected       func(error)
+	onMessage            func(*ResponseMessage)
+	onReject             func(*Reject)
+	messageChan          chan *ResponseMessage
+	adminChan            chan *ResponseMessage
+	splitChannels        bool
+	errorChan            chan error
+	stopChan             chan struct{}
+	ctx                  context.Context
+	cancel               context.CancelFunc
+	useTLS               bool
+	tlsConfig            *tls.Config
+	serverName           string
+	waitMu               sync.Mutex
+	waiters              []*waiter
+	readerDone           chan struct{}
+	recent               []*ResponseMessage
+	requestCounter       uint64
+	closing              bool
+	lastLogon            *LogonRequest
+	lastInbound          time.Time
+	heartbeatOverride    time.Duration
+	maxMissedHeartbeats  int
+	sessionInfo          SessionInfo
+	maxMessageSize       int
+	limiter              *rateLimiter
+	orderLimiter         *rateLimiter
+	rateLimitFailFast    bool
+	checksumDiagnostics  bool
+	checksumValidation   bool
+	securities           map[string]Security
+	symbols              *symbolCache
+	securityPages        map[string][]Security
+	subscriptions        map[string]*mdSubscription
+	tradingSessions      map[string]TradingSession
+	sessionGuard         bool
+	workingOrders        map[string]*ExecutionReport
+	reconcileOnReconnect bool
+	quotes               map[string]*quote
+	postLogonHooks       []func(*Client) error
+	recorder             io.Writer
+	recordMu             sync.Mutex
+	echoOutbound         bool
+	dial                 func() (net.Conn, error)
+	autoHeartbeat        bool
+	outboundHeartbeat    time.Duration
+	lastOutbound         time.Time
+	stopHeartbeats       context.CancelFunc
+	autoTestRequestReply bool
+	seqStore             SequenceStore
+	readTimeout          time.Duration
+	writeTimeout         time.Duration
+	autoReconnect        bool
+	reconnectMaxAttempts int
+	reconnectBackoff     time.Duration
+	onReconnecting       func(attempt int)
+	logger               Logger
+	metrics              *metrics
+	missingSeqNums       []seqRange
+	sendBuffer           *sendBuffer
+	prepared             sentMessage
+	clockOffset          time.Duration
+	autoClockOffset      bool
+	clockSkew            time.Duration
+	clockSkewMeasured    bool
+	timestampPrecision   Precision
 }
 
 type ClientOption func(*Client)
@@ -45,10 +106,14 @@ func NewClient(host string, port int, config *Config, opts ...ClientOption) *Cli
 		config:             config,
 		messageSequenceNum: 0,
 		messageChan:        make(chan *ResponseMessage, 100),
+		adminChan:          make(chan *ResponseMessage, 100),
 		errorChan:          make(chan error, 10),
 		stopChan:           make(chan struct{}),
 		ctx:                ctx,
 		cancel:             cancel,
+		logger:             NopLogger{},
+		metrics:            newMetrics(),
+		symbols:            newSymbolCache(),
 	}
 	
 	for _, opt := range opts {
@@ -64,53 +129,199 @@ func WithSSL(enabled bool) ClientOption {
 	}
 }
 
+// WithTLSConfig connects over TLS using config as given, e.g. with
+// certificate verification for production servers. It implies WithSSL(true).
+func WithTLSConfig(config *tls.Config) ClientOption {
+	return func(c *Client) {
+		c.ssl = true
+		c.tlsConfig = config
+	}
+}
+
+// WithServerName sets the name sent for SNI and checked against the server's
+// certificate. Without WithTLSConfig it also turns certificate verification
+// on; a config given to WithTLSConfig only takes the name if it has none.
+func WithServerName(name string) ClientOption {
+	return func(c *Client) {
+		c.serverName = name
+	}
+}
+
 func WithDelimiter(delimiter string) ClientOption {
 	return func(c *Client) {
 		c.delimiter = delimiter
 	}
 }
 
+// WithMaxMissedHeartbeats makes the client tear down and re-establish the
+// connection when nothing has been received from the server for n heartbeat
+// intervals, catching half-open connections that never return a read error.
+// The last logon is re-sent on the new connection; the connected callback is
+// not invoked again.
+func WithMaxMissedHeartbeats(n int) ClientOption {
+	return func(c *Client) {
+		c.maxMissedHeartbeats = n
+	}
+}
+
+// WithReadTimeout makes the client drop the connection when nothing has been
+// received for d, so a half-open connection is noticed instead of blocking
+// the reader forever. It defaults to twice the heartbeat interval; a negative
+// d disables it.
+func WithReadTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.readTimeout = d
+	}
+}
+
+// WithWriteTimeout makes Send fail when a message cannot be written within d.
+func WithWriteTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.writeTimeout = d
+	}
+}
+
+// effectiveReadTimeout returns the read deadline to apply, or zero for none.
+// The default is two heartbeat intervals, the server's once it has logged on.
+func (c *Client) effectiveReadTimeout() time.Duration {
+	switch {
+	case c.readTimeout > 0:
+		return c.readTimeout
+	case c.readTimeout < 0:
+		return 0
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return 2 * c.heartbeatPeriod()
+}
+
+// WithConn makes Connect use conn instead of dialing host and port, for
+// in-memory pipes in tests or transports the client cannot dial itself. The
+// connection can only be used once, so reconnecting after it is lost fails.
+func WithConn(conn net.Conn) ClientOption {
+	return func(c *Client) {
+		used := false
+		c.dial = func() (net.Conn, error) {
+			if used {
+				return nil, fmt.Errorf("provided connection has already been used")
+			}
+			used = true
+			return conn, nil
+		}
+	}
+}
+
+// WithMaxMessageSize makes Send refuse messages longer than n bytes. A
+// MaxMessageSize (383) advertised by the server on logon takes precedence.
+func WithMaxMessageSize(n int) ClientOption {
+	return func(c *Client) {
+		c.maxMessageSize = n
+	}
+}
+
 func (c *Client) Connect() error {
+	return c.ConnectContext(context.Background())
+}
+
+// ConnectContext is like Connect but gives up dialing when ctx is canceled or
+// its deadline passes. ctx only bounds the dial; the session outlives it.
+func (c *Client) ConnectContext(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	
 	if c.isConnected {
 		return fmt.Errorf("client is already connected")
 	}
+	if err := c.config.Validate(); err != nil {
+		return err
+	}
+	if c.config.EncryptMethod != 0 {
+		c.logger.LogEvent(LogLevelWarn, fmt.Sprintf("EncryptMethod %d is not supported by cTrader; logons will likely be rejected, use 0 (none)", c.config.EncryptMethod))
+	}
 	
-	address := fmt.Sprintf("%s:%d", c.host, c.port)
+	c.closing = false
+	if err := c.start(ctx); err != nil {
+		return err
+	}
+	c.logger.LogEvent(LogLevelInfo, "connected", "address", net.JoinHostPort(c.host, strconv.Itoa(c.port)))
+	
+	if c.onConnected != nil {
+		go c.onConnected()
+	}
+	
+	return nil
+}
+
+// start dials the server, or uses the connection given to WithConn, and
+// launches the per-connection goroutines. ctx bounds the dial. Callers must
+// hold c.mu.
+func (c *Client) start(ctx context.Context) error {
+	address := net.JoinHostPort(c.host, strconv.Itoa(c.port))
 	
 	var conn net.Conn
 	var err error
 	
-	if c.ssl {
-		// Create TLS configuration
-		tlsConfig := &tls.Config{
-			InsecureSkipVerify: true, // For demo/testing
-			MinVersion:         tls.VersionTLS12,
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	
+	if c.dial != nil {
+		conn, err = c.dial()
+		if err != nil {
+			return err
 		}
-		
+	} else if c.ssl {
 		// Connect with TLS
-		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: 10 * time.Second}, "tcp", address, tlsConfig)
+		dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: 10 * time.Second}, Config: c.clientTLSConfig()}
+		conn, err = dialer.DialContext(ctx, "tcp", address)
 		if err != nil {
 			return fmt.Errorf("failed to connect with TLS to %s: %w", address, err)
 		}
 	} else {
 		// Connect with plain TCP
-		conn, err = net.DialTimeout("tcp", address, 10*time.Second)
+		dialer := &net.Dialer{Timeout: 10 * time.Second}
+		conn, err = dialer.DialContext(ctx, "tcp", address)
 		if err != nil {
 			return fmt.Errorf("failed to connect to %s: %w", address, err)
 		}
 	}
 	
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	select {
+	case <-c.stopChan:
+		c.stopChan = make(chan struct{})
+	default:
+	}
 	c.conn = conn
 	c.isConnected = true
 	c.messageSequenceNum = 0
+	c.inboundSeqNum = 0
+	c.missingSeqNums = nil
+	c.prepared = sentMessage{}
+	if c.seqStore != nil {
+		c.messageSequenceNum = c.seqStore.LoadOutbound()
+		c.inboundSeqNum = c.seqStore.LoadInbound()
+	} else {
+		c.clearSendBuffer()
+	}
+	c.lastInbound = time.Now()
 	
-	go c.readMessages()
+	// Closed when the reader stops, failing requests still waiting for a
+	// response on this connection.
+	readerDone := make(chan struct{})
+	c.waitMu.Lock()
+	c.readerDone = readerDone
+	c.waitMu.Unlock()
 	
-	if c.onConnected != nil {
-		go c.onConnected()
+	callbacks := newCallbackQueue()
+	go func() {
+		defer close(readerDone)
+		c.readMessages(c.ctx, conn, callbacks)
+	}()
+	go c.dispatchMessages(c.ctx, callbacks)
+	
+	if c.maxMissedHeartbeats > 0 {
+		go c.watchHeartbeats(c.ctx, conn)
 	}
 	
 	return nil
@@ -120,7 +331,11 @@ func (c *Client) Disconnect() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	
+	c.closing = true
+	
 	if !c.isConnected {
+		// Stops a reconnect in progress.
+		c.endSession()
 		return nil
 	}
 	
@@ -131,6 +346,8 @@ func (c *Client) Disconnect() error {
 	}
 	
 	c.isConnected = false
+	c.endSession()
+	c.logger.LogEvent(LogLevelInfo, "disconnected")
 	
 	if c.onDisconnected != nil {
 		go c.onDisconnected(fmt.Errorf("client disconnected"))
@@ -139,59 +356,297 @@ func (c *Client) Disconnect() error {
 	return nil
 }
 
+// Done returns a channel that is closed when the session ends, either through
+// Disconnect or because the connection was lost. Application loops can select
+// on it instead of polling IsConnected. A later Connect starts a new session
+// with a new channel.
+func (c *Client) Done() <-chan struct{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.stopChan
+}
+
+// endSession closes the Done channel once and ends all market data
+// subscriptions; the caller must hold c.mu.
+func (c *Client) endSession() {
+	select {
+	case <-c.stopChan:
+	default:
+		close(c.stopChan)
+	}
+	c.closeSubscriptions()
+}
+
 func (c *Client) IsConnected() bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	return c.isConnected
 }
 
-func (c *Client) Send(message interface{}) error {
+func (c *Client) Send(message Message) error {
+	return c.SendContext(context.Background(), message)
+}
+
+// SendContext is like Send but gives up when ctx is canceled or its deadline
+// passes, including while the write is blocked. The earlier of the ctx
+// deadline and WithWriteTimeout applies to the write. Orders with a Bracket
+// are refused; only PlaceBracketOrder sends their protective orders.
+func (c *Client) SendContext(ctx context.Context, message Message) error {
+	if err := checkNoBracket(message); err != nil {
+		return err
+	}
+	_, err := c.send(ctx, message)
+	return err
+}
+
+// send serializes and writes message, returning the sequence number it was
+// sent with. ctx bounds the rate limiter wait and the write.
+func (c *Client) send(ctx context.Context, message Message) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	
+	if c.sessionGuard {
+		if err := c.checkSession(message); err != nil {
+			return 0, err
+		}
+	}
+	
+	if validator, ok := message.(interface{ Validate() error }); ok {
+		if err := validator.Validate(); err != nil {
+			return 0, fmt.Errorf("invalid message: %w", err)
+		}
+	}
+	
 	c.mu.RLock()
-	defer c.mu.RUnlock()
+	limiter := c.limiter
+	sessionCtx := c.ctx
+	c.mu.RUnlock()
+	
+	if c.orderLimiter != nil || limiter != nil {
+		waitCtx, cancel := context.WithCancel(ctx)
+		stopWait := context.AfterFunc(sessionCtx, cancel)
+		err := c.waitOrderLimit(waitCtx, message)
+		if err == nil && limiter != nil {
+			err = limiter.wait(waitCtx)
+		}
+		stopWait()
+		cancel()
+		if err != nil {
+			return 0, err
+		}
+	}
+	
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	
 	if !c.isConnected {
-		return fmt.Errorf("client is not connected")
+		return 0, ErrNotConnected
 	}
 	
 	c.messageSequenceNum++
-	var messageString string
+	nextSeqNum := 0 // set when the message moves the outbound sequence
 	
+	// Requests with session side effects; every request serializes itself.
 	switch msg := message.(type) {
 	case *LogonRequest:
-		messageString = msg.GetMessage(c.messageSequenceNum)
-	case *Heartbeat:
-		messageString = msg.GetMessage(c.messageSequenceNum)
-	case *TestRequest:
-		messageString = msg.GetMessage(c.messageSequenceNum)
-	case *LogoutRequest:
-		messageString = msg.GetMessage(c.messageSequenceNum)
-	case *OrderMsg:
-		messageString = msg.GetMessage(c.messageSequenceNum)
-	case *OrderCancelRequest:
-		messageString = msg.GetMessage(c.messageSequenceNum)
-	case *MarketDataRequest:
-		messageString = msg.GetMessage(c.messageSequenceNum)
-	case *SecurityListRequest:
-		messageString = msg.GetMessage(c.messageSequenceNum)
-	case *RequestForPositions:
-		messageString = msg.GetMessage(c.messageSequenceNum)
-	default:
-		return fmt.Errorf("unsupported message type")
+		if msg.ResetSeqNum {
+			// Both sides restart from 1; the server's logon is the first
+			// inbound message of the new sequence.
+			c.messageSequenceNum = 1
+			c.inboundSeqNum = 0
+			c.saveInbound()
+			c.clearSendBuffer()
+		}
+		c.lastLogon = msg
+	case *SequenceReset:
+		nextSeqNum = msg.NewSeqNo
+	}
+	c.applySecurityDefaults(message)
+	messageString := c.serialize(message, c.messageSequenceNum)
+	
+	// Bytes handed out by RawMessage are sent as they are.
+	if c.prepared.seqNum == c.messageSequenceNum && sameExceptSendingTime(c.prepared.raw, messageString, c.delimiter) {
+		messageString = c.prepared.raw
 	}
+	c.prepared = sentMessage{}
 	
-	if !strings.HasSuffix(messageString, c.delimiter) {
-		messageString += c.delimiter
+	if c.maxMessageSize > 0 && len(messageString) > c.maxMessageSize {
+		c.messageSequenceNum--
+		return 0, fmt.Errorf("%w: %d bytes exceeds limit of %d", ErrMessageTooLarge, len(messageString), c.maxMessageSize)
 	}
 	
-	_, err := c.conn.Write([]byte(messageString))
+	var deadline time.Time
+	if c.writeTimeout > 0 {
+		deadline = time.Now().Add(c.writeTimeout)
+	}
+	ctxDeadline, ctxBound := ctx.Deadline()
+	if ctxBound && (deadline.IsZero() || ctxDeadline.Before(deadline)) {
+		deadline = ctxDeadline
+	} else {
+		ctxBound = false
+	}
+	if !deadline.IsZero() {
+		c.conn.SetWriteDeadline(deadline)
+	}
+	conn := c.conn
+	// A canceled ctx unblocks the write by moving the deadline into the past.
+	interrupted := make(chan struct{})
+	stopInterrupt := context.AfterFunc(ctx, func() {
+		conn.SetWriteDeadline(time.Unix(1, 0))
+		close(interrupted)
+	})
+	_, err := conn.Write([]byte(messageString))
+	if !stopInterrupt() {
+		<-interrupted
+		conn.SetWriteDeadline(time.Time{})
+	} else if !deadline.IsZero() {
+		conn.SetWriteDeadline(time.Time{})
+	}
 	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return 0, fmt.Errorf("failed to send message: %w", ctxErr)
+		}
+		var netErr net.Error
+		if ctxBound && errors.As(err, &netErr) && netErr.Timeout() {
+			// The write hit the ctx deadline just before ctx itself expired.
+			return 0, fmt.Errorf("failed to send message: %w", context.DeadlineExceeded)
+		}
+		return 0, fmt.Errorf("failed to send message: %w", wrapConnError("write", err))
+	}
+	sentSeqNum := c.messageSequenceNum
+	if nextSeqNum > 0 {
+		c.messageSequenceNum = nextSeqNum - 1
+	}
+	c.lastOutbound = time.Now()
+	c.saveOutbound()
+	if c.sendBuffer != nil {
+		c.sendBuffer.add(sentSeqNum, messageString)
+	}
+	c.record(recordOutbound, messageString)
+	c.logger.LogOutbound(messageString)
+	if typed, ok := message.(interface{ MsgType() string }); ok {
+		c.metrics.countSent(typed.MsgType())
+	} else {
+		c.metrics.countSent(NewResponseMessage(messageString, c.delimiter).GetMessageType())
 	}
+	c.echo(messageString)
 	
-	return nil
+	return sentSeqNum, nil
 }
 
-func (c *Client) readMessages() {
+// clientTLSConfig returns the TLS configuration to dial with. Unless
+// WithTLSConfig or WithServerName was given, the server's certificate is not
+// verified, which is only acceptable for demo servers.
+func (c *Client) clientTLSConfig() *tls.Config {
+	if c.tlsConfig != nil {
+		if c.serverName == "" || c.tlsConfig.ServerName != "" {
+			return c.tlsConfig
+		}
+		config := c.tlsConfig.Clone()
+		config.ServerName = c.serverName
+		return config
+	}
+	
+	if c.serverName != "" {
+		return &tls.Config{ServerName: c.serverName, MinVersion: tls.VersionTLS12}
+	}
+	
+	c.logger.LogEvent(LogLevelWarn, "TLS certificate verification is disabled; use WithTLSConfig or WithServerName in production")
+	return &tls.Config{
+		InsecureSkipVerify: true, // For demo/testing
+		MinVersion:         tls.VersionTLS12,
+	}
+}
+
+// RawMessage returns message serialized as Send would transmit it next, with
+// the client's real outbound sequence number, so it can be logged before it is
+// sent. If message is sent next and unchanged, Send transmits exactly these
+// bytes, SendingTime (52) included. Nothing is sent and the sequence number is
+// not consumed.
+func (c *Client) RawMessage(message Message) (string, error) {
+	if validator, ok := message.(interface{ Validate() error }); ok {
+		if err := validator.Validate(); err != nil {
+			return "", fmt.Errorf("invalid message: %w", err)
+		}
+	}
+	
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	seqNum := c.messageSequenceNum + 1
+	if logon, ok := message.(*LogonRequest); ok && logon.ResetSeqNum {
+		seqNum = 1
+	}
+	c.applySecurityDefaults(message)
+	raw := c.serialize(message, seqNum)
+	
+	if c.maxMessageSize > 0 && len(raw) > c.maxMessageSize {
+		return "", fmt.Errorf("%w: %d bytes exceeds limit of %d", ErrMessageTooLarge, len(raw), c.maxMessageSize)
+	}
+	
+	c.prepared = sentMessage{seqNum: seqNum, raw: raw}
+	return raw, nil
+}
+
+// applySecurityDefaults fills in order fields known from the security list.
+// Callers must hold c.mu.
+func (c *Client) applySecurityDefaults(message Message) {
+	switch msg := message.(type) {
+	case *OrderMsg:
+		if security, exists := c.securities[msg.Symbol]; exists && msg.QtyStep == 0 {
+			msg.QtyStep = security.QtyStep
+		}
+	case *OrderCancelReplaceRequest:
+		if security, exists := c.securities[msg.Symbol]; exists && msg.QtyStep == 0 {
+			msg.QtyStep = security.QtyStep
+		}
+	}
+}
+
+// serialize renders message as seqNum, terminated by the client's delimiter.
+// Callers must hold c.mu.
+func (c *Client) serialize(message Message, seqNum int) string {
+	if clocked, ok := message.(interface{ setClock(clock) }); ok {
+		clocked.setClock(c.clock())
+	}
+	raw := message.GetMessage(seqNum)
+	if !strings.HasSuffix(raw, c.delimiter) {
+		raw += c.delimiter
+	}
+	return raw
+}
+
+// sameExceptSendingTime reports whether a and b carry the same fields apart
+// from SendingTime (52) and the BodyLength and CheckSum depending on it.
+func sameExceptSendingTime(a, b, delimiter string) bool {
+	strip := func(raw string) []Field {
+		var fields []Field
+		for _, field := range NewResponseMessage(raw, delimiter).Fields() {
+			switch field.Tag {
+			case 9, 10, 52:
+			default:
+				fields = append(fields, field)
+			}
+		}
+		return fields
+	}
+	
+	fieldsA, fieldsB := strip(a), strip(b)
+	if len(fieldsA) != len(fieldsB) {
+		return false
+	}
+	for i := range fieldsA {
+		if fieldsA[i] != fieldsB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// readMessages reads and processes inbound messages until the session ends,
+// queueing each for the message callback on callbacks.
+func (c *Client) readMessages(ctx context.Context, conn net.Conn, callbacks *callbackQueue) {
 	defer func() {
 		if r := recover(); r != nil {
 			c.errorChan <- fmt.Errorf("panic in readMessages: %v", r)
@@ -203,13 +658,32 @@ func (c *Client) readMessages() {
 	
 	for {
 		select {
-		case <-c.ctx.Done():
+		case <-ctx.Done():
 			return
 		default:
-			n, err := c.conn.Read(buffer)
+			readTimeout := c.effectiveReadTimeout()
+			if readTimeout > 0 {
+				conn.SetReadDeadline(time.Now().Add(readTimeout))
+			}
+			
+			n, err := conn.Read(buffer)
 			if err != nil {
-				c.errorChan <- fmt.Errorf("read error: %w", err)
-				c.handleDisconnection()
+				if ctx.Err() != nil {
+					return
+				}
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					err = fmt.Errorf("%w: %w: nothing received from server for %s: %w", ErrConnectionLost, ErrReadTimeout, readTimeout, err)
+					conn.Close()
+				} else {
+					err = fmt.Errorf("%w: %w", ErrConnectionLost, wrapConnError("read", err))
+				}
+				if c.autoReconnect {
+					go c.reconnect(conn, err)
+					return
+				}
+				c.errorChan <- err
+				c.handleDisconnection(conn, err)
 				return
 			}
 			
@@ -217,54 +691,104 @@ func (c *Client) readMessages() {
 			
 			// Process complete messages
 			for {
-				messageEnd := c.findMessageEnd(messageBuffer)
-				if messageEnd == -1 {
+				message, rest, discarded := c.nextFrame(messageBuffer)
+				messageBuffer = rest
+				if discarded > 0 {
+					c.reportError(fmt.Errorf("%w: discarded %d bytes outside a message", ErrMalformedMessage, discarded))
+				}
+				if message == "" {
 					break // No complete message found
 				}
 				
-				// Extract complete message
-				message := string(messageBuffer[:messageEnd])
-				messageBuffer = messageBuffer[messageEnd:]
+				c.record(recordInbound, message)
+				c.logger.LogInbound(message)
+				if c.checksumDiagnostics || c.checksumValidation {
+					if !c.verifyChecksum(message) && c.checksumValidation {
+						continue
+					}
+				}
 				
 				// Parse and send message
 				responseMessage := NewResponseMessage(message, c.delimiter)
+				c.metrics.countReceived(responseMessage)
+				if err := responseMessage.checkIntegrity(); err != nil {
+					c.reportError(fmt.Errorf("%w (MsgType %s): possible delimiter inside a value", err, responseMessage.GetMessageType()))
+				}
+				switch status, expected, received := c.trackInbound(responseMessage); status {
+				case seqTooLow:
+					c.rejectSeqTooLow(expected, received)
+					return
+				case seqGap:
+					c.requestResend(expected)
+				case seqDuplicate:
+					continue // Already processed; the server resent it.
+				}
+				switch responseMessage.GetMessageType() {
+				case "A":
+					c.handleLogon(responseMessage)
+					if c.autoHeartbeat {
+						c.startHeartbeating(ctx)
+					}
+					if len(c.postLogonHooks) > 0 {
+						go c.runPostLogonHooks()
+					}
+				case "1":
+					if c.autoTestRequestReply {
+						go c.replyToTestRequest(responseMessage)
+					}
+				case "2":
+					c.handleResendRequest(responseMessage)
+				case "3":
+					// SessionRejectReason 10 = SendingTime accuracy problem
+					if responseMessage.first(373) == "10" {
+						c.measureClockSkew(responseMessage)
+					}
+					if c.onReject != nil {
+						go c.onReject(ParseReject(responseMessage))
+					}
+				case "h":
+					c.handleTradingSessionStatus(responseMessage)
+				case "y":
+					c.trackSymbols(responseMessage)
+				case "8":
+					c.trackOrder(responseMessage)
+				case "W", "X":
+					c.trackQuote(responseMessage)
+					c.routeMarketData(responseMessage)
+				case "Y":
+					c.routeMarketData(responseMessage)
+				}
+				c.notifyWaiters(responseMessage)
+				
+				if c.onMessage != nil {
+					callbacks.push(responseMessage)
+				}
 				
 				select {
-				case c.messageChan <- responseMessage:
-				case <-c.ctx.Done():
+				case c.channelFor(responseMessage) <- responseMessage:
+				case <-ctx.Done():
 					return
 				default:
+					// The callback still gets it; only unseen messages are lost.
+					if c.onMessage == nil {
+						c.dropMessage(responseMessage)
+					}
 				}
 			}
 		}
 	}
 }
 
-func (c *Client) findMessageEnd(buffer []byte) int {
-	// Look for pattern "10=XXX" where XXX is checksum followed by SOH
-	for i := 0; i < len(buffer)-4; i++ {
-		if buffer[i] == '1' && buffer[i+1] == '0' && buffer[i+2] == '=' {
-			// Found "10=", now look for end SOH
-			for j := i + 3; j < len(buffer); j++ {
-				if buffer[j] == byte(c.delimiter[0]) {
-					// Return message end without strict checksum validation
-					return j + 1
-				}
-			}
-		}
-	}
-	return -1
-}
-
-func (c *Client) handleDisconnection() {
+func (c *Client) handleDisconnection(conn net.Conn, cause error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	
-	if c.isConnected {
+	if c.isConnected && c.conn == conn {
 		c.isConnected = false
+		c.endSession()
 		
 		if c.onDisconnected != nil {
-			go c.onDisconnected(fmt.Errorf("connection lost"))
+			go c.onDisconnected(cause)
 		}
 	}
 }
@@ -277,14 +801,72 @@ func (c *Client) SetDisconnectedCallback(callback func(error)) {
 	c.onDisconnected = callback
 }
 
+// SetMessageCallback sets the function called with every inbound message, in
+// the order received, once the client has processed it. It runs on a
+// dispatch goroutine of its own, so reading goes on while it works and it may
+// wait for responses (SendAndWait, Request, ...); the next call waits for it
+// to return. Messages are still delivered on Messages as well, but with a
+// callback that channel need not be drained; messages that do not fit it are
+// not reported as dropped. A panic in the callback is reported on Errors.
 func (c *Client) SetMessageCallback(callback func(*ResponseMessage)) {
 	c.onMessage = callback
 }
 
+// Messages returns the channel inbound messages are delivered on. With
+// WithSplitChannels it only carries application messages.
 func (c *Client) Messages() <-chan *ResponseMessage {
 	return c.messageChan
 }
 
+// AdminMessages returns the channel session-level messages (35=0, 1, 2, 3, 4,
+// 5 and A) are delivered on with WithSplitChannels. Without it nothing is
+// delivered here.
+func (c *Client) AdminMessages() <-chan *ResponseMessage {
+	return c.adminChan
+}
+
+// WithSplitChannels delivers session-level messages on AdminMessages instead
+// of Messages, so consumers of Messages only see application messages such as
+// execution reports and market data.
+func WithSplitChannels(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.splitChannels = enabled
+	}
+}
+
+// WithMessageBuffer sets the capacity of the Messages and AdminMessages
+// channels; the default is 100. Messages arriving while a channel is full are
+// dropped, counted in DroppedMessages and reported as ErrMessageDropped.
+func WithMessageBuffer(size int) ClientOption {
+	return func(c *Client) {
+		if size < 0 {
+			size = 0
+		}
+		c.messageChan = make(chan *ResponseMessage, size)
+		c.adminChan = make(chan *ResponseMessage, size)
+	}
+}
+
+// DroppedMessages returns how many messages were dropped because their
+// channel was full.
+func (c *Client) DroppedMessages() uint64 {
+	return c.Metrics().DroppedMessages
+}
+
+// dropMessage counts and reports a message that did not fit its channel.
+func (c *Client) dropMessage(msg *ResponseMessage) {
+	c.metrics.countDropped()
+	c.reportError(fmt.Errorf("%w: channel full, dropped MsgType %s (MsgSeqNum %s)", ErrMessageDropped, msg.GetMessageType(), msg.first(34)))
+}
+
+// channelFor returns the channel msg is delivered on.
+func (c *Client) channelFor(msg *ResponseMessage) chan *ResponseMessage {
+	if c.splitChannels && adminMsgTypes[msg.GetMessageType()] {
+		return c.adminChan
+	}
+	return c.messageChan
+}
+
 func (c *Client) Errors() <-chan error {
 	return c.errorChan
 }
@@ -293,6 +875,12 @@ func (c *Client) ChangeMessageSequenceNumber(newSeqNum int) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.messageSequenceNum = newSeqNum
+	c.saveOutbound()
+}
+
+// Config returns the configuration the client logs on with.
+func (c *Client) Config() *Config {
+	return c.config
 }
 
 func (c *Client) GetMessageSequenceNumber() int {
@@ -300,3 +888,149 @@ func (c *Client) GetMessageSequenceNumber() int {
 	defer c.mu.RUnlock()
 	return c.messageSequenceNum
 }
+
+// Describe returns a multi-line summary of the effective client configuration
+// and session state, suitable for pasting into bug reports. The password is
+// always masked.
+func (c *Client) Describe() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	
+	state := "disconnected"
+	if c.isConnected {
+		state = "connected"
+	}
+	
+	password := "(empty)"
+	if c.config.Password != "" {
+		password = "********"
+	}
+	
+	ssl := "off"
+	if c.ssl {
+		ssl = "on"
+	}
+	
+	options := c.enabledOptions()
+	if len(options) == 0 {
+		options = []string{"none"}
+	}
+	
+	var b strings.Builder
+	fmt.Fprintf(&b, "host: %s\n", c.host)
+	fmt.Fprintf(&b, "port: %d\n", c.port)
+	fmt.Fprintf(&b, "ssl: %s\n", ssl)
+	fmt.Fprintf(&b, "begin string: %s\n", c.config.BeginString)
+	fmt.Fprintf(&b, "sender: %s/%s\n", c.config.SenderCompID, c.config.SenderSubID)
+	fmt.Fprintf(&b, "target: %s/%s\n", c.config.TargetCompID, c.config.TargetSubID)
+	fmt.Fprintf(&b, "username: %s\n", c.config.Username)
+	fmt.Fprintf(&b, "password: %s\n", password)
+	fmt.Fprintf(&b, "heartbeat: %ds\n", c.config.HeartBeat)
+	fmt.Fprintf(&b, "options: %s\n", strings.Join(options, ", "))
+	fmt.Fprintf(&b, "state: %s\n", state)
+	fmt.Fprintf(&b, "outbound seqnum: %d\n", c.messageSequenceNum)
+	fmt.Fprintf(&b, "inbound seqnum: %d\n", c.inboundSeqNum)
+	
+	return b.String()
+}
+
+// enabledOptions lists the non-default client options for Describe.
+func (c *Client) enabledOptions() []string {
+	var options []string
+	if c.delimiter != "\x01" {
+		options = append(options, fmt.Sprintf("delimiter=%q", c.delimiter))
+	}
+	if c.maxMissedHeartbeats > 0 {
+		options = append(options, fmt.Sprintf("max-missed-heartbeats=%d", c.maxMissedHeartbeats))
+	}
+	if c.maxMessageSize > 0 {
+		options = append(options, fmt.Sprintf("max-message-size=%d", c.maxMessageSize))
+	}
+	if c.limiter != nil {
+		options = append(options, fmt.Sprintf("rate-limit=%d/%s", c.limiter.max, c.limiter.per))
+	}
+	if c.orderLimiter != nil {
+		options = append(options, fmt.Sprintf("order-rate-limit=%d/%s", c.orderLimiter.max, c.orderLimiter.per))
+	}
+	if c.rateLimitFailFast {
+		options = append(options, "rate-limit-fail-fast")
+	}
+	if c.checksumDiagnostics {
+		options = append(options, "checksum-diagnostics")
+	}
+	if c.checksumValidation {
+		options = append(options, "checksum-validation")
+	}
+	if c.sessionGuard {
+		options = append(options, "session-guard")
+	}
+	if c.reconcileOnReconnect {
+		options = append(options, "reconcile-on-reconnect")
+	}
+	if c.recorder != nil {
+		options = append(options, "recorder")
+	}
+	if c.clockOffset != 0 {
+		options = append(options, fmt.Sprintf("clock-offset=%s", c.clockOffset))
+	}
+	if c.autoClockOffset {
+		options = append(options, "auto-clock-offset")
+	}
+	if c.timestampPrecision != PrecisionSeconds {
+		options = append(options, fmt.Sprintf("timestamp-precision=%s", c.timestampPrecision.layout()))
+	}
+	if c.tlsConfig != nil {
+		options = append(options, "tls-config")
+	}
+	if c.serverName != "" {
+		options = append(options, fmt.Sprintf("server-name=%s", c.serverName))
+	}
+	if c.splitChannels {
+		options = append(options, "split-channels")
+	}
+	if cap(c.messageChan) != 100 {
+		options = append(options, fmt.Sprintf("message-buffer=%d", cap(c.messageChan)))
+	}
+	if c.sendBuffer != nil {
+		options = append(options, fmt.Sprintf("send-buffer=%d", len(c.sendBuffer.entries)))
+	}
+	if _, nop := c.logger.(NopLogger); !nop {
+		options = append(options, "logger")
+	}
+	if len(c.postLogonHooks) > 0 {
+		options = append(options, fmt.Sprintf("post-logon-hooks=%d", len(c.postLogonHooks)))
+	}
+	if c.echoOutbound {
+		options = append(options, "echo-outbound")
+	}
+	if c.dial != nil {
+		options = append(options, "custom-conn")
+	}
+	if c.autoHeartbeat {
+		options = append(options, fmt.Sprintf("auto-heartbeat=%s", c.outboundHeartbeatPeriod()))
+	}
+	if c.autoTestRequestReply {
+		options = append(options, "auto-test-request-reply")
+	}
+	if c.seqStore != nil {
+		options = append(options, "sequence-store")
+	}
+	if c.readTimeout != 0 {
+		options = append(options, fmt.Sprintf("read-timeout=%s", c.readTimeout))
+	}
+	if c.writeTimeout > 0 {
+		options = append(options, fmt.Sprintf("write-timeout=%s", c.writeTimeout))
+	}
+	if c.autoReconnect {
+		options = append(options, fmt.Sprintf("auto-reconnect=%d/%s", c.reconnectMaxAttempts, c.reconnectBackoff))
+	}
+	return options
+}
+
+// GetInboundSequenceNumber returns the MsgSeqNum of the latest message
+// received from the server.
+func (c *Client) GetInboundSequenceNumber() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.inboundSeqNum
+}
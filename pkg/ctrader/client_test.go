@@ -0,0 +1,3611 @@
+package ctrader
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pappi/ctrader-go/pkg/ctrader/testserver"
+)
+
+func testConfig() *Config {
+	return &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "TEST_SENDER",
+		TargetCompID: "cServer",
+		TargetSubID:  "TRADE",
+		SenderSubID:  "TRADE",
+		Username:     "testuser",
+		Password:     "testpass",
+		HeartBeat:    30,
+	}
+}
+
+// testServer is a minimal FIX acceptor used to drive a Client over a real socket.
+type testServer struct {
+	t     *testing.T
+	ln    net.Listener
+	conns chan *testConn
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	s := &testServer{t: t, ln: ln, conns: make(chan *testConn, 4)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.conns <- &testConn{t: t, conn: conn}
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+
+	return s
+}
+
+// client returns a connected client pointed at the server.
+func (s *testServer) client(config *Config, opts ...ClientOption) *Client {
+	s.t.Helper()
+
+	port := s.ln.Addr().(*net.TCPAddr).Port
+	client := NewClient("127.0.0.1", port, config, opts...)
+	if err := client.Connect(); err != nil {
+		s.t.Fatalf("failed to connect: %v", err)
+	}
+	s.t.Cleanup(func() { client.Disconnect() })
+
+	return client
+}
+
+func (s *testServer) accept() *testConn {
+	s.t.Helper()
+
+	select {
+	case conn := <-s.conns:
+		s.t.Cleanup(func() { conn.conn.Close() })
+		return conn
+	case <-time.After(2 * time.Second):
+		s.t.Fatal("timed out waiting for connection")
+		return nil
+	}
+}
+
+type testConn struct {
+	t      *testing.T
+	conn   net.Conn
+	buffer []byte
+	seqNum int
+}
+
+// next returns the next message written by the client.
+func (tc *testConn) next() *ResponseMessage {
+	tc.t.Helper()
+
+	chunk := make([]byte, 4096)
+	tc.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if start := bytes.Index(tc.buffer, []byte("\x0110=")); start != -1 {
+			if end := bytes.IndexByte(tc.buffer[start+1:], '\x01'); end != -1 {
+				end += start + 2
+				message := string(tc.buffer[:end])
+				tc.buffer = tc.buffer[end:]
+				return NewResponseMessage(message, "\x01")
+			}
+		}
+
+		n, err := tc.conn.Read(chunk)
+		if err != nil {
+			tc.t.Fatalf("failed to read from client: %v", err)
+		}
+		tc.buffer = append(tc.buffer, chunk[:n]...)
+	}
+}
+
+// send writes a server message of msgType with the given "tag=value" body fields.
+func (tc *testConn) send(msgType string, fields ...string) {
+	tc.t.Helper()
+
+	tc.seqNum++
+	if _, err := tc.conn.Write([]byte(buildTestMessage(msgType, tc.seqNum, fields...))); err != nil {
+		tc.t.Fatalf("failed to write to client: %v", err)
+	}
+}
+
+func buildTestMessage(msgType string, seqNum int, fields ...string) string {
+	return buildTestMessageAt(time.Now(), msgType, seqNum, fields...)
+}
+
+// buildTestMessageAt is buildTestMessage with sendingTime as SendingTime (52).
+func buildTestMessageAt(sendingTime time.Time, msgType string, seqNum int, fields ...string) string {
+	header := []string{
+		"35=" + msgType,
+		"49=cServer",
+		"56=TEST_SENDER",
+		fmt.Sprintf("34=%d", seqNum),
+		"52=" + sendingTime.UTC().Format("20060102-15:04:05"),
+	}
+	body := strings.Join(append(header, fields...), "\x01") + "\x01"
+	message := fmt.Sprintf("8=FIX.4.4\x019=%d\x01%s", len(body), body)
+
+	checksum := 0
+	for _, b := range []byte(message) {
+		checksum += int(b)
+	}
+	return fmt.Sprintf("%s10=%03d\x01", message, checksum%256)
+}
+
+func TestSendAndWaitUnsupportedMessage(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	go func() {
+		request := conn.next()
+		conn.send("j",
+			"45="+request.first(34),
+			"372="+request.GetMessageType(),
+			"380=3",
+			"58=Unsupported message type",
+		)
+	}()
+
+	securityReq := NewSecurityListRequest(client.config)
+	securityReq.SecurityReqID = "SEC_1"
+	securityReq.SecurityListRequestType = "0"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	_, err := client.SendAndWait(ctx, securityReq, func(msg *ResponseMessage) bool {
+		return msg.GetMessageType() == "y"
+	})
+
+	if !errors.Is(err, ErrUnsupportedMessage) {
+		t.Fatalf("Expected ErrUnsupportedMessage, got %v", err)
+	}
+
+	var unsupported *UnsupportedMessageError
+	if !errors.As(err, &unsupported) {
+		t.Fatalf("Expected *UnsupportedMessageError, got %T", err)
+	}
+
+	if unsupported.Session != "TRADE" || unsupported.MsgType != "x" {
+		t.Errorf("Expected TRADE/x, got %s/%s", unsupported.Session, unsupported.MsgType)
+	}
+
+	if !strings.Contains(err.Error(), "TRADE session does not support SecurityListRequest") {
+		t.Errorf("Error should name session and message type, got %q", err.Error())
+	}
+}
+
+func TestSendAndWaitResponse(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	go func() {
+		request := conn.next()
+		conn.send("y", "320="+request.first(320), "55=1")
+	}()
+
+	securityReq := NewSecurityListRequest(client.config)
+	securityReq.SecurityReqID = "SEC_2"
+	securityReq.SecurityListRequestType = "0"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	response, err := client.SendAndWait(ctx, securityReq, func(msg *ResponseMessage) bool {
+		return msg.GetMessageType() == "y" && msg.first(320) == "SEC_2"
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if response.first(55) != "1" {
+		t.Errorf("Expected symbol 1, got %s", response.first(55))
+	}
+}
+
+func TestRequestMatchesCorrelationTag(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	go func() {
+		request := conn.next()
+		conn.send("y", "320=SEC_OTHER", "55=2")
+		conn.send("y", "320="+request.first(320), "55=1")
+	}()
+
+	securityReq := NewSecurityListRequest(client.config)
+	securityReq.SecurityReqID = "SEC_3"
+	securityReq.SecurityListRequestType = "0"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	response, err := client.Request(ctx, securityReq, 320)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if response.first(55) != "1" {
+		t.Errorf("Expected the response to SEC_3 with symbol 1, got %s", response.first(55))
+	}
+
+	if _, err := client.Request(ctx, securityReq, 710); err == nil {
+		t.Error("Expected an error for a correlation tag the request does not carry")
+	}
+}
+
+func TestRawMessageIsTransmittedVerbatim(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	testReq := NewTestRequest(client.config)
+	testReq.TestReqID = "RAW_1"
+
+	raw, err := client.RawMessage(testReq)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if seqNum := NewResponseMessage(raw, "\x01").first(34); seqNum != "1" {
+		t.Errorf("Expected the next sequence number 1, got %s", seqNum)
+	}
+
+	// A fresh serialization would carry a later SendingTime.
+	time.Sleep(1100 * time.Millisecond)
+	if err := client.Send(testReq); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	if sent, want := conn.next().GetMessage(), NewResponseMessage(raw, "\x01").GetMessage(); sent != want {
+		t.Errorf("Expected the transmitted bytes to equal RawMessage\nsent: %q\nraw:  %q", sent, want)
+	}
+
+	// Once sent, the bytes are not reused.
+	next, err := client.RawMessage(testReq)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if seqNum := NewResponseMessage(next, "\x01").first(34); seqNum != "2" {
+		t.Errorf("Expected the next sequence number 2, got %s", seqNum)
+	}
+}
+
+func TestSendRawVerbatim(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	// Captured from another session: sequence number, time and checksum are
+	// all foreign to this one.
+	raw := NewTestRequest(client.config).GetMessage(42)
+	raw = strings.TrimSuffix(raw, "\x01")
+	if err := client.SendRawVerbatim(raw); err != nil {
+		t.Fatalf("SendRawVerbatim failed: %v", err)
+	}
+
+	if sent, want := conn.next().GetMessage(), NewResponseMessage(raw+"\x01", "\x01").GetMessage(); sent != want {
+		t.Errorf("Expected the input bytes\nsent: %q\nraw:  %q", sent, want)
+	}
+	if seqNum := client.GetMessageSequenceNumber(); seqNum != 0 {
+		t.Errorf("Expected the outbound sequence untouched, got %d", seqNum)
+	}
+}
+
+func TestDescribeRedactsPassword(t *testing.T) {
+	config := testConfig()
+	config.Password = "s3cret-pass"
+
+	client := NewClient("demo-uk-eqx-01.p.c-trader.com", 5212, config, WithSSL(true))
+	client.ChangeMessageSequenceNumber(7)
+
+	description := client.Describe()
+
+	if strings.Contains(description, "s3cret-pass") {
+		t.Fatalf("Describe should not contain the password:\n%s", description)
+	}
+
+	for _, expected := range []string{
+		"host: demo-uk-eqx-01.p.c-trader.com",
+		"port: 5212",
+		"ssl: on",
+		"sender: TEST_SENDER/TRADE",
+		"target: cServer/TRADE",
+		"password: ********",
+		"heartbeat: 30s",
+		"state: disconnected",
+		"outbound seqnum: 7",
+	} {
+		if !strings.Contains(description, expected) {
+			t.Errorf("Describe should contain %q:\n%s", expected, description)
+		}
+	}
+}
+
+func TestRequestPositions(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	go func() {
+		request := conn.next()
+		posReqID := request.first(710)
+		conn.send("AP", "710="+posReqID, "721=101", "55=1", "704=1000", "727=2", "728=0")
+		conn.send("AP", "710="+posReqID, "721=102", "55=2", "705=2000", "727=2", "728=0")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	reports, err := client.RequestPositions(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(reports) != 2 {
+		t.Fatalf("Expected 2 reports, got %d", len(reports))
+	}
+
+	if reports[0].PosMaintRptID != "101" || reports[0].LongQty != 1000 {
+		t.Errorf("Unexpected first report: %+v", reports[0])
+	}
+
+	if reports[1].PosMaintRptID != "102" || reports[1].ShortQty != 2000 {
+		t.Errorf("Unexpected second report: %+v", reports[1])
+	}
+}
+
+func TestRequestPositionsNone(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	go func() {
+		request := conn.next()
+		conn.send("AO", "710="+request.first(710), "727=0", "728=2")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	reports, err := client.RequestPositions(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(reports) != 0 {
+		t.Errorf("Expected no reports, got %d", len(reports))
+	}
+}
+
+func TestRequestPositionsWaitsForLastReport(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	go func() {
+		request := conn.next()
+		posReqID := request.first(710)
+		conn.send("AO", "710="+posReqID, "727=3", "728=0", "729=0")
+		conn.send("AP", "710="+posReqID, "721=101", "55=1", "704=1000")
+		conn.send("AP", "710="+posReqID, "721=102", "55=2", "705=2000")
+		conn.send("AP", "710="+posReqID, "721=103", "55=3", "704=500", "912=Y")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	reports, err := client.RequestPositions(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(reports) != 3 {
+		t.Fatalf("Expected 3 reports, got %d", len(reports))
+	}
+	if reports[2].PosMaintRptID != "103" || !reports[2].LastRptRequested {
+		t.Errorf("Unexpected last report: %+v", reports[2])
+	}
+}
+
+func TestRequestPositionsWithoutCount(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	// No ack and no TotalNumPosReports (727); only 912=Y ends the list.
+	go func() {
+		request := conn.next()
+		posReqID := request.first(710)
+		conn.send("AP", "710="+posReqID, "721=101", "55=1", "704=1000")
+		conn.send("AP", "710="+posReqID, "721=102", "55=2", "705=2000", "912=Y")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	reports, err := client.RequestPositions(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("Expected 2 reports, got %d", len(reports))
+	}
+}
+
+func TestPlaceBracketOrder(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	children := make(chan *ResponseMessage, 2)
+	go func() {
+		entry := conn.next()
+		clOrdID := entry.first(11)
+		conn.send("8", "11="+clOrdID, "37=1", "150=0", "39=0", "55=1", "54=1", "38=1000")
+		conn.send("8", "11="+clOrdID, "37=1", "150=F", "39=2", "55=1", "54=1", "38=1000", "14=1000", "6=1.10000", "721=POS1")
+		children <- conn.next()
+		children <- conn.next()
+	}()
+
+	order := NewOrderMsg(client.config)
+	order.ClOrdID = "ENTRY_1"
+	order.Symbol = "1"
+	order.Side = "1"
+	order.OrderQty = 1000
+	order.OrdType = "1"
+	order.Bracket = &Bracket{StopLoss: 1.09, TakeProfit: 1.12}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	bracket, err := client.PlaceBracketOrder(ctx, order)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if bracket.Entry.PosMaintRptID != "POS1" {
+		t.Errorf("Expected entry position POS1, got %s", bracket.Entry.PosMaintRptID)
+	}
+
+	stopLoss := <-children
+	takeProfit := <-children
+
+	for _, child := range []*ResponseMessage{stopLoss, takeProfit} {
+		if child.GetMessageType() != "D" || child.first(721) != "POS1" || child.first(54) != "2" {
+			t.Errorf("Child order should close POS1: %s", child.GetMessage())
+		}
+	}
+
+	if stopLoss.first(40) != "3" || stopLoss.first(99) != "1.09000" {
+		t.Errorf("Unexpected stop loss: %s", stopLoss.GetMessage())
+	}
+
+	if takeProfit.first(40) != "2" || takeProfit.first(44) != "1.12000" {
+		t.Errorf("Unexpected take profit: %s", takeProfit.GetMessage())
+	}
+}
+
+func TestPlaceOrderRefusesBracket(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	server.accept()
+
+	order, err := NewOrder(client.config).Symbol("1").Buy().Quantity(1000).StopLoss(1.09).Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if _, err := client.PlaceOrder(ctx, order); err == nil || !strings.Contains(err.Error(), "PlaceBracketOrder") {
+		t.Errorf("Expected PlaceOrder to point to PlaceBracketOrder, got %v", err)
+	}
+	if _, err := client.PlaceOrderAsync(order); err == nil {
+		t.Error("Expected PlaceOrderAsync to refuse the bracket")
+	}
+	if err := client.Send(order); err == nil {
+		t.Error("Expected Send to refuse the bracket")
+	}
+	if seqNum := client.GetMessageSequenceNumber(); seqNum != 0 {
+		t.Errorf("Expected nothing to be sent, sequence number is %d", seqNum)
+	}
+}
+
+func TestPlaceBracketOrderRejectedEntry(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	go func() {
+		entry := conn.next()
+		conn.send("8", "11="+entry.first(11), "150=8", "39=8", "58=Not enough money")
+	}()
+
+	order := NewOrderMsg(client.config)
+	order.Symbol = "1"
+	order.Side = "1"
+	order.OrderQty = 1000
+	order.OrdType = "1"
+	order.Bracket = &Bracket{StopLoss: 1.09, TakeProfit: 1.12}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if _, err := client.PlaceBracketOrder(ctx, order); !errors.Is(err, ErrOrderRejected) {
+		t.Fatalf("Expected ErrOrderRejected, got %v", err)
+	}
+
+	if seqNum := client.GetMessageSequenceNumber(); seqNum != 1 {
+		t.Errorf("Expected only the entry to be sent, sequence number is %d", seqNum)
+	}
+}
+
+func TestMaxMissedHeartbeatsReconnects(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig(), WithMaxMissedHeartbeats(2), withHeartbeatPeriod(50*time.Millisecond))
+	conn := server.accept()
+
+	logon := NewLogonRequest(client.config)
+	logon.ResetSeqNum = true
+	if err := client.Send(logon); err != nil {
+		t.Fatalf("Failed to send logon: %v", err)
+	}
+
+	if msg := conn.next(); msg.GetMessageType() != "A" {
+		t.Fatalf("Expected logon, got %s", msg.GetMessageType())
+	}
+	conn.send("A", "98=0", "108=30")
+
+	// The server now goes silent; the client should give up on this socket.
+	reconnected := server.accept()
+	if msg := reconnected.next(); msg.GetMessageType() != "A" {
+		t.Fatalf("Expected logon on the new connection, got %s", msg.GetMessageType())
+	}
+
+	if !client.IsConnected() {
+		t.Error("Client should be connected after reconnecting")
+	}
+}
+
+func TestWaitForAck(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	order := NewOrderMsg(client.config)
+	order.ClOrdID = "ACK_1"
+	order.Symbol = "1"
+	order.Side = "1"
+	order.OrderQty = 1000
+	order.OrdType = "1"
+
+	if err := client.Send(order); err != nil {
+		t.Fatalf("Failed to send order: %v", err)
+	}
+	conn.next()
+	conn.send("8", "11=ACK_1", "37=1", "150=0", "39=0")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := client.WaitForAck(ctx, "ACK_1"); err != nil {
+		t.Errorf("Expected ack, got %v", err)
+	}
+}
+
+func TestWaitForAckReject(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- client.WaitForAck(ctx, "MD_1")
+	}()
+
+	mdReq := NewMarketDataRequest(client.config)
+	mdReq.MDReqID = "MD_1"
+	mdReq.SubscriptionRequestType = "1"
+	mdReq.Symbol = "999"
+	if err := client.Send(mdReq); err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	conn.next()
+	conn.send("j", "379=MD_1", "372=V", "380=0", "58=Unknown symbol")
+
+	if err := <-result; !errors.Is(err, ErrRequestRejected) {
+		t.Errorf("Expected ErrRequestRejected, got %v", err)
+	}
+}
+
+func TestSlowWaiterDoesNotStallReader(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	// Never read from; the reader must keep going regardless.
+	stalled := client.addWaiter(func(*ResponseMessage) bool { return true })
+	defer client.removeWaiter(stalled)
+
+	for i := 0; i < 100; i++ {
+		conn.send("8", fmt.Sprintf("11=FILL_%d", i), "37=1", "150=0", "39=0")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := client.WaitForAck(ctx, "FILL_99"); err != nil {
+		t.Errorf("Expected the last ack to arrive, got %v", err)
+	}
+}
+
+// newTestCertificate returns a self-signed certificate for 127.0.0.1.
+func newTestCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "cServer"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestTLSErrorMidSession(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	serverConfig := &tls.Config{Certificates: []tls.Certificate{newTestCertificate(t)}}
+	go func() {
+		raw, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer raw.Close()
+
+		if err := tls.Server(raw, serverConfig).Handshake(); err != nil {
+			return
+		}
+		// Write an application-data record that cannot be decrypted,
+		// bypassing the TLS layer.
+		raw.Write([]byte{23, 3, 3, 0, 32})
+		raw.Write(bytes.Repeat([]byte{0xAA}, 32))
+		time.Sleep(time.Second)
+	}()
+
+	client := NewClient("127.0.0.1", ln.Addr().(*net.TCPAddr).Port, testConfig(), WithSSL(true))
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer client.Disconnect()
+
+	select {
+	case err := <-client.Errors():
+		if !errors.Is(err, ErrTLS) {
+			t.Errorf("Expected ErrTLS, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for TLS error")
+	}
+}
+
+func TestWithTLSConfigIsUsed(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	certificate := newTestCertificate(t)
+	serverConfig := &tls.Config{Certificates: []tls.Certificate{certificate}}
+	go func() {
+		for {
+			raw, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer raw.Close()
+				conn := tls.Server(raw, serverConfig)
+				if err := conn.Handshake(); err != nil {
+					return
+				}
+				io.Copy(io.Discard, conn)
+			}()
+		}
+	}()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+
+	connect := func(opts ...ClientOption) error {
+		client := NewClient("127.0.0.1", port, testConfig(), opts...)
+		err := client.Connect()
+		if err == nil {
+			client.Disconnect()
+		}
+		return err
+	}
+
+	// The self-signed certificate is accepted by the permissive default.
+	logger := &captureLogger{}
+	if err := connect(WithSSL(true), WithLogger(logger)); err != nil {
+		t.Fatalf("Default TLS config should connect: %v", err)
+	}
+	logger.mu.Lock()
+	warned := len(logger.events) > 0 && strings.HasPrefix(logger.events[0], LogLevelWarn+" ")
+	logger.mu.Unlock()
+	if !warned {
+		t.Errorf("Expected a warning about disabled verification, got %v", logger.events)
+	}
+
+	var verificationErr *tls.CertificateVerificationError
+	if err := connect(WithTLSConfig(&tls.Config{InsecureSkipVerify: false})); !errors.As(err, &verificationErr) {
+		t.Errorf("Expected the supplied config to verify the certificate, got %v", err)
+	}
+
+	if err := connect(WithTLSConfig(&tls.Config{RootCAs: roots})); err != nil {
+		t.Errorf("Expected the certificate to verify against the supplied roots: %v", err)
+	}
+
+	if err := connect(WithTLSConfig(&tls.Config{RootCAs: roots}), WithServerName("other.example")); !errors.As(err, &verificationErr) {
+		t.Errorf("Expected the server name to be checked, got %v", err)
+	}
+}
+
+func TestPlainReadErrorIsNotTLS(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	conn.conn.(*net.TCPConn).SetLinger(0)
+	conn.conn.Close()
+
+	select {
+	case err := <-client.Errors():
+		if errors.Is(err, ErrTLS) {
+			t.Errorf("A TCP reset should not be reported as ErrTLS: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for read error")
+	}
+}
+
+func TestQueryOrder(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	go func() {
+		request := conn.next()
+		if request.GetMessageType() != "H" {
+			return
+		}
+		conn.send("8", "11="+request.first(11), "37=42", "150=I", "39=1", "55=1", "54=1", "38=2000", "14=1000", "151=1000")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	report, err := client.QueryOrder(ctx, "ORD_1", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if report.OrderID != "42" || report.OrdStatus != "1" || report.LeavesQty != 1000 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+}
+
+func TestQueryOrderNotFound(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	go func() {
+		request := conn.next()
+		conn.send("8", "11="+request.first(11), "150=8", "39=8", "58=ORDER_NOT_FOUND")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if _, err := client.QueryOrder(ctx, "MISSING", ""); !errors.Is(err, ErrOrderNotFound) {
+		t.Fatalf("Expected ErrOrderNotFound, got %v", err)
+	}
+}
+
+func TestServerThrottleHints(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	if rate, _ := client.RateLimit(); rate != 0 {
+		t.Fatalf("Expected no rate limit before logon, got %d", rate)
+	}
+
+	if err := client.Send(NewLogonRequest(client.config)); err != nil {
+		t.Fatalf("Failed to send logon: %v", err)
+	}
+	conn.next()
+	conn.send("A", "98=0", "108=30", "383=600", "1685=1", "1686=5", "1687=2", "1688=0")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for client.SessionInfo().HeartBtInt == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	info := client.SessionInfo()
+	if info.MaxMessageSize != 600 || info.ThrottleLimit != 5 || info.ThrottleWindow != 2*time.Second {
+		t.Fatalf("Unexpected session info: %+v", info)
+	}
+
+	if rate, per := client.RateLimit(); rate != 5 || per != 2*time.Second {
+		t.Errorf("Expected rate limit 5/2s, got %d/%s", rate, per)
+	}
+
+	order := NewOrderMsg(client.config)
+	order.ClOrdID = "ORD_1"
+	order.Symbol = "1"
+	order.Side = "1"
+	order.OrderQty = 1000
+	order.OrdType = "1"
+	order.PosMaintRptID = strings.Repeat("X", 600)
+	if err := client.Send(order); !errors.Is(err, ErrMessageTooLarge) {
+		t.Errorf("Expected ErrMessageTooLarge, got %v", err)
+	}
+}
+
+func TestRateLimiterWindow(t *testing.T) {
+	limiter := newRateLimiter(2, 100*time.Millisecond)
+
+	if limiter.reserve() != 0 || limiter.reserve() != 0 {
+		t.Fatal("First two events should be allowed")
+	}
+
+	if delay := limiter.reserve(); delay <= 0 || delay > 100*time.Millisecond {
+		t.Errorf("Third event should wait for the window, got %s", delay)
+	}
+}
+
+func TestWithRateLimit(t *testing.T) {
+	newOrder := func(config *Config) *OrderMsg {
+		order := NewOrderMsg(config)
+		order.ClOrdID = "ORDER_1"
+		order.Symbol = "1"
+		order.Side = "1"
+		order.OrderQty = 1000
+		order.OrdType = "1"
+		return order
+	}
+
+	t.Run("fail fast", func(t *testing.T) {
+		server := newTestServer(t)
+		client := server.client(testConfig(), WithRateLimit(2, time.Minute), WithRateLimitFailFast(true))
+		server.accept()
+
+		for i := 0; i < 2; i++ {
+			if err := client.Send(newOrder(client.config)); err != nil {
+				t.Fatalf("Order %d should be allowed: %v", i+1, err)
+			}
+		}
+		if err := client.Send(NewHeartbeat(client.config)); err != nil {
+			t.Errorf("Session messages should not count against the order limit: %v", err)
+		}
+		if err := client.Send(newOrder(client.config)); !errors.Is(err, ErrRateLimited) {
+			t.Errorf("Expected ErrRateLimited, got %v", err)
+		}
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		server := newTestServer(t)
+		client := server.client(testConfig(), WithRateLimit(1, 100*time.Millisecond))
+		server.accept()
+
+		start := time.Now()
+		for i := 0; i < 2; i++ {
+			if err := client.Send(newOrder(client.config)); err != nil {
+				t.Fatalf("Send failed: %v", err)
+			}
+		}
+		if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+			t.Errorf("Expected the second order to wait for the window, took %s", elapsed)
+		}
+	})
+}
+
+func TestLogonResetNotHonored(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	logon := NewLogonRequest(client.config)
+	logon.ResetSeqNum = true
+	if err := client.Send(logon); err != nil {
+		t.Fatalf("Failed to send logon: %v", err)
+	}
+	conn.next()
+
+	conn.seqNum = 56
+	conn.send("A", "98=0", "108=30")
+
+	select {
+	case err := <-client.Errors():
+		if !errors.Is(err, ErrSeqResetIgnored) {
+			t.Fatalf("Expected ErrSeqResetIgnored, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for warning")
+	}
+
+	if seqNum := client.GetInboundSequenceNumber(); seqNum != 57 {
+		t.Errorf("Expected inbound sequence 57, got %d", seqNum)
+	}
+}
+
+func TestReduceOrder(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	replaces := make(chan *ResponseMessage, 1)
+	go func() {
+		status := conn.next()
+		conn.send("8", "11="+status.first(11), "37=42", "150=I", "39=1", "55=1", "54=1", "40=2", "44=1.10000", "38=2000", "14=500")
+
+		replace := conn.next()
+		replaces <- replace
+		conn.send("8", "11="+replace.first(11), "41=ORIG", "37=42", "150=5", "39=5", "38=1000", "14=500")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	report, err := client.ReduceOrder(ctx, "ORIG", 1000)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if report.ExecType != "5" || report.OrderQty != 1000 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	replace := <-replaces
+	if replace.GetMessageType() != "G" || replace.first(41) != "ORIG" || replace.first(38) != "1000.00" || replace.first(44) != "1.10000" {
+		t.Errorf("Unexpected replace request: %s", replace.GetMessage())
+	}
+}
+
+func TestReduceOrderBelowFilled(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	go func() {
+		status := conn.next()
+		conn.send("8", "11="+status.first(11), "37=42", "150=I", "39=1", "38=2000", "14=1500")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if _, err := client.ReduceOrder(ctx, "ORIG", 1000); err == nil {
+		t.Fatal("Expected reducing below the filled quantity to fail")
+	}
+}
+
+func TestChecksumDiagnostics(t *testing.T) {
+	server := newTestServer(t)
+	logger := &captureLogger{}
+	client := server.client(testConfig(), WithChecksumDiagnostics(true), WithLogger(logger))
+	conn := server.accept()
+
+	good := buildTestMessage("0", 1)
+	corrupted := buildTestMessage("0", 2)
+	corrupted = corrupted[:len(corrupted)-4] + "999\x01"
+
+	if _, err := conn.conn.Write([]byte(good + corrupted)); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-client.Messages():
+		case <-time.After(2 * time.Second):
+			t.Fatal("Both messages should still be delivered")
+		}
+	}
+
+	select {
+	case err := <-client.Errors():
+		if !errors.Is(err, ErrChecksumMismatch) {
+			t.Fatalf("Expected ErrChecksumMismatch, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "00000000  38 3d 46 49 58") {
+			t.Errorf("Expected a hex dump of the frame, got %v", err)
+		}
+	default:
+		t.Fatal("Expected a checksum mismatch to be reported")
+	}
+
+	select {
+	case err := <-client.Errors():
+		t.Errorf("Expected exactly one mismatch, also got %v", err)
+	default:
+	}
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	for _, event := range logger.events {
+		if strings.Contains(event, "checksum ok") {
+			return
+		}
+	}
+	t.Errorf("Expected the good frame to be logged, got %q", logger.events)
+}
+
+func TestChecksumValidationDropsCorruptedMessage(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig(), WithChecksumValidation(true))
+	conn := server.accept()
+
+	corrupted := buildTestMessage("1", 2, "112=CORRUPTED")
+	corrupted = corrupted[:len(corrupted)-4] + "999\x01"
+	frames := buildTestMessage("1", 1, "112=FIRST") + corrupted + buildTestMessage("1", 3, "112=LAST")
+	if _, err := conn.conn.Write([]byte(frames)); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	for _, want := range []string{"FIRST", "LAST"} {
+		select {
+		case msg := <-client.Messages():
+			if id := msg.first(112); id != want {
+				t.Fatalf("Expected %s to be delivered, got %s", want, id)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for %s", want)
+		}
+	}
+
+	select {
+	case err := <-client.Errors():
+		if !errors.Is(err, ErrChecksumMismatch) {
+			t.Fatalf("Expected ErrChecksumMismatch, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the corrupted message to be reported")
+	}
+}
+
+func TestSendAppliesSecurityQtyStep(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	client.SetSecurity(Security{SymbolID: "1", SymbolName: "EURUSD", Digits: 5, QtyStep: 1})
+
+	order := NewOrderMsg(client.config)
+	order.ClOrdID = "QTY_1"
+	order.Symbol = "1"
+	order.Side = "1"
+	order.OrdType = "1"
+	order.OrderQty = 1000
+	if err := client.Send(order); err != nil {
+		t.Fatalf("Failed to send order: %v", err)
+	}
+
+	if qty := conn.next().first(38); qty != "1000" {
+		t.Errorf("Expected 38=1000, got 38=%s", qty)
+	}
+}
+
+func TestIsSymbolTradable(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	if !client.IsSymbolTradable("1") {
+		t.Error("Expected symbol to be tradable without any session status")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.RequestTradingSessionStatus(ctx)
+		done <- err
+	}()
+
+	request := conn.next()
+	conn.send("h", "335="+request.first(335), "340="+TradSesStatusClosed)
+
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if client.IsSymbolTradable("1") {
+		t.Error("Expected symbol not to be tradable while the session is closed")
+	}
+
+	conn.send("h", "55=1", "340="+TradSesStatusOpen)
+	deadline := time.Now().Add(time.Second)
+	for !client.IsSymbolTradable("1") {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected symbol status to override the session status")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSessionGuardRefusesOrderOnQuote(t *testing.T) {
+	config := testConfig()
+	config.SenderSubID = "QUOTE"
+	config.TargetSubID = "QUOTE"
+
+	server := newTestServer(t)
+	client := server.client(config, WithSessionGuard(true))
+	server.accept()
+
+	order := NewOrderMsg(config)
+	order.ClOrdID = "ORD_1"
+	order.Symbol = "1"
+	order.Side = "1"
+	order.OrderQty = 1000
+	order.OrdType = "1"
+
+	err := client.Send(order)
+	if !errors.Is(err, ErrUnsupportedMessage) {
+		t.Fatalf("Expected ErrUnsupportedMessage, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "QUOTE session does not support NewOrderSingle (35=D)") {
+		t.Errorf("Expected error naming the mismatch, got %q", err)
+	}
+	if seq := client.GetMessageSequenceNumber(); seq != 0 {
+		t.Errorf("Expected nothing to be sent, sequence number is %d", seq)
+	}
+
+	mdReq := NewMarketDataRequest(config)
+	mdReq.MDReqID = "MD_1"
+	if err := client.Send(mdReq); err != nil {
+		t.Errorf("Expected market data request to be allowed on QUOTE, got %v", err)
+	}
+}
+
+func TestSessionGuardFollowsTargetSubID(t *testing.T) {
+	config := testConfig()
+	config.SenderSubID = "TRADE"
+	config.TargetSubID = "QUOTE"
+
+	server := newTestServer(t)
+	client := server.client(config, WithSessionGuard(true))
+	server.accept()
+
+	order := NewOrderMsg(config)
+	order.ClOrdID = "ORD_1"
+	order.Symbol = "1"
+	order.Side = "1"
+	order.OrderQty = 1000
+	order.OrdType = "1"
+
+	var unsupported *UnsupportedMessageError
+	if err := client.Send(order); !errors.As(err, &unsupported) || unsupported.Session != "QUOTE" {
+		t.Fatalf("Expected the guard to refuse the order for the QUOTE session, got %v", err)
+	}
+}
+
+func TestReconcileOrdersWithoutTotNumReports(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	go func() {
+		request := conn.next()
+		massStatusReqID := request.first(584)
+		conn.send("8", "584="+massStatusReqID, "11=ORD_1", "37=1", "150=I", "39=0")
+		conn.send("8", "584="+massStatusReqID, "11=ORD_2", "37=2", "150=I", "39=0", "912=Y")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := client.ReconcileOrders(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if orders := client.WorkingOrders(); len(orders) != 2 {
+		t.Errorf("Expected both working orders, got %d", len(orders))
+	}
+}
+
+func TestReconcileOnReconnect(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig(), WithReconcileOnReconnect(true), WithMaxMissedHeartbeats(2), withHeartbeatPeriod(50*time.Millisecond))
+	conn := server.accept()
+
+	if err := client.Send(NewLogonRequest(client.config)); err != nil {
+		t.Fatalf("Failed to send logon: %v", err)
+	}
+	conn.next()
+	conn.send("A", "98=0", "108=30")
+	conn.send("8", "11=ORD_1", "37=1", "150=0", "39=0")
+	conn.send("8", "11=ORD_2", "37=2", "150=0", "39=0")
+
+	deadline := time.Now().Add(time.Second)
+	for len(client.WorkingOrders()) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 2 working orders, got %d", len(client.WorkingOrders()))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// The server goes silent; ORD_1 fills while the client is away.
+	reconnected := server.accept()
+	if msg := reconnected.next(); msg.GetMessageType() != "A" {
+		t.Fatalf("Expected logon on the new connection, got %s", msg.GetMessageType())
+	}
+	reconnected.send("A", "98=0", "108=30")
+
+	request := reconnected.next()
+	if request.GetMessageType() != "AF" {
+		t.Fatalf("Expected OrderMassStatusRequest, got %s", request.GetMessageType())
+	}
+	reconnected.send("8", "584="+request.first(584), "911=1", "912=Y", "11=ORD_2", "37=2", "150=I", "39=1", "14=500")
+
+	deadline = time.Now().Add(time.Second)
+	for {
+		orders := client.WorkingOrders()
+		if len(orders) == 1 && orders[0].ClOrdID == "ORD_2" && orders[0].CumQty == 500 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected only ORD_2 to be working after reconciling, got %+v", orders)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSendRejectsInvalidIDs(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	server.accept()
+
+	posReq := NewRequestForPositions(client.config)
+	posReq.PosReqID = "POS|1"
+	if err := client.Send(posReq); err == nil || !strings.Contains(err.Error(), "PosReqID") {
+		t.Errorf("Expected PosReqID to be rejected before sending, got %v", err)
+	}
+	if seq := client.GetMessageSequenceNumber(); seq != 0 {
+		t.Errorf("Expected nothing to be sent, sequence number is %d", seq)
+	}
+}
+
+func TestDoneClosesOnDisconnect(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	done := client.Done()
+	select {
+	case <-done:
+		t.Fatal("Done should not be closed while connected")
+	default:
+	}
+
+	conn.conn.Close()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Done was not closed after the connection was lost")
+	}
+
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to reconnect: %v", err)
+	}
+	server.accept()
+
+	done = client.Done()
+	client.Disconnect()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Done was not closed after Disconnect")
+	}
+}
+
+func TestReplayRecordedSession(t *testing.T) {
+	server := newTestServer(t)
+
+	var recording bytes.Buffer
+	client := server.client(testConfig(), WithRecorder(&recording))
+	conn := server.accept()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	go func() {
+		conn.next()
+		conn.send("A", "98=0", "108=30")
+		conn.next()
+		conn.send("8", "11=ORD_1", "37=1", "150=0", "39=0")
+	}()
+
+	_, err := client.SendAndWait(ctx, NewLogonRequest(client.config), func(msg *ResponseMessage) bool {
+		return msg.GetMessageType() == "A"
+	})
+	if err != nil {
+		t.Fatalf("Failed to log on: %v", err)
+	}
+
+	order := NewOrderMsg(client.config)
+	order.ClOrdID = "ORD_1"
+	order.Symbol = "1"
+	order.Side = "1"
+	order.OrderQty = 1000
+	order.OrdType = "1"
+
+	if _, err := client.PlaceOrder(ctx, order); err != nil {
+		t.Fatalf("Failed to place order: %v", err)
+	}
+	client.Disconnect()
+
+	messages, err := ReadRecording(&recording)
+	if err != nil {
+		t.Fatalf("Failed to read recording: %v", err)
+	}
+	if len(messages) != 4 || !messages[0].Outbound || messages[1].Outbound || !messages[2].Outbound {
+		t.Fatalf("Unexpected recording: %+v", messages)
+	}
+
+	replayClient := server.client(testConfig())
+	replayConn := server.accept()
+	// Shift the replayed session's sequence numbers to check they are restamped.
+	replayClient.ChangeMessageSequenceNumber(10)
+
+	go func() {
+		logon := replayConn.next()
+		if err := NewProtocol("\x01").validateChecksum(strings.ReplaceAll(logon.GetMessage(), "|", "\x01")); err != nil {
+			t.Errorf("Replayed logon has a bad checksum: %v", err)
+		}
+		if seq := logon.first(34); seq != "11" {
+			t.Errorf("Expected replayed logon to use MsgSeqNum 11, got %s", seq)
+		}
+		replayConn.send("A", "98=0", "108=30")
+
+		if msg := replayConn.next(); msg.first(11) != "ORD_1" {
+			t.Errorf("Expected replayed order ORD_1, got %q", msg.GetMessage())
+		}
+		replayConn.send("8", "11=ORD_1", "37=2", "150=0", "39=0")
+	}()
+
+	replayer := NewReplayer(replayClient, messages)
+	replayer.Speed = 0
+	result, err := replayer.Run(ctx)
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if result.Matched != 2 || len(result.Divergences) != 0 {
+		t.Errorf("Expected 2 matched responses, got %+v", result)
+	}
+}
+
+func TestGroupCountMismatchReported(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	conn.send("W", "262=MD_1", "268=2", "269=0", "270=1.10000")
+
+	select {
+	case err := <-client.Errors():
+		if !errors.Is(err, ErrMalformedMessage) {
+			t.Errorf("Expected ErrMalformedMessage, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a malformed message warning")
+	}
+}
+
+func TestBestPricesStaleness(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	if _, _, _, ok := client.BestPrices("1"); ok {
+		t.Fatal("Expected no prices before any market data")
+	}
+
+	waitForPrices := func(bid float64) time.Duration {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			if got, _, age, ok := client.BestPrices("1"); ok && got == bid {
+				return age
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected bid %v", bid)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	conn.send("W", "262=MD_1", "55=1", "268=2", "269=0", "270=1.10000", "269=1", "270=1.10020")
+	waitForPrices(1.10000)
+
+	time.Sleep(100 * time.Millisecond)
+	bid, ask, stale, _ := client.BestPrices("1")
+	if bid != 1.10000 || ask != 1.10020 {
+		t.Errorf("Expected 1.10000/1.10020, got %v/%v", bid, ask)
+	}
+	if stale < 100*time.Millisecond {
+		t.Errorf("Expected age to grow without updates, got %s", stale)
+	}
+
+	conn.send("X", "262=MD_1", "268=1", "279=1", "269=0", "55=1", "270=1.10010")
+	if fresh := waitForPrices(1.10010); fresh >= stale {
+		t.Errorf("Expected age to reset on a new tick, got %s after %s", fresh, stale)
+	}
+}
+
+func TestBestPricesFromDepth(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	waitForPrices := func(wantBid, wantAsk float64) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			bid, ask, _, ok := client.BestPrices("1")
+			if ok && bid == wantBid && ask == wantAsk {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %v/%v, got %v/%v", wantBid, wantAsk, bid, ask)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// The deepest levels come last; they must not be taken as the best.
+	conn.send("W", "262=MD_1", "55=1", "268=4",
+		"269=0", "278=B1", "270=1.10000", "269=0", "278=B2", "270=1.09990",
+		"269=1", "278=A1", "270=1.10020", "269=1", "278=A2", "270=1.10030")
+	waitForPrices(1.10000, 1.10020)
+
+	// Deleting the best bid promotes the next level.
+	conn.send("X", "262=MD_1", "268=1", "279=2", "269=0", "278=B1", "55=1")
+	waitForPrices(1.09990, 1.10020)
+
+	// A new level behind the best leaves it unchanged.
+	conn.send("X", "262=MD_1", "268=1", "279=0", "269=1", "278=A3", "55=1", "270=1.10050")
+	conn.send("X", "262=MD_1", "268=1", "279=0", "269=1", "278=A4", "55=1", "270=1.10010")
+	waitForPrices(1.09990, 1.10010)
+}
+
+func TestRequestSecurityListPaginated(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	type result struct {
+		securities []Security
+		err        error
+	}
+	done := make(chan result, 1)
+	go func() {
+		securities, err := client.RequestSecurityList(ctx)
+		done <- result{securities, err}
+	}()
+
+	request := conn.next()
+	if listType := request.first(559); listType != "4" {
+		t.Errorf("Expected SecurityListRequestType 4, got %s", listType)
+	}
+	reqID := request.first(320)
+
+	conn.send("y", "320="+reqID, "560=0", "393=3", "146=2", "55=1", "1007=EURUSD", "1008=5", "55=2", "1007=GBPUSD", "1008=5")
+
+	select {
+	case r := <-done:
+		t.Fatalf("Expected to wait for the final page, got %+v", r)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if _, exists := client.Security("1"); exists {
+		t.Error("Expected the symbol cache to stay empty until the list is complete")
+	}
+
+	conn.send("y", "320="+reqID, "560=0", "393=3", "146=1", "55=3", "1007=USDJPY", "1008=3")
+
+	r := <-done
+	if r.err != nil {
+		t.Fatalf("Unexpected error: %v", r.err)
+	}
+	if len(r.securities) != 3 {
+		t.Fatalf("Expected 3 securities, got %+v", r.securities)
+	}
+	if security, exists := client.Security("3"); !exists || security.SymbolName != "USDJPY" || security.Digits != 3 {
+		t.Errorf("Expected USDJPY in the symbol cache, got %+v", security)
+	}
+}
+
+func TestPostLogonHooks(t *testing.T) {
+	var order []string
+	var mu sync.Mutex
+	ran := make(chan struct{}, 4)
+
+	hook := func(name string) func(*Client) error {
+		return func(*Client) error {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			ran <- struct{}{}
+			return nil
+		}
+	}
+
+	server := newTestServer(t)
+	client := server.client(testConfig(), WithPostLogonHooks(hook("symbols"), hook("subscribe")), WithMaxMissedHeartbeats(2), withHeartbeatPeriod(50*time.Millisecond))
+	conn := server.accept()
+
+	if err := client.Send(NewLogonRequest(client.config)); err != nil {
+		t.Fatalf("Failed to send logon: %v", err)
+	}
+	conn.next()
+	conn.send("A", "98=0", "108=30")
+
+	waitForHooks := func() {
+		t.Helper()
+		for i := 0; i < 2; i++ {
+			select {
+			case <-ran:
+			case <-time.After(2 * time.Second):
+				t.Fatal("Expected post-logon hooks to run")
+			}
+		}
+	}
+	waitForHooks()
+
+	// The server goes silent and the client reconnects and logs on again.
+	reconnected := server.accept()
+	reconnected.next()
+	reconnected.send("A", "98=0", "108=30")
+	waitForHooks()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := strings.Join(order, ","); got != "symbols,subscribe,symbols,subscribe" {
+		t.Errorf("Expected hooks to run in order after each logon, got %s", got)
+	}
+}
+
+func TestInboundSeqNumTooLow(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	conn.send("0")
+	conn.send("0")
+	conn.seqNum = 1 // The next message repeats MsgSeqNum 2 without PossDupFlag.
+	conn.send("0")
+
+	logout := conn.next()
+	if logout.GetMessageType() != "5" {
+		t.Fatalf("Expected logout, got %s", logout.GetMessageType())
+	}
+	if text := logout.first(58); !strings.HasPrefix(text, "MsgSeqNum too low") {
+		t.Errorf("Expected logout text to explain the error, got %q", text)
+	}
+
+	select {
+	case <-client.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the session to end")
+	}
+
+	select {
+	case err := <-client.Errors():
+		if !errors.Is(err, ErrSeqNumTooLow) {
+			t.Errorf("Expected ErrSeqNumTooLow, got %v", err)
+		}
+	default:
+		t.Error("Expected the error to be reported")
+	}
+}
+
+func TestInboundSeqNumGap(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	conn.send("0")
+	conn.seqNum = 4 // MsgSeqNums 2 to 4 are missing.
+	conn.send("0")
+
+	resend := conn.next()
+	if resend.GetMessageType() != "2" {
+		t.Fatalf("Expected resend request, got %s", resend.GetMessageType())
+	}
+	if begin, end := resend.first(7), resend.first(16); begin != "2" || end != "0" {
+		t.Errorf("Expected resend of 2 to 0, got %s to %s", begin, end)
+	}
+
+	// Resent messages carry PossDupFlag and are accepted.
+	conn.seqNum = 1
+	conn.send("0", "43=Y")
+	conn.seqNum = 5
+	conn.send("0")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for client.GetInboundSequenceNumber() != 6 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected inbound sequence number 6, got %d", client.GetInboundSequenceNumber())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !client.IsConnected() {
+		t.Error("Expected the session to continue")
+	}
+}
+
+func TestSequenceResetGapFill(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	waitInbound := func(want int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for client.GetInboundSequenceNumber() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected inbound sequence number %d, got %d", want, client.GetInboundSequenceNumber())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	conn.send("0")
+	conn.seqNum = 4 // MsgSeqNums 2 to 4 are missing.
+	conn.send("0")
+	if resend := conn.next(); resend.GetMessageType() != "2" {
+		t.Fatalf("Expected resend request, got %s", resend.GetMessageType())
+	}
+
+	// Recovery: 2 is resent, 3 and 4 are skipped with a gap fill that must
+	// not move the expectation back.
+	conn.seqNum = 1
+	conn.send("0", "43=Y")
+	conn.send("4", "43=Y", "123=Y", "36=5")
+	conn.seqNum = 5
+	conn.send("0")
+	waitInbound(6)
+
+	// A gap fill ahead of the expectation skips forward.
+	conn.send("4", "123=Y", "36=10")
+	conn.seqNum = 9
+	conn.send("0")
+	waitInbound(10)
+
+	// A reset without GapFillFlag may go backwards.
+	conn.send("4", "36=3")
+	conn.seqNum = 2
+	conn.send("0")
+	waitInbound(3)
+
+	gapFill := NewSequenceReset(client.config)
+	gapFill.GapFillFlag = true
+	gapFill.NewSeqNo = 10
+	if err := client.Send(gapFill); err != nil {
+		t.Fatalf("Failed to send gap fill: %v", err)
+	}
+	sent := conn.next()
+	if sent.GetMessageType() != "4" || sent.first(123) != "Y" || sent.first(36) != "10" {
+		t.Fatalf("Expected a gap fill to 10, got %q", sent.GetMessage())
+	}
+
+	if err := client.Send(NewTestRequest(client.config)); err != nil {
+		t.Fatalf("Failed to send test request: %v", err)
+	}
+	if next := conn.next(); next.GetMessageType() != "1" || next.first(34) != "10" {
+		t.Errorf("Expected the next message to be a test request with MsgSeqNum 10, got %q", next.GetMessage())
+	}
+	if !client.IsConnected() {
+		t.Error("Expected the session to continue")
+	}
+}
+
+func TestPlaceOrderAsync(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	newOrder := func(side string) *OrderMsg {
+		order := NewOrderMsg(client.config)
+		order.Symbol = "1"
+		order.Side = side
+		order.OrderQty = 1000
+		order.OrdType = "1"
+		return order
+	}
+
+	buy, err := client.PlaceOrderAsync(newOrder("1"))
+	if err != nil {
+		t.Fatalf("Failed to place buy: %v", err)
+	}
+	sell, err := client.PlaceOrderAsync(newOrder("2"))
+	if err != nil {
+		t.Fatalf("Failed to place sell: %v", err)
+	}
+	if buy.ClOrdID() == sell.ClOrdID() {
+		t.Fatalf("Expected distinct ClOrdIDs, got %s", buy.ClOrdID())
+	}
+
+	first, second := conn.next(), conn.next()
+	conn.send("8", "11="+second.first(11), "37=2", "150=8", "39=8", "58=No liquidity")
+	conn.send("8", "11="+first.first(11), "37=1", "150=F", "39=2")
+
+	for _, future := range []*OrderFuture{buy, sell} {
+		select {
+		case <-future.Done():
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Future for %s did not complete", future.ClOrdID())
+		}
+	}
+
+	if report, err := buy.Result(); err != nil || report.OrdStatus != "2" {
+		t.Errorf("Expected filled buy, got %+v, %v", report, err)
+	}
+	if _, err := sell.Result(); !errors.Is(err, ErrOrderRejected) {
+		t.Errorf("Expected rejected sell, got %v", err)
+	}
+
+	pending, err := client.PlaceOrderAsync(newOrder("1"))
+	if err != nil {
+		t.Fatalf("Failed to place order: %v", err)
+	}
+	conn.next()
+	client.Disconnect()
+
+	if _, err := pending.Result(); !errors.Is(err, ErrConnectionLost) {
+		t.Errorf("Expected ErrConnectionLost once the session ends, got %v", err)
+	}
+}
+
+func TestEchoOutbound(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig(), WithEchoOutbound(true))
+	conn := server.accept()
+
+	order := NewOrderMsg(client.config)
+	order.Symbol = "1"
+	order.Side = "1"
+	order.OrderQty = 1000
+	order.OrdType = "1"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.PlaceOrder(ctx, order)
+		done <- err
+	}()
+
+	sent := conn.next()
+	conn.send("8", "11="+sent.first(11), "37=1", "150=F", "39=2", "55=1", "54=1", "38=1000")
+
+	if err := <-done; err != nil {
+		t.Fatalf("Expected the order to complete on the inbound report, got %v", err)
+	}
+
+	echoed := <-client.Messages()
+	if !echoed.IsOutbound() || echoed.GetMessageType() != "D" || echoed.first(11) != sent.first(11) {
+		t.Errorf("Expected the sent order echoed first, got %q", echoed.GetMessage())
+	}
+
+	received := <-client.Messages()
+	if received.IsOutbound() || received.GetMessageType() != "8" {
+		t.Errorf("Expected the inbound execution report next, got %q", received.GetMessage())
+	}
+}
+
+func TestWithConnOverPipe(t *testing.T) {
+	clientSide, serverSide := net.Pipe()
+	conn := &testConn{t: t, conn: serverSide}
+	t.Cleanup(func() { serverSide.Close() })
+
+	client := NewClient("unused", 0, testConfig(), WithConn(clientSide))
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect over pipe: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.SendAndWait(ctx, NewLogonRequest(client.config), func(msg *ResponseMessage) bool {
+			return msg.GetMessageType() == "A"
+		})
+		done <- err
+	}()
+
+	logon := conn.next()
+	if logon.GetMessageType() != "A" || logon.first(553) != "testuser" {
+		t.Fatalf("Expected a logon over the pipe, got %q", logon.GetMessage())
+	}
+	conn.send("A", "98=0", "108=30")
+
+	if err := <-done; err != nil {
+		t.Fatalf("Expected the logon reply to be dispatched, got %v", err)
+	}
+
+	conn.send("1", "112=PING")
+	select {
+	case msg := <-client.Messages():
+		if msg.GetMessageType() != "A" {
+			t.Fatalf("Expected the logon reply first, got %q", msg.GetMessage())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the logon reply")
+	}
+	select {
+	case msg := <-client.Messages():
+		if msg.first(112) != "PING" {
+			t.Errorf("Expected the test request, got %q", msg.GetMessage())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the test request")
+	}
+
+	if err := client.Connect(); err == nil {
+		t.Error("Expected Connect to fail while connected")
+	}
+}
+
+func TestAutoHeartbeat(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig(), WithAutoHeartbeat(true), WithHeartbeatInterval(100*time.Millisecond))
+	conn := server.accept()
+
+	if err := client.Send(NewLogonRequest(client.config)); err != nil {
+		t.Fatalf("Failed to send logon: %v", err)
+	}
+	conn.next()
+	conn.send("A", "98=0", "108=30")
+
+	if heartbeat := conn.next(); heartbeat.GetMessageType() != "0" {
+		t.Fatalf("Expected a heartbeat after logon, got %q", heartbeat.GetMessage())
+	}
+
+	for i := 0; i < 5; i++ {
+		testRequest := NewTestRequest(client.config)
+		testRequest.TestReqID = fmt.Sprintf("TR%d", i)
+		if err := client.Send(testRequest); err != nil {
+			t.Fatalf("Failed to send test request: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	for i := 0; i < 5; i++ {
+		if msg := conn.next(); msg.GetMessageType() != "1" {
+			t.Fatalf("Expected no heartbeat while sending, got %q", msg.GetMessage())
+		}
+	}
+
+	if heartbeat := conn.next(); heartbeat.GetMessageType() != "0" {
+		t.Fatalf("Expected heartbeats to resume once idle, got %q", heartbeat.GetMessage())
+	}
+}
+
+func TestAutoHeartbeatAdoptsNegotiatedInterval(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig(), WithAutoHeartbeat(true))
+	conn := server.accept()
+
+	if got := client.NegotiatedHeartbeat(); got != 0 {
+		t.Fatalf("Expected no negotiated heartbeat before logon, got %s", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.SendAndWait(ctx, NewLogonRequest(client.config), func(msg *ResponseMessage) bool {
+			return msg.GetMessageType() == "A"
+		})
+		done <- err
+	}()
+	conn.next()
+	conn.send("A", "98=0", "108=20")
+	if err := <-done; err != nil {
+		t.Fatalf("Logon failed: %v", err)
+	}
+
+	if got := client.NegotiatedHeartbeat(); got != 20*time.Second {
+		t.Errorf("Expected a negotiated heartbeat of 20s, got %s", got)
+	}
+	client.mu.RLock()
+	interval := client.outboundHeartbeatPeriod()
+	client.mu.RUnlock()
+	if interval != 20*time.Second {
+		t.Errorf("Expected the heartbeat timer to adopt 20s instead of the configured %ds, got %s", client.config.HeartBeat, interval)
+	}
+}
+
+func TestAutoTestRequestReply(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig(), WithAutoTestRequestReply(true))
+	conn := server.accept()
+
+	if err := client.Send(NewLogonRequest(client.config)); err != nil {
+		t.Fatalf("Failed to send logon: %v", err)
+	}
+	conn.next()
+
+	// The test request arrives before the logon is acknowledged.
+	conn.send("1", "112=EARLY")
+	if reply := conn.next(); reply.GetMessageType() != "0" || reply.first(112) != "EARLY" {
+		t.Fatalf("Expected a heartbeat echoing EARLY, got %q", reply.GetMessage())
+	}
+
+	conn.send("A", "98=0", "108=30")
+	conn.send("1", "112=LATER")
+	if reply := conn.next(); reply.GetMessageType() != "0" || reply.first(112) != "LATER" {
+		t.Fatalf("Expected a heartbeat echoing LATER, got %q", reply.GetMessage())
+	}
+
+	select {
+	case msg := <-client.Messages():
+		if msg.GetMessageType() != "1" {
+			t.Errorf("Expected the test request to still be delivered, got %q", msg.GetMessage())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the test request")
+	}
+}
+
+func TestSequenceStoreResumesAcrossConnections(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seqnums")
+	server := newTestServer(t)
+
+	store, err := NewFileSequenceStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	client := server.client(testConfig(), WithSequenceStore(store))
+	conn := server.accept()
+
+	if err := client.Send(NewLogonRequest(client.config)); err != nil {
+		t.Fatalf("Failed to send logon: %v", err)
+	}
+	conn.next()
+	conn.send("A", "98=0", "108=30")
+	if err := client.Send(NewTestRequest(client.config)); err != nil {
+		t.Fatalf("Failed to send test request: %v", err)
+	}
+	conn.next()
+	conn.send("0")
+	<-client.Messages()
+	<-client.Messages()
+	client.Disconnect()
+
+	// A new process resumes from the file.
+	store, err = NewFileSequenceStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	if store.LoadOutbound() != 2 || store.LoadInbound() != 2 {
+		t.Fatalf("Expected 2/2 persisted, got %d/%d", store.LoadOutbound(), store.LoadInbound())
+	}
+
+	client = server.client(testConfig(), WithSequenceStore(store))
+	conn = server.accept()
+
+	if err := client.Send(NewLogonRequest(client.config)); err != nil {
+		t.Fatalf("Failed to send logon: %v", err)
+	}
+	if logon := conn.next(); logon.first(34) != "3" {
+		t.Errorf("Expected the resumed logon to use MsgSeqNum 3, got %s", logon.first(34))
+	}
+
+	reset := NewLogonRequest(client.config)
+	reset.ResetSeqNum = true
+	if err := client.Send(reset); err != nil {
+		t.Fatalf("Failed to send logon: %v", err)
+	}
+	if logon := conn.next(); logon.first(34) != "1" {
+		t.Errorf("Expected ResetSeqNum to restart at MsgSeqNum 1, got %s", logon.first(34))
+	}
+	if store.LoadOutbound() != 1 || store.LoadInbound() != 0 {
+		t.Errorf("Expected ResetSeqNum to reset the store, got %d/%d", store.LoadOutbound(), store.LoadInbound())
+	}
+}
+
+// resumedClient connects a client whose store expects inbound MsgSeqNum 6
+// and sends a logon without ResetSeqNum.
+func resumedClient(t *testing.T, server *testServer) (*Client, *testConn) {
+	t.Helper()
+
+	store, err := NewFileSequenceStore(filepath.Join(t.TempDir(), "seqnums"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	store.SaveOutbound(3)
+	store.SaveInbound(5)
+
+	client := server.client(testConfig(), WithSequenceStore(store))
+	conn := server.accept()
+	if err := client.Send(NewLogonRequest(client.config)); err != nil {
+		t.Fatalf("Failed to send logon: %v", err)
+	}
+	conn.next()
+	return client, conn
+}
+
+func TestResumedLogonGapRequestsResend(t *testing.T) {
+	server := newTestServer(t)
+	client, conn := resumedClient(t, server)
+
+	conn.seqNum = 7 // MsgSeqNums 6 and 7 were sent while we were offline.
+	conn.send("A", "98=0", "108=30")
+
+	resend := conn.next()
+	if resend.GetMessageType() != "2" {
+		t.Fatalf("Expected resend request, got %s", resend.GetMessageType())
+	}
+	if begin := resend.first(7); begin != "6" {
+		t.Errorf("Expected resend from 6, got %s", begin)
+	}
+	if seqNum := client.GetInboundSequenceNumber(); seqNum != 8 {
+		t.Errorf("Expected inbound sequence 8, got %d", seqNum)
+	}
+}
+
+func TestResumedLogonSeqNumTooLowLogsOut(t *testing.T) {
+	server := newTestServer(t)
+	client, conn := resumedClient(t, server)
+
+	conn.seqNum = 2
+	conn.send("A", "98=0", "108=30")
+
+	logout := conn.next()
+	if logout.GetMessageType() != "5" {
+		t.Fatalf("Expected logout, got %s", logout.GetMessageType())
+	}
+	if text := logout.first(58); !strings.HasPrefix(text, "MsgSeqNum too low") {
+		t.Errorf("Expected logout text to explain the error, got %q", text)
+	}
+
+	select {
+	case <-client.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the session to end")
+	}
+}
+
+func TestFindMessageEndIgnoresCheckSumLikeValues(t *testing.T) {
+	client := NewClient("127.0.0.1", 0, testConfig())
+
+	heartbeat := "8=FIX.4.4\x019=5\x0135=0\x0110=123\x01"
+	idWithCheckSum := "8=FIX.4.4\x019=40\x0135=8\x0111=ORD10=123\x0158=x\x0110=045\x01"
+	textWithCheckSum := "8=FIX.4.4\x019=40\x0135=8\x0158=spread\x0110=1.5 pips\x0110=045\x01"
+
+	tests := []struct {
+		name   string
+		buffer string
+		end    int
+	}{
+		{"value containing 10=", idWithCheckSum, len(idWithCheckSum)},
+		{"delimiter and 10= inside text", textWithCheckSum, len(textWithCheckSum)},
+		{"short checksum", "8=FIX.4.4\x019=40\x0135=8\x0110=45\x01", -1},
+		{"incomplete checksum", "8=FIX.4.4\x019=40\x0135=8\x0110=04", -1},
+		{"two messages", heartbeat + heartbeat, len(heartbeat)},
+	}
+
+	for _, tt := range tests {
+		if end := client.findMessageEnd([]byte(tt.buffer)); end != tt.end {
+			t.Errorf("%s: expected end %d, got %d", tt.name, tt.end, end)
+		}
+	}
+}
+
+// pipeClient returns a connected client and the server end of an in-memory
+// connection to it.
+func pipeClient(t *testing.T, opts ...ClientOption) (*Client, net.Conn) {
+	t.Helper()
+
+	clientSide, serverSide := net.Pipe()
+	client := NewClient("pipe", 0, testConfig(), append(opts, WithConn(clientSide))...)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect over pipe: %v", err)
+	}
+	t.Cleanup(func() {
+		client.Disconnect()
+		serverSide.Close()
+	})
+
+	return client, serverSide
+}
+
+// receiveTestRequests reads n messages from client and checks they are the
+// test requests TR1 to TRn in order.
+func receiveTestRequests(t *testing.T, client *Client, n int) {
+	t.Helper()
+
+	for i := 1; i <= n; i++ {
+		select {
+		case msg := <-client.Messages():
+			if id := msg.first(112); id != fmt.Sprintf("TR%d", i) {
+				t.Fatalf("Expected TR%d, got %q", i, msg.GetMessage())
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for message %d", i)
+		}
+	}
+	select {
+	case msg := <-client.Messages():
+		t.Fatalf("Expected exactly %d messages, got another: %q", n, msg.GetMessage())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestFramingByteAtATime(t *testing.T) {
+	client, server := pipeClient(t)
+
+	message := buildTestMessage("1", 1, "112=TR1")
+	for i := 0; i < len(message); i++ {
+		if _, err := server.Write([]byte{message[i]}); err != nil {
+			t.Fatalf("Failed to write byte %d: %v", i, err)
+		}
+	}
+
+	receiveTestRequests(t, client, 1)
+}
+
+func TestFramingCoalescedMessages(t *testing.T) {
+	client, server := pipeClient(t)
+
+	var buffer string
+	for i := 1; i <= 3; i++ {
+		buffer += buildTestMessage("1", i, fmt.Sprintf("112=TR%d", i))
+	}
+	if _, err := server.Write([]byte(buffer)); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	receiveTestRequests(t, client, 3)
+}
+
+func TestFramingDropsBytesOutsideMessages(t *testing.T) {
+	client, server := pipeClient(t)
+
+	truncated := buildTestMessage("1", 1, "112=LOST")[:30]
+	buffer := "garbage" + truncated + buildTestMessage("1", 1, "112=TR1") + "\r\n" + buildTestMessage("1", 2, "112=TR2")
+	if _, err := server.Write([]byte(buffer)); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	receiveTestRequests(t, client, 2)
+
+	select {
+	case err := <-client.Errors():
+		if !errors.Is(err, ErrMalformedMessage) {
+			t.Errorf("Expected ErrMalformedMessage for the dropped bytes, got %v", err)
+		}
+	default:
+		t.Error("Expected the dropped bytes to be reported")
+	}
+}
+
+func TestReadTimeoutDisconnects(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig(), WithReadTimeout(100*time.Millisecond))
+	conn := server.accept()
+
+	disconnected := make(chan error, 1)
+	client.SetDisconnectedCallback(func(err error) { disconnected <- err })
+
+	conn.send("A", "98=0", "108=30")
+	started := time.Now()
+
+	// The server goes silent.
+	select {
+	case <-client.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the client to disconnect after the read timeout")
+	}
+	if elapsed := time.Since(started); elapsed < 100*time.Millisecond {
+		t.Errorf("Disconnected after %s, before the timeout", elapsed)
+	}
+
+	select {
+	case err := <-disconnected:
+		if !errors.Is(err, ErrReadTimeout) || !errors.Is(err, ErrConnectionLost) {
+			t.Errorf("Expected a read timeout cause, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the disconnected callback")
+	}
+	if client.IsConnected() {
+		t.Error("Expected the client to be disconnected")
+	}
+}
+
+func TestDefaultReadTimeoutFollowsHeartbeat(t *testing.T) {
+	client := NewClient("127.0.0.1", 0, testConfig())
+	if timeout := client.effectiveReadTimeout(); timeout != 60*time.Second {
+		t.Errorf("Expected twice the 30s heartbeat, got %s", timeout)
+	}
+
+	client = NewClient("127.0.0.1", 0, testConfig(), WithReadTimeout(-1))
+	if timeout := client.effectiveReadTimeout(); timeout != 0 {
+		t.Errorf("Expected a negative timeout to disable the deadline, got %s", timeout)
+	}
+}
+
+func TestDefaultReadTimeoutFollowsNegotiatedHeartbeat(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	if err := client.Send(NewLogonRequest(client.config)); err != nil {
+		t.Fatalf("Failed to send logon: %v", err)
+	}
+	conn.next()
+	conn.send("A", "98=0", "108=90")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for client.NegotiatedHeartbeat() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if timeout := client.effectiveReadTimeout(); timeout != 180*time.Second {
+		t.Errorf("Expected twice the server's 90s heartbeat, got %s", timeout)
+	}
+}
+
+func TestAutoReconnect(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig(), WithAutoReconnect(2, 10*time.Millisecond))
+	conn := server.accept()
+
+	attempts := make(chan int, 10)
+	client.SetReconnectingCallback(func(attempt int) { attempts <- attempt })
+
+	if err := client.Send(NewLogonRequest(client.config)); err != nil {
+		t.Fatalf("Failed to send logon: %v", err)
+	}
+	conn.next()
+	conn.send("A", "98=0", "108=30")
+
+	// The server drops the connection; the client reconnects and logs on again.
+	conn.conn.Close()
+	conn = server.accept()
+	if logon := conn.next(); logon.GetMessageType() != "A" || logon.first(34) != "1" {
+		t.Fatalf("Expected a fresh logon after reconnecting, got %q", logon.GetMessage())
+	}
+	if attempt := <-attempts; attempt != 1 {
+		t.Errorf("Expected reconnect attempt 1, got %d", attempt)
+	}
+	select {
+	case <-client.Done():
+		t.Fatal("Done should stay open across a successful reconnect")
+	default:
+	}
+
+	// Now the server goes away for good; the client gives up after two attempts.
+	server.ln.Close()
+	conn.conn.Close()
+
+	select {
+	case <-client.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Done to close after the last attempt")
+	}
+	if client.IsConnected() {
+		t.Error("Expected the client to be disconnected")
+	}
+	for _, want := range []int{1, 2} {
+		if attempt := <-attempts; attempt != want {
+			t.Errorf("Expected attempt %d, got %d", want, attempt)
+		}
+	}
+
+	gaveUp := false
+	for len(client.Errors()) > 0 {
+		if err := <-client.Errors(); errors.Is(err, ErrConnectionLost) {
+			gaveUp = true
+		}
+	}
+	if !gaveUp {
+		t.Error("Expected ErrConnectionLost once reconnecting was abandoned")
+	}
+}
+
+func TestDisconnectStopsAutoReconnect(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig(), WithAutoReconnect(0, 50*time.Millisecond))
+	conn := server.accept()
+
+	server.ln.Close()
+	conn.conn.Close()
+
+	time.Sleep(20 * time.Millisecond)
+	client.Disconnect()
+
+	select {
+	case <-client.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected Disconnect to end the session while reconnecting")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if client.IsConnected() {
+		t.Error("Expected no reconnect after Disconnect")
+	}
+}
+
+func TestLogoutWaitsForServerLogout(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	done := make(chan error, 1)
+	go func() { done <- client.Logout(2 * time.Second) }()
+
+	if msg := conn.next(); msg.GetMessageType() != "5" {
+		t.Fatalf("Expected a logout, got %q", msg.GetMessage())
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("Logout returned before the server answered: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	conn.send("5")
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Expected an acknowledged logout, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for Logout to return")
+	}
+	if client.IsConnected() {
+		t.Error("Expected the connection to be closed after logout")
+	}
+}
+
+func TestLogoutTimesOut(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	err := client.Logout(50 * time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected a timeout error, got %v", err)
+	}
+	if msg := conn.next(); msg.GetMessageType() != "5" {
+		t.Errorf("Expected a logout to have been sent, got %q", msg.GetMessage())
+	}
+	if client.IsConnected() {
+		t.Error("Expected the connection to be closed after an unanswered logout")
+	}
+}
+
+func TestTypedConnectionErrors(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	conn.conn.Close()
+
+	select {
+	case err := <-client.Errors():
+		if !errors.Is(err, ErrConnectionLost) {
+			t.Errorf("Expected ErrConnectionLost, got %v", err)
+		}
+		if errors.Is(err, ErrReadTimeout) {
+			t.Errorf("A closed connection should not be a read timeout: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for read error")
+	}
+
+	select {
+	case <-client.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the session to end")
+	}
+	if err := client.Send(NewTestRequest(client.config)); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Expected Send to return ErrNotConnected, got %v", err)
+	}
+}
+
+func TestResolveSymbols(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	if _, ok := client.ResolveSymbolID("EURUSD"); ok {
+		t.Error("Expected no symbols before a security list arrives")
+	}
+
+	if err := client.RequestAllSymbols(); err != nil {
+		t.Fatalf("Failed to request symbols: %v", err)
+	}
+	request := conn.next()
+	if request.GetMessageType() != "x" || request.first(559) != "4" {
+		t.Fatalf("Expected a request for all securities, got %q", request.GetMessage())
+	}
+
+	conn.send("y", "320="+request.first(320), "560=0", "146=2", "55=1", "1007=EURUSD", "1008=5", "55=3", "1007=USDJPY", "1008=3")
+	select {
+	case <-client.Messages():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the security list")
+	}
+
+	if id, ok := client.ResolveSymbolID("USDJPY"); !ok || id != "3" {
+		t.Errorf("Expected USDJPY to resolve to 3, got %q, %v", id, ok)
+	}
+	if name, ok := client.ResolveSymbolName("1"); !ok || name != "EURUSD" {
+		t.Errorf("Expected 1 to resolve to EURUSD, got %q, %v", name, ok)
+	}
+	if _, ok := client.ResolveSymbolName("2"); ok {
+		t.Error("Expected an unknown ID not to resolve")
+	}
+}
+
+func TestResolveSymbolsWaitsForLastPage(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	if err := client.RequestAllSymbols(); err != nil {
+		t.Fatalf("Failed to request symbols: %v", err)
+	}
+	reqID := conn.next().first(320)
+
+	receive := func() {
+		t.Helper()
+		select {
+		case <-client.Messages():
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for the security list")
+		}
+	}
+
+	conn.send("y", "320="+reqID, "560=0", "393=3", "893=N", "146=2", "55=1", "1007=EURUSD", "55=2", "1007=GBPUSD")
+	receive()
+	if _, ok := client.ResolveSymbolID("EURUSD"); ok {
+		t.Error("Expected symbols to be recorded only once the list is complete")
+	}
+
+	conn.send("y", "320="+reqID, "560=0", "393=3", "893=Y", "146=1", "55=3", "1007=USDJPY")
+	receive()
+	for name, want := range map[string]string{"EURUSD": "1", "GBPUSD": "2", "USDJPY": "3"} {
+		if id, ok := client.ResolveSymbolID(name); !ok || id != want {
+			t.Errorf("Expected %s to resolve to %s, got %q, %v", name, want, id, ok)
+		}
+	}
+}
+
+func TestSubscribeMarketData(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	updates, unsubscribe, err := client.SubscribeMarketData("1", 0)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	request := conn.next()
+	if request.GetMessageType() != "V" || request.first(263) != "1" || request.first(264) != "0" || request.first(55) != "1" {
+		t.Fatalf("Unexpected subscription request: %q", request.GetMessage())
+	}
+	mdReqID := request.first(262)
+
+	next := func() *MarketDataSnapshot {
+		t.Helper()
+		select {
+		case update := <-updates:
+			return update
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for a market data update")
+			return nil
+		}
+	}
+
+	conn.send("W", "262=OTHER", "55=1", "268=1", "269=0", "270=1.2")
+	conn.send("W", "262="+mdReqID, "55=1", "268=2", "269=0", "278=B1", "270=1.10000", "271=1000000", "269=1", "278=A1", "270=1.10020", "271=2000000")
+	if update := next(); update.MDReqID != mdReqID || len(update.Entries) != 2 || update.Entries[0].Price != 1.10000 {
+		t.Errorf("Unexpected snapshot: %+v", update)
+	}
+
+	conn.send("X", "262="+mdReqID, "268=1", "279=0", "269=0", "278=B2", "55=1", "270=1.10010", "271=500000")
+	update := next()
+	if bids := (&MarketDataSnapshot{Entries: update.Entries}).Bids(); len(bids) != 2 || bids[0].EntryID != "B2" {
+		t.Errorf("Expected the increment to add a better bid, got %+v", update.Entries)
+	}
+
+	if err := unsubscribe(); err != nil {
+		t.Fatalf("Failed to unsubscribe: %v", err)
+	}
+	cancel := conn.next()
+	if cancel.GetMessageType() != "V" || cancel.first(263) != "2" || cancel.first(262) != mdReqID {
+		t.Errorf("Unexpected unsubscribe request: %q", cancel.GetMessage())
+	}
+	if _, open := <-updates; open {
+		t.Error("Expected the channel to be closed after unsubscribing")
+	}
+	if err := unsubscribe(); err != nil {
+		t.Errorf("Expected a second unsubscribe to be a no-op, got %v", err)
+	}
+}
+
+func TestSubscribeMarketDataReportsRptSeqGaps(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	updates, _, err := client.SubscribeMarketData("1", 0)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	mdReqID := conn.next().first(262)
+
+	increment := func(rptSeq int) {
+		t.Helper()
+		conn.send("X", "262="+mdReqID, "268=1", "279=0", "269=0", fmt.Sprintf("278=B%d", rptSeq), "55=1", "83="+strconv.Itoa(rptSeq), "270=1.1", "271=1000")
+		select {
+		case <-updates:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for increment %d", rptSeq)
+		}
+	}
+	expectGap := func(expected, received int) {
+		t.Helper()
+		select {
+		case err := <-client.Errors():
+			var gap *MarketDataGapError
+			if !errors.As(err, &gap) || !errors.Is(err, ErrMarketDataGap) {
+				t.Fatalf("Expected a *MarketDataGapError, got %v", err)
+			}
+			if gap.MDReqID != mdReqID || gap.Symbol != "1" || gap.Expected != expected || gap.Received != received {
+				t.Errorf("Unexpected gap: %+v", gap)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected a gap expecting %d, got %d", expected, received)
+		}
+	}
+
+	conn.send("W", "262="+mdReqID, "55=1", "83=10", "268=1", "269=0", "270=1.1", "271=1000")
+	<-updates
+	increment(11)
+	increment(12)
+
+	// 13 is lost and 15 overtakes 14.
+	increment(14)
+	expectGap(13, 14)
+	increment(16)
+	expectGap(15, 16)
+	increment(15)
+	expectGap(17, 15)
+	increment(17)
+
+	select {
+	case err := <-client.Errors():
+		t.Errorf("Expected no further errors, got %v", err)
+	default:
+	}
+
+	// A new snapshot starts the sequence afresh.
+	conn.send("W", "262="+mdReqID, "55=1", "83=40", "268=1", "269=0", "270=1.1", "271=1000")
+	<-updates
+	increment(41)
+	select {
+	case err := <-client.Errors():
+		t.Errorf("Expected no error after a new snapshot, got %v", err)
+	default:
+	}
+}
+
+func TestSubscribeMarketDataReject(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	updates, _, err := client.SubscribeMarketData("999", 1)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	request := conn.next()
+	conn.send("Y", "262="+request.first(262), "281=0", "58=Unknown symbol")
+
+	select {
+	case _, open := <-updates:
+		if open {
+			t.Error("Expected no update for a rejected subscription")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the channel to be closed after a reject")
+	}
+	select {
+	case err := <-client.Errors():
+		var reject *MarketDataRequestReject
+		if !errors.Is(err, ErrRequestRejected) || !errors.As(err, &reject) {
+			t.Fatalf("Expected a *MarketDataRequestReject, got %v", err)
+		}
+		if reject.MDReqID != request.first(262) || reject.ReasonText() != "unknown symbol" {
+			t.Errorf("Unexpected reject: %+v", reject)
+		}
+		if !strings.Contains(err.Error(), "unknown symbol (281=0): Unknown symbol") {
+			t.Errorf("Expected the reason in the error, got %q", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the reject to be reported")
+	}
+}
+
+func TestSendGenericMessage(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	msg := NewGenericMessage("BE", client.config).
+		SetField(923, "USER_1").
+		SetField(924, "1").
+		SetField(553, "trader").
+		SetField(924, "4")
+	if err := client.Send(msg); err != nil {
+		t.Fatalf("Failed to send generic message: %v", err)
+	}
+
+	sent := conn.next()
+	if sent.GetMessageType() != "BE" || sent.first(49) != client.config.SenderCompID || sent.first(34) == "" {
+		t.Errorf("Expected a BE message with the standard header, got %q", sent.GetMessage())
+	}
+	var body []string
+	for _, field := range sent.Fields() {
+		switch field.Tag {
+		case 923, 924, 553:
+			body = append(body, fmt.Sprintf("%d=%s", field.Tag, field.Value))
+		}
+	}
+	if strings.Join(body, " ") != "923=USER_1 924=4 553=trader" {
+		t.Errorf("Expected fields in insertion order with 924 replaced, got %v", body)
+	}
+	if err := client.Send(NewGenericMessage("BE", client.config).SetField(34, "99")); err == nil {
+		t.Error("Expected setting a session tag to be refused")
+	}
+}
+
+// collateralInquiry is an application-defined request sent through the
+// Message interface.
+type collateralInquiry struct {
+	config *Config
+	id     string
+}
+
+func (ci *collateralInquiry) GetMessage(sequenceNumber int) string {
+	return NewGenericMessage("BB", ci.config).SetField(909, ci.id).GetMessage(sequenceNumber)
+}
+
+func TestSendCustomMessageType(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	if err := client.Send(NewTestRequest(client.config)); err != nil {
+		t.Fatalf("Failed to send test request: %v", err)
+	}
+	if err := client.Send(&collateralInquiry{config: client.config, id: "COLL_1"}); err != nil {
+		t.Fatalf("Failed to send custom message: %v", err)
+	}
+
+	if msg := conn.next(); msg.GetMessageType() != "1" || msg.first(34) != "1" {
+		t.Errorf("Expected the test request as MsgSeqNum 1, got %q", msg.GetMessage())
+	}
+	msg := conn.next()
+	if msg.GetMessageType() != "BB" || msg.first(909) != "COLL_1" {
+		t.Errorf("Expected the custom message, got %q", msg.GetMessage())
+	}
+	if seqNum := msg.first(34); seqNum != "2" {
+		t.Errorf("Expected the custom message to be sent as MsgSeqNum 2, got %s", seqNum)
+	}
+}
+
+func TestSendContextCancelsBlockedWrite(t *testing.T) {
+	// Nobody reads the server side of the pipe, so every write blocks.
+	client, serverSide := pipeClient(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- client.SendContext(ctx, NewHeartbeat(client.config))
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("SendContext did not return after cancel")
+	}
+
+	// The cancellation must not leave a deadline behind for later sends.
+	conn := &testConn{t: t, conn: serverSide}
+	go func() {
+		testRequest := NewTestRequest(client.config)
+		testRequest.TestReqID = "TR1"
+		done <- client.Send(testRequest)
+	}()
+	if msg := conn.next(); msg.GetMessageType() != "1" {
+		t.Fatalf("Expected the test request, got %q", msg.GetMessage())
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Send after a canceled SendContext failed: %v", err)
+	}
+}
+
+func TestSendContextDeadline(t *testing.T) {
+	client, _ := pipeClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := client.SendContext(ctx, NewHeartbeat(client.config)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestConnectContextCanceled(t *testing.T) {
+	server := newTestServer(t)
+	port := server.ln.Addr().(*net.TCPAddr).Port
+	client := NewClient("127.0.0.1", port, testConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := client.ConnectContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if client.IsConnected() {
+		t.Fatal("Expected the client to stay disconnected")
+	}
+
+	if err := client.ConnectContext(context.Background()); err != nil {
+		t.Fatalf("ConnectContext failed: %v", err)
+	}
+	client.Disconnect()
+}
+
+func TestRejectCallback(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	rejects := make(chan *Reject, 1)
+	client.SetRejectCallback(func(reject *Reject) {
+		rejects <- reject
+	})
+
+	conn.send("3", "45=2", "371=44", "373=5", "58=Invalid price")
+
+	select {
+	case reject := <-rejects:
+		if reject.RefSeqNum != 2 || reject.RefTagID != 44 || reject.SessionRejectReason != "5" || reject.Text != "Invalid price" {
+			t.Errorf("Unexpected reject: %+v", reject)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Reject callback was not called")
+	}
+}
+
+// captureLogger is a Logger that keeps everything it is given.
+type captureLogger struct {
+	mu       sync.Mutex
+	outbound []string
+	inbound  []string
+	events   []string
+}
+
+func (l *captureLogger) LogOutbound(raw string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.outbound = append(l.outbound, raw)
+}
+
+func (l *captureLogger) LogInbound(raw string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inbound = append(l.inbound, raw)
+}
+
+func (l *captureLogger) LogEvent(level, msg string, kv ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, level+" "+msg)
+}
+
+func TestEncryptMethodWarning(t *testing.T) {
+	logger := &captureLogger{}
+	config := testConfig()
+	config.EncryptMethod = 1
+	server := newTestServer(t)
+	server.client(config, WithLogger(logger))
+	server.accept()
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	for _, event := range logger.events {
+		if strings.HasPrefix(event, LogLevelWarn+" EncryptMethod 1") {
+			return
+		}
+	}
+	t.Errorf("Expected a warning about EncryptMethod, got %v", logger.events)
+}
+
+func TestWithLogger(t *testing.T) {
+	logger := &captureLogger{}
+	server := newTestServer(t)
+	client := server.client(testConfig(), WithLogger(logger))
+	conn := server.accept()
+
+	if err := client.Send(NewHeartbeat(client.config)); err != nil {
+		t.Fatalf("Failed to send heartbeat: %v", err)
+	}
+	conn.next()
+	conn.send("0")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		logger.mu.Lock()
+		received := len(logger.inbound)
+		logger.mu.Unlock()
+		if received > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	client.Disconnect()
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	if len(logger.outbound) != 1 || !strings.Contains(logger.outbound[0], "35=0\x01") {
+		t.Errorf("Expected the heartbeat to be logged as outbound, got %q", logger.outbound)
+	}
+	if len(logger.inbound) != 1 || !strings.Contains(logger.inbound[0], "35=0\x01") {
+		t.Errorf("Expected the server heartbeat to be logged as inbound, got %q", logger.inbound)
+	}
+	if strings.Join(logger.events, ", ") != "info connected, info disconnected" {
+		t.Errorf("Unexpected events: %q", logger.events)
+	}
+}
+
+func TestWriterLogger(t *testing.T) {
+	var out bytes.Buffer
+	logger := NewWriterLogger(&out, "|")
+
+	logger.LogInbound("8=FIX.4.4|9=5|35=0|10=000|")
+	logger.LogEvent(LogLevelError, "connection lost", "attempt", 2)
+
+	got := out.String()
+	for _, want := range []string{"<<< inbound\n", "Message Type: Heartbeat (0)\n", "MsgType: 0\n", "[error] connection lost attempt=2\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in the log, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig(), WithChecksumValidation(true))
+	conn := server.accept()
+
+	if err := client.Send(NewHeartbeat(client.config)); err != nil {
+		t.Fatalf("Failed to send heartbeat: %v", err)
+	}
+	conn.next()
+
+	corrupted := buildTestMessage("1", 2, "112=CORRUPTED")
+	corrupted = corrupted[:len(corrupted)-4] + "999\x01"
+	frames := buildTestMessage("0", 1) + corrupted +
+		buildTestMessage("3", 2, "45=1", "373=1") +
+		buildTestMessage("j", 3, "372=V", "380=2")
+	if _, err := conn.conn.Write([]byte(frames)); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-client.Messages():
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for messages")
+		}
+	}
+
+	metrics := client.Metrics()
+	if metrics.Sent["0"] != 1 {
+		t.Errorf("Expected 1 heartbeat sent, got %v", metrics.Sent)
+	}
+	for _, msgType := range []string{"0", "3", "j"} {
+		if metrics.Received[msgType] != 1 {
+			t.Errorf("Expected 1 message of type %s received, got %v", msgType, metrics.Received)
+		}
+	}
+	if metrics.Received["1"] != 0 {
+		t.Errorf("Expected the corrupted message not to count as received, got %v", metrics.Received)
+	}
+	if metrics.Rejects != 2 || metrics.ChecksumFailures != 1 {
+		t.Errorf("Expected 2 rejects and 1 checksum failure, got %d and %d", metrics.Rejects, metrics.ChecksumFailures)
+	}
+	if metrics.LastHeartbeat.IsZero() {
+		t.Error("Expected the last heartbeat time to be set")
+	}
+	if metrics.OutboundSeqNum != 1 || metrics.InboundSeqNum != 3 {
+		t.Errorf("Expected sequence numbers 1/3, got %d/%d", metrics.OutboundSeqNum, metrics.InboundSeqNum)
+	}
+}
+
+// startTestServer starts a testserver.Server that is closed when the test ends.
+func startTestServer(t *testing.T, opts ...testserver.Option) *testserver.Server {
+	t.Helper()
+
+	server, err := testserver.New(opts...)
+	if err != nil {
+		t.Fatalf("Failed to start test server: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+func TestSessionLifecycleWithTestServer(t *testing.T) {
+	server := startTestServer(t, testserver.WithHeartbeatInterval(50*time.Millisecond))
+	client := NewClient(server.Host(), server.Port(), testConfig())
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect() })
+	session, err := server.Accept(2 * time.Second)
+	if err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	logon := NewLogonRequest(client.config)
+	logon.ResetSeqNum = true
+	response, err := client.SendAndWait(ctx, logon, func(msg *ResponseMessage) bool {
+		return msg.GetMessageType() == "A"
+	})
+	if err != nil {
+		t.Fatalf("Logon failed: %v", err)
+	}
+	if response.first(34) != "1" || response.first(49) != "cServer" || response.first(56) != "TEST_SENDER" {
+		t.Errorf("Unexpected logon response %q", response.GetMessage())
+	}
+
+	testRequest := NewTestRequest(client.config)
+	testRequest.TestReqID = "TR1"
+	if _, err := client.SendAndWait(ctx, testRequest, func(msg *ResponseMessage) bool {
+		return msg.GetMessageType() == "0" && msg.first(112) == "TR1"
+	}); err != nil {
+		t.Fatalf("Test request was not answered: %v", err)
+	}
+
+	// The server beats on its own while the client is idle.
+	if _, err := client.SendAndWait(ctx, NewHeartbeat(client.config), func(msg *ResponseMessage) bool {
+		return msg.GetMessageType() == "0" && msg.first(112) == ""
+	}); err != nil {
+		t.Fatalf("Expected a server heartbeat: %v", err)
+	}
+
+	if err := client.Logout(2 * time.Second); err != nil {
+		t.Fatalf("Logout failed: %v", err)
+	}
+	for _, msgType := range []string{"A", "1", "0", "5"} {
+		if _, err := session.Expect(msgType, time.Second); err != nil {
+			t.Fatalf("Unexpected message from the client: %v", err)
+		}
+	}
+	select {
+	case <-session.Closed():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the server to close the connection after the logout")
+	}
+}
+
+func TestWithheldHeartbeatsReconnectWithTestServer(t *testing.T) {
+	server := startTestServer(t)
+	client := NewClient(server.Host(), server.Port(), testConfig(), WithMaxMissedHeartbeats(2), withHeartbeatPeriod(50*time.Millisecond))
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect() })
+	session, err := server.Accept(2 * time.Second)
+	if err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+	session.WithholdHeartbeats(true)
+
+	if err := client.Send(NewLogonRequest(client.config)); err != nil {
+		t.Fatalf("Failed to send logon: %v", err)
+	}
+
+	reconnected, err := server.Accept(2 * time.Second)
+	if err != nil {
+		t.Fatalf("Expected the client to reconnect to the silent server: %v", err)
+	}
+	if _, err := reconnected.Expect("A", 2*time.Second); err != nil {
+		t.Fatalf("Expected a logon on the new connection: %v", err)
+	}
+}
+
+func TestPendingRequestFailsWhenSessionDrops(t *testing.T) {
+	server := startTestServer(t)
+	client := NewClient(server.Host(), server.Port(), testConfig())
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect() })
+	session, err := server.Accept(2 * time.Second)
+	if err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+
+	securityReq := NewSecurityListRequest(client.config)
+	securityReq.SecurityReqID = "SEC_DROP"
+	securityReq.SecurityListRequestType = "0"
+
+	requestErr := make(chan error, 1)
+	ackErr := make(chan error, 1)
+	go func() {
+		_, err := client.Request(context.Background(), securityReq, 320)
+		requestErr <- err
+	}()
+	go func() {
+		ackErr <- client.WaitForAck(context.Background(), "SEC_DROP")
+	}()
+
+	if _, err := session.Expect("x", 2*time.Second); err != nil {
+		t.Fatalf("Expected the security list request: %v", err)
+	}
+	session.Drop()
+
+	for name, errs := range map[string]chan error{"Request": requestErr, "WaitForAck": ackErr} {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, ErrConnectionLost) {
+				t.Errorf("%s: expected ErrConnectionLost, got %v", name, err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s still waiting after the session dropped", name)
+		}
+	}
+}
+
+func TestPossDupDuplicatesAreDropped(t *testing.T) {
+	server := startTestServer(t)
+	client := NewClient(server.Host(), server.Port(), testConfig())
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect() })
+	session, err := server.Accept(2 * time.Second)
+	if err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+
+	logon := NewLogonRequest(client.config)
+	logon.ResetSeqNum = true
+	if err := client.Send(logon); err != nil {
+		t.Fatalf("Failed to send logon: %v", err)
+	}
+	if _, err := session.Expect("A", time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	report := func(execID string) []string {
+		return []string{"11=ORD_1", "17=" + execID, "150=0", "39=0", "55=1", "54=1", "38=1000"}
+	}
+	session.Send("8", report("E1")...)                                      // 2
+	session.SendWithSeqNum(2, "8", append(report("E1"), "43=Y", "97=N")...) // already processed
+	session.SetSeqNum(4)
+	session.Send("8", report("E3")...) // 4, skipping 3
+	if resend, err := session.Expect("2", time.Second); err != nil || resend.Get(7) != "3" {
+		t.Fatalf("Expected a resend request from 3, got %v, %v", resend, err)
+	}
+	session.SendWithSeqNum(3, "8", append(report("E2"), "43=Y")...) // fills the gap
+	session.SendWithSeqNum(2, "8", append(report("E1"), "43=Y")...) // already processed
+	session.SetSeqNum(5)
+	session.Send("1", "112=DONE")
+
+	var execIDs []string
+	for {
+		select {
+		case msg := <-client.Messages():
+			switch msg.GetMessageType() {
+			case "8":
+				execIDs = append(execIDs, msg.first(17))
+				continue
+			case "1":
+			default:
+				continue
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out; delivered so far: %v", execIDs)
+		}
+		break
+	}
+
+	if got := strings.Join(execIDs, ","); got != "E1,E3,E2" {
+		t.Errorf("Expected every report delivered exactly once, got %s", got)
+	}
+	if name := NewProtocol("").GetFieldNames()[43]; name != "PossDupFlag" {
+		t.Errorf("Expected tag 43 to be named PossDupFlag, got %q", name)
+	}
+	if name := NewProtocol("").GetFieldNames()[97]; name != "PossResend" {
+		t.Errorf("Expected tag 97 to be named PossResend, got %q", name)
+	}
+}
+
+func TestResendRequestIsAnsweredFromSendBuffer(t *testing.T) {
+	server := startTestServer(t)
+	client := NewClient(server.Host(), server.Port(), testConfig(), WithSendBuffer(10))
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect() })
+	session, err := server.Accept(2 * time.Second)
+	if err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+
+	order := func(clOrdID string) *OrderMsg {
+		order := NewOrderMsg(client.config)
+		order.ClOrdID, order.Symbol, order.Side, order.OrderQty, order.OrdType = clOrdID, "1", "1", 1000, "1"
+		return order
+	}
+	testRequest := NewTestRequest(client.config)
+	testRequest.TestReqID = "TR1"
+	for _, msg := range []Message{NewLogonRequest(client.config), NewHeartbeat(client.config), order("ORD_1"), testRequest, order("ORD_2")} {
+		if err := client.Send(msg); err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+	}
+	originals := make(map[int]*testserver.Message)
+	for i := 0; i < 5; i++ {
+		msg, err := session.Next(time.Second)
+		if err != nil {
+			t.Fatalf("Expected 5 messages: %v", err)
+		}
+		originals[msg.SeqNum()] = msg
+	}
+
+	session.Send("2", "7=1", "16=0")
+
+	expected := []struct {
+		msgType  string
+		seqNum   int
+		newSeqNo string
+		clOrdID  string
+	}{
+		{"4", 1, "3", ""},
+		{"D", 3, "", "ORD_1"},
+		{"4", 4, "5", ""},
+		{"D", 5, "", "ORD_2"},
+	}
+	for _, want := range expected {
+		msg, err := session.Expect(want.msgType, time.Second)
+		if err != nil {
+			t.Fatalf("Expected %s as %d: %v", want.msgType, want.seqNum, err)
+		}
+		if msg.SeqNum() != want.seqNum || msg.Get(43) != "Y" || msg.Get(122) == "" {
+			t.Errorf("Expected a possible duplicate as %d, got %q", want.seqNum, msg.Raw)
+		}
+		if want.msgType == "4" && (msg.Get(123) != "Y" || msg.Get(36) != want.newSeqNo) {
+			t.Errorf("Expected a gap fill to %s, got %q", want.newSeqNo, msg.Raw)
+		}
+		if want.msgType == "D" {
+			if msg.Get(11) != want.clOrdID || msg.Get(122) != originals[want.seqNum].Get(52) {
+				t.Errorf("Expected %s with its original SendingTime, got %q", want.clOrdID, msg.Raw)
+			}
+		}
+	}
+
+	// The resend leaves the outbound sequence alone.
+	if err := client.Send(NewHeartbeat(client.config)); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+	if msg, err := session.Expect("0", time.Second); err != nil || msg.SeqNum() != 6 {
+		t.Fatalf("Expected the next heartbeat as 6, got %v, %v", msg, err)
+	}
+}
+
+func TestResendRequestWithoutSendBufferIsGapFilled(t *testing.T) {
+	server := startTestServer(t)
+	client := NewClient(server.Host(), server.Port(), testConfig())
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect() })
+	session, err := server.Accept(2 * time.Second)
+	if err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := client.Send(NewHeartbeat(client.config)); err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+		session.Next(time.Second)
+	}
+	session.Send("2", "7=2", "16=0")
+
+	msg, err := session.Expect("4", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.SeqNum() != 2 || msg.Get(36) != "4" || msg.Get(123) != "Y" || msg.Get(43) != "Y" {
+		t.Errorf("Expected a gap fill from 2 to 4, got %q", msg.Raw)
+	}
+}
+
+func TestSplitChannels(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig(), WithSplitChannels(true))
+	conn := server.accept()
+
+	conn.send("A", "98=0", "108=30")
+	conn.send("8", "11=ORD_1", "150=0", "39=0")
+	conn.send("0")
+	conn.send("W", "262=MD_1", "55=1", "268=0")
+
+	receive := func(ch <-chan *ResponseMessage, n int) []string {
+		var msgTypes []string
+		for i := 0; i < n; i++ {
+			select {
+			case msg := <-ch:
+				msgTypes = append(msgTypes, msg.GetMessageType())
+			case <-time.After(2 * time.Second):
+				t.Fatalf("Timed out after %v", msgTypes)
+			}
+		}
+		return msgTypes
+	}
+
+	if got := strings.Join(receive(client.Messages(), 2), ","); got != "8,W" {
+		t.Errorf("Expected only application messages on Messages, got %s", got)
+	}
+	if got := strings.Join(receive(client.AdminMessages(), 2), ","); got != "A,0" {
+		t.Errorf("Expected session messages on AdminMessages, got %s", got)
+	}
+}
+
+func TestFullMessageChannelReportsDrops(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig(), WithMessageBuffer(2))
+	conn := server.accept()
+
+	for i := 1; i <= 3; i++ {
+		conn.send("8", fmt.Sprintf("11=ORD_%d", i), "150=0", "39=0")
+	}
+
+	select {
+	case err := <-client.Errors():
+		if !errors.Is(err, ErrMessageDropped) || !strings.Contains(err.Error(), "MsgSeqNum 3") {
+			t.Fatalf("Expected the third report to be reported as dropped, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the drop to be reported")
+	}
+	if dropped := client.DroppedMessages(); dropped != 1 {
+		t.Errorf("Expected 1 dropped message, got %d", dropped)
+	}
+	for i := 1; i <= 2; i++ {
+		if msg := <-client.Messages(); msg.first(11) != fmt.Sprintf("ORD_%d", i) {
+			t.Errorf("Expected ORD_%d to be delivered, got %s", i, msg.first(11))
+		}
+	}
+}
+
+func TestMessageCallbackReceivesMessagesInOrder(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig(), WithMessageBuffer(1))
+	conn := server.accept()
+
+	received := make(chan string, 10)
+	client.SetMessageCallback(func(msg *ResponseMessage) {
+		received <- msg.first(11)
+	})
+
+	for i := 1; i <= 5; i++ {
+		conn.send("8", fmt.Sprintf("11=ORD_%d", i), "150=0", "39=0")
+	}
+
+	for i := 1; i <= 5; i++ {
+		select {
+		case clOrdID := <-received:
+			if clOrdID != fmt.Sprintf("ORD_%d", i) {
+				t.Fatalf("Expected ORD_%d, got %s", i, clOrdID)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Callback was not called for ORD_%d", i)
+		}
+	}
+
+	// The undrained channel overflowed, but the callback saw every message.
+	if dropped := client.DroppedMessages(); dropped != 0 {
+		t.Errorf("Expected no dropped messages with a callback, got %d", dropped)
+	}
+}
+
+func TestSlowMessageCallbackDoesNotBlockReading(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig())
+	conn := server.accept()
+
+	release := make(chan struct{})
+	received := make(chan string, 2)
+	client.SetMessageCallback(func(msg *ResponseMessage) {
+		received <- msg.first(11)
+		<-release
+	})
+
+	conn.send("8", "11=ORD_1", "150=0", "39=0")
+	conn.send("8", "11=ORD_2", "150=0", "39=0")
+
+	select {
+	case clOrdID := <-received:
+		if clOrdID != "ORD_1" {
+			t.Fatalf("Expected the callback to get ORD_1 first, got %s", clOrdID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Callback was not called")
+	}
+
+	// The callback is still busy with ORD_1, yet both reach the channel.
+	for i := 1; i <= 2; i++ {
+		select {
+		case msg := <-client.Messages():
+			if msg.first(11) != fmt.Sprintf("ORD_%d", i) {
+				t.Errorf("Expected ORD_%d on the channel, got %s", i, msg.first(11))
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Reading was blocked by the callback before ORD_%d", i)
+		}
+	}
+
+	close(release)
+	select {
+	case clOrdID := <-received:
+		if clOrdID != "ORD_2" {
+			t.Errorf("Expected ORD_2 next, got %s", clOrdID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Callback was not called for ORD_2")
+	}
+}
+
+func TestSessionManagerRoutesBothSessions(t *testing.T) {
+	quoteServer := startTestServer(t)
+	tradeServer := startTestServer(t)
+
+	quoteConfig := testConfig()
+	quoteConfig.TargetSubID, quoteConfig.SenderSubID = "QUOTE", "QUOTE"
+	quote := NewClient(quoteServer.Host(), quoteServer.Port(), quoteConfig)
+	trade := NewClient(tradeServer.Host(), tradeServer.Port(), testConfig())
+	trade.SetSecurity(Security{SymbolID: "1", SymbolName: "EURUSD"})
+
+	manager := NewSessionManager(quote, trade)
+	snapshots := make(chan *MarketDataSnapshot, 1)
+	reports := make(chan *ExecutionReport, 1)
+	manager.SetMarketDataCallback(func(snapshot *MarketDataSnapshot) { snapshots <- snapshot })
+	manager.SetExecutionReportCallback(func(report *ExecutionReport) { reports <- report })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := manager.Connect(ctx); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	t.Cleanup(manager.Disconnect)
+
+	quoteSession, err := quoteServer.Accept(time.Second)
+	if err != nil {
+		t.Fatalf("QUOTE session not accepted: %v", err)
+	}
+	tradeSession, err := tradeServer.Accept(time.Second)
+	if err != nil {
+		t.Fatalf("TRADE session not accepted: %v", err)
+	}
+
+	quoteSession.Send("W", "262=MD_1", "55=1", "268=1", "269=0", "270=1.1", "271=1000")
+	tradeSession.Send("8", "11=ORD_1", "37=1", "17=EXEC_1", "150=0", "39=0", "55=1", "54=1")
+
+	select {
+	case snapshot := <-snapshots:
+		if snapshot.Symbol != "1" || len(snapshot.Entries) != 1 {
+			t.Errorf("Unexpected snapshot %+v", snapshot)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for market data from QUOTE")
+	}
+
+	select {
+	case report := <-reports:
+		if report.ClOrdID != "ORD_1" {
+			t.Errorf("Expected ORD_1, got %s", report.ClOrdID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for an execution report from TRADE")
+	}
+
+	if id, ok := quote.ResolveSymbolID("EURUSD"); !ok || id != "1" {
+		t.Errorf("Expected the QUOTE client to resolve a symbol learned on TRADE, got %q, %v", id, ok)
+	}
+	quote.SetSecurity(Security{SymbolID: "2", SymbolName: "GBPUSD"})
+	if name, ok := trade.ResolveSymbolName("2"); !ok || name != "GBPUSD" {
+		t.Errorf("Expected the TRADE client to resolve a symbol learned on QUOTE, got %q, %v", name, ok)
+	}
+}
+
+func TestClockOffset(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig(), WithClockOffset(-time.Hour))
+	conn := server.accept()
+
+	if err := client.Send(NewHeartbeat(client.config)); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	sendingTime, ok := conn.next().GetTime(52)
+	if !ok {
+		t.Fatal("Expected a SendingTime")
+	}
+	if lag := time.Since(sendingTime); lag < 59*time.Minute || lag > 61*time.Minute {
+		t.Errorf("Expected SendingTime an hour behind, got %s", lag)
+	}
+}
+
+func TestAutoClockOffsetAdoptsMeasuredSkew(t *testing.T) {
+	server := newTestServer(t)
+	client := server.client(testConfig(), WithAutoClockOffset(true))
+	conn := server.accept()
+
+	if _, measured := client.ClockSkew(); measured {
+		t.Fatal("Expected no skew before the logon response")
+	}
+
+	// The server's clock is ten minutes ahead.
+	serverTime := time.Now().Add(10 * time.Minute)
+	if _, err := conn.conn.Write([]byte(buildTestMessageAt(serverTime, "A", 1, "98=0", "108=30"))); err != nil {
+		t.Fatalf("Failed to write logon: %v", err)
+	}
+	select {
+	case <-client.AdminMessages():
+	case <-client.Messages():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the logon response")
+	}
+
+	skew, measured := client.ClockSkew()
+	if !measured || skew < 9*time.Minute || skew > 11*time.Minute {
+		t.Fatalf("Expected a skew of about ten minutes, got %s (measured %v)", skew, measured)
+	}
+
+	if err := client.Send(NewHeartbeat(client.config)); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	sendingTime, _ := conn.next().GetTime(52)
+	if ahead := time.Until(sendingTime); ahead < 9*time.Minute || ahead > 11*time.Minute {
+		t.Errorf("Expected SendingTime corrected by the skew, got %s ahead", ahead)
+	}
+}
+
+func TestTimestampPrecision(t *testing.T) {
+	tests := []struct {
+		precision Precision
+		digits    int
+	}{
+		{PrecisionSeconds, 0},
+		{PrecisionMillis, 3},
+		{PrecisionMicros, 6},
+	}
+
+	for _, tt := range tests {
+		server := newTestServer(t)
+		client := server.client(testConfig(), WithTimestampPrecision(tt.precision))
+		conn := server.accept()
+
+		order := NewOrderMsg(client.config)
+		order.ClOrdID = "ORDER_1"
+		order.Symbol = "1"
+		order.Side = "1"
+		order.OrderQty = 1000
+		order.OrdType = "1"
+		if err := client.Send(order); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+
+		msg := conn.next()
+		for _, tag := range []int{52, 60} {
+			value := msg.first(tag)
+			digits := 0
+			if i := strings.IndexByte(value, '.'); i >= 0 {
+				digits = len(value) - i - 1
+			}
+			if digits != tt.digits {
+				t.Errorf("Expected %d fractional digits in tag %d, got %q", tt.digits, tag, value)
+			}
+			if _, ok := msg.GetTime(tag); !ok {
+				t.Errorf("Expected tag %d to parse as a UTCTimestamp, got %q", tag, value)
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package ctrader
+
+import "time"
+
+// Precision is the fractional-second precision of the UTCTimestamp fields a
+// client sends.
+type Precision int
+
+const (
+	PrecisionSeconds Precision = iota // 20060102-15:04:05
+	PrecisionMillis                   // 20060102-15:04:05.000
+	PrecisionMicros                   // 20060102-15:04:05.000000
+)
+
+func (p Precision) layout() string {
+	switch p {
+	case PrecisionMillis:
+		return "20060102-15:04:05.000"
+	case PrecisionMicros:
+		return "20060102-15:04:05.000000"
+	}
+	return "20060102-15:04:05"
+}
+
+// clock produces the timestamps stamped into outbound messages. The zero
+// value is the local time in whole seconds.
+type clock struct {
+	offset    time.Duration
+	precision Precision
+}
+
+func (c clock) now() time.Time {
+	return time.Now().Add(c.offset).UTC()
+}
+
+// format renders t as a UTCTimestamp with the clock's precision.
+func (c clock) format(t time.Time) string {
+	return t.UTC().Format(c.precision.layout())
+}
+
+// setClock makes the request stamp its timestamps with c.
+func (rm *RequestMessage) setClock(c clock) {
+	rm.clock = c
+}
+
+func (rm *rawMessage) setClock(c clock) {
+	rm.clock = c
+}
+
+// WithClockOffset adds d to the local time stamped into SendingTime (52) and
+// the TransactTime (60) of orders, for machines whose clock is off by -d. A
+// skewed clock makes the server reject messages with SessionRejectReason 10
+// (SendingTime accuracy problem).
+func WithClockOffset(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.clockOffset = d
+	}
+}
+
+// WithTimestampPrecision sets the precision of SendingTime (52) and of the
+// TransactTime (60) fields of every message the client sends, for brokers
+// that require one precision throughout. The default is PrecisionSeconds.
+func WithTimestampPrecision(p Precision) ClientOption {
+	return func(c *Client) {
+		c.timestampPrecision = p
+	}
+}
+
+// WithAutoClockOffset makes the client correct its timestamps by the skew
+// measured against the server (see ClockSkew) once one is known, instead of
+// the offset given to WithClockOffset. A logon rejected for SendingTime
+// accuracy is therefore sent correctly when it is retried, e.g. by
+// WithAutoReconnect.
+func WithAutoClockOffset(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.autoClockOffset = enabled
+	}
+}
+
+// ClockSkew returns how far the server's clock is ahead of the local one, as
+// measured from the SendingTime (52) of the server's logon response or of a
+// reject for SendingTime accuracy, and whether it has been measured. It is
+// only as precise as the server's timestamps, typically one second.
+func (c *Client) ClockSkew() (time.Duration, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.clockSkew, c.clockSkewMeasured
+}
+
+// measureClockSkew records the skew between the SendingTime of msg and the
+// local time it arrived.
+func (c *Client) measureClockSkew(msg *ResponseMessage) {
+	sendingTime, ok := msg.GetTime(52)
+	if !ok {
+		return
+	}
+	skew := sendingTime.Sub(time.Now())
+	
+	c.mu.Lock()
+	c.clockSkew, c.clockSkewMeasured = skew, true
+	c.mu.Unlock()
+	c.logger.LogEvent(LogLevelInfo, "clock skew measured", "skew", skew)
+}
+
+// clock returns the clock outbound messages are stamped with. Callers must
+// hold c.mu.
+func (c *Client) clock() clock {
+	if c.autoClockOffset && c.clockSkewMeasured {
+		return clock{offset: c.clockSkew, precision: c.timestampPrecision}
+	}
+	return clock{offset: c.clockOffset, precision: c.timestampPrecision}
+}
@@ -0,0 +1,325 @@
+package ctrader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// waiter queues every inbound message accepted by match until it is removed.
+// The queue is unbounded so the reader never waits on a slow consumer.
+// sessionDone is closed when the reader of the session it was added in stops.
+type waiter struct {
+	match       func(*ResponseMessage) bool
+	mu          sync.Mutex
+	queue       []*ResponseMessage
+	ready       chan struct{}
+	sessionDone <-chan struct{}
+}
+
+// recentMessageLimit bounds the inbound history replayed to new waiters.
+const recentMessageLimit = 64
+
+// correlationTags echo a client-assigned request ID in the server's responses.
+var correlationTags = []int{11, 262, 320, 335, 584, 568, 710, 112}
+
+func (c *Client) addWaiter(match func(*ResponseMessage) bool) *waiter {
+	w := &waiter{match: match, ready: make(chan struct{}, 1)}
+	
+	c.waitMu.Lock()
+	w.sessionDone = c.readerDone
+	c.waiters = append(c.waiters, w)
+	c.waitMu.Unlock()
+	
+	return w
+}
+
+// addWaiterWithHistory is addWaiter, but first queues any recently received
+// messages accepted by match so a response that raced ahead is not missed.
+func (c *Client) addWaiterWithHistory(match func(*ResponseMessage) bool) *waiter {
+	w := &waiter{match: match, ready: make(chan struct{}, 1)}
+	
+	c.waitMu.Lock()
+	w.sessionDone = c.readerDone
+	for _, msg := range c.recent {
+		if match(msg) {
+			w.push(msg)
+		}
+	}
+	c.waiters = append(c.waiters, w)
+	c.waitMu.Unlock()
+	
+	return w
+}
+
+func (c *Client) removeWaiter(w *waiter) {
+	c.waitMu.Lock()
+	defer c.waitMu.Unlock()
+	
+	for i, existing := range c.waiters {
+		if existing == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// notifyWaiters queues message for every waiter that accepts it. It runs on
+// the reader goroutine and never blocks on the waiters' consumers.
+func (c *Client) notifyWaiters(message *ResponseMessage) {
+	c.waitMu.Lock()
+	defer c.waitMu.Unlock()
+	
+	c.recent = append(c.recent, message)
+	if len(c.recent) > recentMessageLimit {
+		c.recent = c.recent[1:]
+	}
+	for _, w := range c.waiters {
+		if w.match(message) {
+			w.push(message)
+		}
+	}
+}
+
+// push queues msg and wakes a pending receive.
+func (w *waiter) push(msg *ResponseMessage) {
+	w.mu.Lock()
+	w.queue = append(w.queue, msg)
+	w.mu.Unlock()
+	
+	select {
+	case w.ready <- struct{}{}:
+	default:
+	}
+}
+
+// pop removes and returns the oldest queued message, if any.
+func (w *waiter) pop() (*ResponseMessage, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	
+	if len(w.queue) == 0 {
+		return nil, false
+	}
+	msg := w.queue[0]
+	w.queue[0] = nil
+	w.queue = w.queue[1:]
+	return msg, true
+}
+
+// SendAndWait sends message and blocks until an inbound message satisfies
+// match, the server rejects the request, or ctx is done. A reject reporting
+// that the message type is unsupported on this session is returned as an
+// *UnsupportedMessageError.
+func (c *Client) SendAndWait(ctx context.Context, message Message, match func(*ResponseMessage) bool) (*ResponseMessage, error) {
+	var response *ResponseMessage
+	err := c.sendAndCollect(ctx, message, match, func(msg *ResponseMessage) (bool, error) {
+		response = msg
+		return true, nil
+	})
+	return response, err
+}
+
+// Request sends message and blocks until the response carrying the request's
+// value of correlationTag arrives, e.g. 320 (SecurityReqID) for a security
+// list, 710 (PosReqID) for positions or 11 (ClOrdID) for an order. Rejects
+// and ctx are handled as by SendAndWait.
+func (c *Client) Request(ctx context.Context, message Message, correlationTag int) (*ResponseMessage, error) {
+	requestID := NewResponseMessage(message.GetMessage(0), c.delimiter).first(correlationTag)
+	if requestID == "" {
+		return nil, fmt.Errorf("invalid message: no value for correlation tag %d", correlationTag)
+	}
+	
+	return c.SendAndWait(ctx, message, func(msg *ResponseMessage) bool {
+		return msg.first(correlationTag) == requestID
+	})
+}
+
+// sendAndCollect sends message and passes every inbound message accepted by
+// match to handle until handle reports completion or an error, the server
+// rejects the request, or ctx is done.
+func (c *Client) sendAndCollect(ctx context.Context, message Message, match func(*ResponseMessage) bool, handle func(*ResponseMessage) (bool, error)) error {
+	pending, err := c.sendPending(ctx, message, match)
+	if err != nil {
+		return err
+	}
+	return pending.collect(ctx, handle)
+}
+
+// pendingRequest is a sent request whose responses have not been collected.
+type pendingRequest struct {
+	client  *Client
+	waiter  *waiter
+	match   func(*ResponseMessage) bool
+	msgType string
+	seqNum  int
+}
+
+// sendPending registers for the responses of message before sending it, so
+// none are missed, and returns them for collection.
+func (c *Client) sendPending(ctx context.Context, message Message, match func(*ResponseMessage) bool) (*pendingRequest, error) {
+	pending := &pendingRequest{client: c, match: match}
+	if typed, ok := message.(interface{ MsgType() string }); ok {
+		pending.msgType = typed.MsgType()
+	}
+	
+	pending.waiter = c.addWaiter(func(msg *ResponseMessage) bool {
+		switch msg.GetMessageType() {
+		case "3", "j":
+			return true
+		}
+		return match(msg)
+	})
+	
+	seqNum, err := c.send(ctx, message)
+	if err != nil {
+		c.removeWaiter(pending.waiter)
+		return nil, err
+	}
+	pending.seqNum = seqNum
+	
+	return pending, nil
+}
+
+// collect passes responses to handle as described for sendAndCollect and
+// unregisters the request when done. If ctx was canceled with a cause, the
+// cause is returned; if the session ends first, an error wrapping
+// ErrConnectionLost is.
+func (p *pendingRequest) collect(ctx context.Context, handle func(*ResponseMessage) (bool, error)) error {
+	defer p.client.removeWaiter(p.waiter)
+	
+	for {
+		msg, err := p.waiter.receive(ctx)
+		if err != nil {
+			return err
+		}
+		if err := p.client.rejectError(msg, p.msgType, p.seqNum); err != nil {
+			return err
+		}
+		if !p.match(msg) {
+			continue
+		}
+		if done, err := handle(msg); done || err != nil {
+			return err
+		}
+	}
+}
+
+// receive returns the next message for w. Messages that arrived before the
+// session ended are still returned; after that an error wrapping
+// ErrConnectionLost is. If ctx is done first, its cause is returned.
+func (w *waiter) receive(ctx context.Context) (*ResponseMessage, error) {
+	for {
+		if msg, ok := w.pop(); ok {
+			return msg, nil
+		}
+		select {
+		case <-w.ready:
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		case <-w.sessionDone:
+			if msg, ok := w.pop(); ok {
+				return msg, nil
+			}
+			return nil, fmt.Errorf("%w: session ended before the response arrived", ErrConnectionLost)
+		}
+	}
+}
+
+// WaitForAck blocks until the server accepts or rejects the request identified
+// by refID, the client-assigned ID of a previously sent request (ClOrdID,
+// MDReqID, SecurityReqID, PosReqID, ...). It returns nil for a positive
+// response and an error wrapping ErrRequestRejected for a reject. Responses
+// received shortly before the call are taken into account. If the session
+// ends before a response arrives, an error wrapping ErrConnectionLost is
+// returned.
+func (c *Client) WaitForAck(ctx context.Context, refID string) error {
+	w := c.addWaiterWithHistory(func(msg *ResponseMessage) bool {
+		return referencesID(msg, refID)
+	})
+	defer c.removeWaiter(w)
+	
+	msg, err := w.receive(ctx)
+	if err != nil {
+		return err
+	}
+	return ackError(msg)
+}
+
+// referencesID reports whether msg is a response to the request with refID.
+func referencesID(msg *ResponseMessage, refID string) bool {
+	if msg.GetMessageType() == "j" {
+		return msg.first(379) == refID
+	}
+	for _, tag := range correlationTags {
+		if msg.first(tag) == refID {
+			return true
+		}
+	}
+	return false
+}
+
+// ackError returns the reject described by msg, or nil if it is a positive
+// response.
+func ackError(msg *ResponseMessage) error {
+	text := msg.first(58)
+	
+	switch msg.GetMessageType() {
+	case "j", "Y", "9":
+		return fmt.Errorf("%w: %s", ErrRequestRejected, text)
+	case "8":
+		if msg.first(150) == "8" || msg.first(39) == "8" {
+			return fmt.Errorf("%w: %s", ErrOrderRejected, text)
+		}
+	case "y":
+		if result := msg.first(560); result != "" && result != "0" {
+			return fmt.Errorf("%w: security request result %s: %s", ErrRequestRejected, result, text)
+		}
+	case "AO", "AP":
+		if result := msg.first(728); result != "" && result != "0" && result != "2" {
+			return fmt.Errorf("%w: position request result %s: %s", ErrRequestRejected, result, text)
+		}
+	}
+	
+	return nil
+}
+
+// nextRequestID returns a request ID with prefix that is unique for the client.
+func (c *Client) nextRequestID(prefix string) string {
+	return fmt.Sprintf("%s_%d_%d", prefix, time.Now().UnixNano(), atomic.AddUint64(&c.requestCounter, 1))
+}
+
+// rejectError returns the error described by msg if it is a session or
+// business reject for the request of msgType sent as seqNum.
+func (c *Client) rejectError(msg *ResponseMessage, msgType string, seqNum int) error {
+	refSeqNum := msg.first(45)
+	text := msg.first(58)
+	
+	switch msg.GetMessageType() {
+	case "3":
+		if refSeqNum != fmt.Sprint(seqNum) {
+			return nil
+		}
+		// SessionRejectReason 11 = Invalid MsgType
+		if msg.first(373) == "11" {
+			return &UnsupportedMessageError{Session: c.sessionName(), MsgType: msgType, Text: text}
+		}
+	case "j":
+		if refSeqNum != "" && refSeqNum != fmt.Sprint(seqNum) {
+			return nil
+		}
+		if refSeqNum == "" && msg.first(372) != msgType {
+			return nil
+		}
+		// BusinessRejectReason 3 = Unsupported Message Type
+		if msg.first(380) == "3" {
+			return &UnsupportedMessageError{Session: c.sessionName(), MsgType: msgType, Text: text}
+		}
+	default:
+		return nil
+	}
+	
+	return fmt.Errorf("%w: %s", ErrRequestRejected, text)
+}
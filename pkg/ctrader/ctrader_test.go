@@ -1,8 +1,14 @@
 package ctrader
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestConfig(t *testing.T) {
@@ -82,6 +88,34 @@ func TestLogonRequest(t *testing.T) {
 	}
 }
 
+func TestLogonRequestConfigDefaults(t *testing.T) {
+	config := &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "TEST_SENDER",
+		TargetCompID: "cServer",
+		Username:     "testuser",
+		Password:     "testpass",
+		HeartBeat:    30,
+		ResetSeqNum:  true,
+	}
+	
+	logon := NewLogonRequest(config)
+	msg := NewResponseMessage(logon.GetMessage(1), "\x01")
+	if msg.first(98) != "0" || msg.first(141) != "Y" {
+		t.Errorf("Expected 98=0 and 141=Y from the config, got %q", logon.GetMessage(1))
+	}
+	
+	logon.ResetSeqNum = false
+	if msg := NewResponseMessage(logon.GetMessage(2), "\x01"); msg.first(141) != "" {
+		t.Errorf("Expected the request to override ResetSeqNum, got %q", logon.GetMessage(2))
+	}
+	
+	config.EncryptMethod = 1
+	if msg := NewResponseMessage(NewLogonRequest(config).GetMessage(1), "\x01"); msg.first(98) != "1" {
+		t.Errorf("Expected 98=1 from the config, got %q", msg.first(98))
+	}
+}
+
 func TestHeartbeat(t *testing.T) {
 	config := &Config{
 		BeginString:  "FIX.4.4",
@@ -220,6 +254,37 @@ func TestOrderMsgWithLimit(t *testing.T) {
 	}
 }
 
+func TestOrderMsgTransactTime(t *testing.T) {
+	config := &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "TEST_SENDER",
+		TargetCompID: "cServer",
+		TargetSubID:  "TRADE",
+		SenderSubID:  "TRADE",
+		Username:     "testuser",
+		Password:     "testpass",
+		HeartBeat:    30,
+	}
+
+	order := NewOrderMsg(config)
+	order.ClOrdID = "ORDER_123"
+	order.Symbol = "1"
+	order.Side = "1"
+	order.OrderQty = 1000
+	order.OrdType = "1"
+	order.TransactTime = time.Date(2023, 11, 1, 10, 30, 15, 0, time.UTC)
+	
+	message := order.GetMessage(1)
+	
+	if !strings.Contains(message, "\x0160=20231101-10:30:15\x01") {
+		t.Errorf("Message should contain the supplied TransactTime, got %q", message)
+	}
+	
+	if message != order.GetMessage(1) {
+		t.Error("Messages with a fixed TransactTime should be reproducible")
+	}
+}
+
 func TestOrderCancelRequest(t *testing.T) {
 	config := &Config{
 		BeginString:  "FIX.4.4",
@@ -255,6 +320,76 @@ func TestOrderCancelRequest(t *testing.T) {
 	}
 }
 
+func TestOrderStatusRequest(t *testing.T) {
+	config := &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "TEST_SENDER",
+		TargetCompID: "cServer",
+		TargetSubID:  "TRADE",
+		SenderSubID:  "TRADE",
+		Username:     "testuser",
+		Password:     "testpass",
+		HeartBeat:    30,
+	}
+
+	statusReq := NewOrderStatusRequest(config)
+	statusReq.ClOrdID = "ORDER_123"
+	statusReq.OrderID = "42"
+	statusReq.Symbol = "1"
+	statusReq.Side = "2"
+	
+	message := statusReq.GetMessage(1)
+	
+	if !strings.Contains(message, "35=H") {
+		t.Error("Message should contain MsgType=H")
+	}
+	
+	for _, expected := range []string{"\x0111=ORDER_123\x01", "\x0137=42\x01", "\x0155=1\x01", "\x0154=2\x01"} {
+		if !strings.Contains(message, expected) {
+			t.Errorf("Expected %q in %q", expected, message)
+		}
+	}
+	
+	if err := NewProtocol("\x01").ValidateMessage(message); err != nil {
+		t.Errorf("Expected a valid message, got %v", err)
+	}
+}
+
+func TestTradeCaptureReportRequest(t *testing.T) {
+	config := &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "TEST_SENDER",
+		TargetCompID: "cServer",
+		TargetSubID:  "TRADE",
+		SenderSubID:  "TRADE",
+		Username:     "testuser",
+		Password:     "testpass",
+		HeartBeat:    30,
+	}
+
+	tradeReq := NewTradeCaptureReportRequest(config)
+	tradeReq.TradeRequestID = "TRADES_1"
+	tradeReq.StartTime = time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	tradeReq.EndTime = time.Date(2023, 11, 2, 0, 0, 0, 0, time.UTC)
+	
+	message := tradeReq.GetMessage(1)
+	
+	for _, expected := range []string{"\x0135=AD\x01", "\x01568=TRADES_1\x01", "\x01569=0\x01", "\x01580=2\x0160=20231101-00:00:00\x0160=20231102-00:00:00\x01"} {
+		if !strings.Contains(message, expected) {
+			t.Errorf("Expected %q in %q", expected, message)
+		}
+	}
+	
+	if err := NewProtocol("\x01").ValidateMessage(message); err != nil {
+		t.Errorf("Expected a valid message, got %v", err)
+	}
+	
+	tradeReq.EndTime = tradeReq.StartTime.Add(-time.Hour)
+	if err := tradeReq.Validate(); err == nil {
+		t.Error("Expected an inverted date range to fail validation")
+	}
+}
+
 func TestMarketDataRequest(t *testing.T) {
 	config := &Config{
 		BeginString:  "FIX.4.4",
@@ -271,9 +406,7 @@ func TestMarketDataRequest(t *testing.T) {
 	mdReq.MDReqID = "MD_REQ_001"
 	mdReq.SubscriptionRequestType = "1"
 	mdReq.MarketDepth = 0
-	mdReq.NoMDEntryTypes = 1
-	mdReq.MDEntryType = "0"
-	mdReq.NoRelatedSym = 1
+	mdReq.MDEntryTypes = []string{"0"}
 	mdReq.Symbol = "EURUSD"
 	
 	message := mdReq.GetMessage(1)
@@ -327,6 +460,60 @@ func TestProtocolValidation(t *testing.T) {
 	}
 }
 
+func TestProtocolValidateOutbound(t *testing.T) {
+	protocol := NewProtocol("\x01")
+	config := &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "SENDER",
+		TargetCompID: "TARGET",
+		TargetSubID:  "TRADE",
+		SenderSubID:  "TRADE",
+		Username:     "user",
+		Password:     "pass",
+		HeartBeat:    30,
+	}
+	
+	order := NewOrderMsg(config)
+	order.ClOrdID = "ORDER_1"
+	order.Symbol = "1"
+	order.Side = "1"
+	order.OrderQty = 1000
+	order.OrdType = "1"
+	
+	for _, message := range []Message{
+		NewLogonRequest(config),
+		NewHeartbeat(config),
+		NewLogoutRequest(config),
+		order,
+		NewRequestForPositions(config),
+	} {
+		raw := message.GetMessage(1)
+		if err := protocol.ValidateOutbound(raw); err != nil {
+			t.Errorf("Expected %q to pass, got %v", raw, err)
+		}
+	}
+	
+	// FIX 4.4 StandardHeader order
+	var tags []int
+	for _, field := range NewResponseMessage(NewHeartbeat(config).GetMessage(7), "\x01").Fields() {
+		tags = append(tags, field.Tag)
+	}
+	if expected := []int{8, 9, 35, 49, 56, 34, 50, 57, 52, 10}; !reflect.DeepEqual(tags, expected) {
+		t.Errorf("Expected header order %v, got %v", expected, tags)
+	}
+	
+	for _, invalid := range []string{
+		"8=FIX.4.4\x019=5\x0135=0\x0149=S\x0156=T\x0157=TRADE\x0134=1\x0152=20240101-00:00:00\x0110=000\x01",
+		"8=FIX.4.4\x0135=0\x019=5\x0149=S\x0156=T\x0134=1\x0152=20240101-00:00:00\x0110=000\x01",
+		"8=FIX.4.4\x019=5\x0135=D\x0149=S\x0156=T\x0111=ORDER_1\x0134=1\x0152=20240101-00:00:00\x0110=000\x01",
+		"8=FIX.4.4\x019=5\x0135=0\x0149=S\x0156=T\x0110=000\x0134=1\x01",
+	} {
+		if err := protocol.ValidateOutbound(invalid); err == nil {
+			t.Errorf("Expected %q to fail", invalid)
+		}
+	}
+}
+
 func TestProtocolFieldNames(t *testing.T) {
 	protocol := NewProtocol("\x01")
 	fieldNames := protocol.GetFieldNames()
@@ -430,3 +617,1466 @@ func TestMessageTimestamp(t *testing.T) {
 		t.Error("Message should be long enough to contain timestamp")
 	}
 }
+
+func TestOrderBuilderMarket(t *testing.T) {
+	config := &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "TEST_SENDER",
+		TargetCompID: "cServer",
+		TargetSubID:  "TRADE",
+		SenderSubID:  "TRADE",
+		Username:     "testuser",
+		Password:     "testpass",
+		HeartBeat:    30,
+	}
+
+	order, err := NewOrder(config).ClOrdID("MKT_1").Symbol("1").Sell().Quantity(1000).Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	message := order.GetMessage(1)
+
+	for _, expected := range []string{"11=MKT_1", "55=1", "54=2", "40=1"} {
+		if !strings.Contains(message, expected) {
+			t.Errorf("Message should contain %s", expected)
+		}
+	}
+
+	if strings.Contains(message, "\x0144=") {
+		t.Error("Market order should not contain a price")
+	}
+}
+
+func TestOrderBuilderLimit(t *testing.T) {
+	config := &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "TEST_SENDER",
+		TargetCompID: "cServer",
+		TargetSubID:  "TRADE",
+		SenderSubID:  "TRADE",
+		Username:     "testuser",
+		Password:     "testpass",
+		HeartBeat:    30,
+	}
+
+	order, err := NewOrder(config).
+		Symbol("1").
+		Buy().
+		Quantity(1000).
+		Limit(1.105).
+		TimeInForce(TimeInForceGTC).
+		StopLoss(1.100).
+		Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	message := order.GetMessage(1)
+
+	for _, expected := range []string{"54=1", "40=2", "44=1.10500", "59=1"} {
+		if !strings.Contains(message, expected) {
+			t.Errorf("Message should contain %s", expected)
+		}
+	}
+
+	if order.Bracket == nil || order.Bracket.StopLoss != 1.100 {
+		t.Errorf("Expected stop loss on the bracket, got %+v", order.Bracket)
+	}
+}
+
+func TestOrderBuilderStopLimitGTD(t *testing.T) {
+	config := &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "TEST_SENDER",
+		TargetCompID: "cServer",
+		TargetSubID:  "TRADE",
+		SenderSubID:  "TRADE",
+		Username:     "testuser",
+		Password:     "testpass",
+		HeartBeat:    30,
+	}
+
+	expireTime := time.Date(2023, 11, 3, 21, 0, 0, 0, time.UTC)
+	order, err := NewOrder(config).
+		Symbol("1").
+		Sell().
+		Quantity(1000).
+		StopLimit(1.099, 1.098).
+		GoodTill(expireTime).
+		Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	message := order.GetMessage(1)
+
+	for _, expected := range []string{"40=4", "44=1.09800", "99=1.09900", "59=6", "126=20231103-21:00:00"} {
+		if !strings.Contains(message, expected) {
+			t.Errorf("Message should contain %s", expected)
+		}
+	}
+
+	if _, err := NewOrder(config).Symbol("1").Buy().Quantity(1000).TimeInForce(TimeInForceGTD).Build(); err == nil {
+		t.Error("Expected a GTD order without expire time to fail validation")
+	}
+
+	market := NewOrderMsg(config)
+	market.Symbol = "1"
+	market.Side = "1"
+	market.OrderQty = 1000
+	market.OrdType = "1"
+	market.StopPx = 1.099
+	if message := market.GetMessage(1); strings.Contains(message, "\x0199=") {
+		t.Errorf("Market order should not carry StopPx, got %q", message)
+	}
+}
+
+func TestOrderBuilderSwitchingTypeClearsPrices(t *testing.T) {
+	config := &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "TEST_SENDER",
+		TargetCompID: "cServer",
+		TargetSubID:  "TRADE",
+		SenderSubID:  "TRADE",
+		Username:     "testuser",
+		Password:     "testpass",
+		HeartBeat:    30,
+	}
+
+	stop, err := NewOrder(config).Symbol("1").Buy().Quantity(1000).Limit(1.1).Stop(1.2).Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if message := stop.GetMessage(1); !strings.Contains(message, "99=1.20000") || strings.Contains(message, "\x0144=") {
+		t.Errorf("Stop order should carry only StopPx, got %q", message)
+	}
+
+	limit, err := NewOrder(config).Symbol("1").Buy().Quantity(1000).StopLimit(1.2, 1.1).Limit(1.05).Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if message := limit.GetMessage(1); !strings.Contains(message, "44=1.05000") || strings.Contains(message, "\x0199=") {
+		t.Errorf("Limit order should carry only Price, got %q", message)
+	}
+}
+
+func TestOrderBuilderInvalid(t *testing.T) {
+	config := &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "TEST_SENDER",
+		TargetCompID: "cServer",
+		TargetSubID:  "TRADE",
+		SenderSubID:  "TRADE",
+		Username:     "testuser",
+		Password:     "testpass",
+		HeartBeat:    30,
+	}
+
+	if _, err := NewOrder(config).Symbol("1").Buy().Quantity(1000).Limit(0).Build(); err == nil {
+		t.Error("Expected a limit order without price to fail validation")
+	}
+
+	if _, err := NewOrder(config).Symbol("1").Quantity(1000).Build(); err == nil {
+		t.Error("Expected an order without side to fail validation")
+	}
+
+	if _, err := NewOrder(config).Symbol("1").Buy().Build(); err == nil {
+		t.Error("Expected an order without quantity to fail validation")
+	}
+}
+
+func TestSupportedMessageTypes(t *testing.T) {
+	config := &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "TEST_SENDER",
+		TargetCompID: "cServer",
+		TargetSubID:  "TRADE",
+		SenderSubID:  "TRADE",
+		Username:     "testuser",
+		Password:     "testpass",
+		HeartBeat:    30,
+	}
+
+	outbound := strings.Join(SupportedOutboundTypes(), ",")
+	if !strings.Contains(outbound, "D") || !strings.Contains(outbound, "AN") {
+		t.Errorf("Expected D and AN in outbound types, got %s", outbound)
+	}
+
+	for _, msgType := range SupportedOutboundTypes() {
+		message, err := NewMessage(msgType, config)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", msgType, err)
+		}
+		if typed := message.(interface{ MsgType() string }); typed.MsgType() != msgType {
+			t.Errorf("Builder for %s produced %s", msgType, typed.MsgType())
+		}
+	}
+
+	parsed := strings.Join(SupportedParsers(), ",")
+	if !strings.Contains(parsed, "8") {
+		t.Errorf("Expected 8 in parsers, got %s", parsed)
+	}
+
+	msg := NewResponseMessage("8=FIX.4.4\x0135=8\x0111=ORD_1\x0139=2\x0110=000\x01", "\x01")
+	report, err := Parse(msg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.(*ExecutionReport).ClOrdID != "ORD_1" {
+		t.Errorf("Expected parsed ClOrdID ORD_1, got %+v", report)
+	}
+}
+
+func TestOrderQtyFormatting(t *testing.T) {
+	config := &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "TEST_SENDER",
+		TargetCompID: "cServer",
+		TargetSubID:  "TRADE",
+		SenderSubID:  "TRADE",
+		Username:     "testuser",
+		Password:     "testpass",
+		HeartBeat:    30,
+	}
+
+	units := NewOrderMsg(config)
+	units.Symbol = "1"
+	units.Side = "1"
+	units.OrdType = "1"
+	units.OrderQty = 1000
+	units.QtyStep = 1000
+	
+	if message := units.GetMessage(1); !strings.Contains(message, "\x0138=1000\x01") {
+		t.Errorf("Integer-unit symbol should send whole units, got %q", message)
+	}
+
+	lots := NewOrderMsg(config)
+	lots.Symbol = "2"
+	lots.Side = "1"
+	lots.OrdType = "1"
+	lots.OrderQty = 0.015
+	lots.QtyStep = 0.001
+	
+	if message := lots.GetMessage(1); !strings.Contains(message, "\x0138=0.015\x01") {
+		t.Errorf("Fractional-lot symbol should use the step precision, got %q", message)
+	}
+}
+
+func TestTinyQuantityNotExponential(t *testing.T) {
+	config := &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "TEST_SENDER",
+		TargetCompID: "cServer",
+		TargetSubID:  "TRADE",
+		SenderSubID:  "TRADE",
+		Username:     "testuser",
+		Password:     "testpass",
+		HeartBeat:    30,
+	}
+
+	order := NewOrderMsg(config)
+	order.Symbol = "1"
+	order.Side = "1"
+	order.OrdType = "3"
+	order.OrderQty = 1e-5
+	order.StopPx = 0.00000812
+	
+	message := order.GetMessage(1)
+	if strings.Contains(message, "e-") {
+		t.Errorf("Expected plain decimal notation, got %q", message)
+	}
+	for _, expected := range []string{"\x0138=0.00001\x01", "\x0199=0.00000812\x01"} {
+		if !strings.Contains(message, expected) {
+			t.Errorf("Expected %q in %q", expected, message)
+		}
+	}
+	
+	replace := NewOrderCancelReplaceRequest(config)
+	replace.OrderQty = 0.30000000000000004
+	replace.Price = 1e-7
+	
+	message = replace.GetMessage(1)
+	for _, expected := range []string{"\x0138=0.30\x01", "\x0144=0.0000001\x01"} {
+		if !strings.Contains(message, expected) {
+			t.Errorf("Expected %q in %q", expected, message)
+		}
+	}
+}
+
+func TestProtocolFormatMessageGroups(t *testing.T) {
+	protocol := NewProtocol("\x01")
+	message := "8=FIX.4.4\x019=100\x0135=W\x0149=cServer\x0156=SENDER\x0134=2\x0152=20231101-10:00:00\x01262=MD_1\x0155=1\x01268=2\x01269=0\x01270=1.10000\x01271=1000000\x01269=1\x01270=1.10020\x01271=2000000\x0110=123\x01"
+	
+	formatted := protocol.FormatMessage(message)
+	
+	expected := "NoMDEntries: 2\n" +
+		"  [1]\n" +
+		"    MDEntryType: 0\n" +
+		"    MDEntryPx: 1.10000\n" +
+		"    MDEntrySize: 1000000\n" +
+		"  [2]\n" +
+		"    MDEntryType: 1\n" +
+		"    MDEntryPx: 1.10020\n" +
+		"    MDEntrySize: 2000000\n" +
+		"CheckSum: 123\n"
+	
+	if !strings.Contains(formatted, expected) {
+		t.Errorf("Expected grouped entries in wire order:\n%s\ngot:\n%s", expected, formatted)
+	}
+	
+	if strings.Index(formatted, "MDReqID") > strings.Index(formatted, "NoMDEntries") {
+		t.Errorf("Fields should keep wire order:\n%s", formatted)
+	}
+}
+
+func TestTradingSessionStatusParse(t *testing.T) {
+	message := "8=FIX.4.4\x019=100\x0135=h\x0149=cServer\x0156=SENDER\x0134=3\x0152=20231101-10:00:00\x01335=TSS_1\x01336=FX\x01340=2\x01342=20231101-00:00:00\x01344=20231103-21:00:00\x0110=123\x01"
+	
+	parsed, err := Parse(NewResponseMessage(message, "\x01"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	
+	session := parsed.(TradingSession)
+	if session.TradSesReqID != "TSS_1" || session.TradingSessionID != "FX" || session.Status != TradSesStatusOpen {
+		t.Errorf("Unexpected session: %+v", session)
+	}
+	
+	if expected := time.Date(2023, 11, 3, 21, 0, 0, 0, time.UTC); !session.CloseTime.Equal(expected) {
+		t.Errorf("Expected close time %v, got %v", expected, session.CloseTime)
+	}
+	
+	if !session.IsOpen(time.Date(2023, 11, 2, 12, 0, 0, 0, time.UTC)) {
+		t.Error("Expected session to be open within its hours")
+	}
+	
+	if session.IsOpen(time.Date(2023, 11, 4, 12, 0, 0, 0, time.UTC)) {
+		t.Error("Expected session to be closed after its close time")
+	}
+}
+
+func TestTradingSessionStatusRequest(t *testing.T) {
+	config := &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "TEST_SENDER",
+		TargetCompID: "cServer",
+		TargetSubID:  "QUOTE",
+		SenderSubID:  "QUOTE",
+		Username:     "testuser",
+		Password:     "testpass",
+		HeartBeat:    30,
+	}
+	
+	request := NewTradingSessionStatusRequest(config)
+	request.TradSesReqID = "TSS_1"
+	
+	message := request.GetMessage(2)
+	
+	if !strings.Contains(message, "35=g") {
+		t.Error("Message should contain message type g")
+	}
+	
+	if !strings.Contains(message, "335=TSS_1\x01263=0") {
+		t.Errorf("Message should contain request ID and snapshot subscription type, got %q", message)
+	}
+}
+
+func TestRequestIDValidation(t *testing.T) {
+	config := &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "TEST_SENDER",
+		TargetCompID: "cServer",
+		TargetSubID:  "QUOTE",
+		SenderSubID:  "QUOTE",
+		Username:     "testuser",
+		Password:     "testpass",
+		HeartBeat:    30,
+	}
+	
+	order := NewOrderMsg(config)
+	order.ClOrdID = strings.Repeat("A", defaultMaxIDLength+1)
+	order.Symbol = "1"
+	order.Side = "1"
+	order.OrderQty = 1000
+	order.OrdType = "1"
+	
+	if err := order.Validate(); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("Expected overlong ClOrdID to be rejected, got %v", err)
+	}
+	
+	order.ClOrdID = strings.Repeat("A", defaultMaxIDLength)
+	if err := order.Validate(); err != nil {
+		t.Errorf("Expected ClOrdID of maximum length to be accepted, got %v", err)
+	}
+	
+	mdReq := NewMarketDataRequest(config)
+	mdReq.MDReqID = "MD 1=EURUSD"
+	if err := mdReq.Validate(); err == nil || !strings.Contains(err.Error(), "MDReqID") {
+		t.Errorf("Expected illegal characters in MDReqID to be rejected, got %v", err)
+	}
+	
+	mdReq.MDReqID = "MD_EURUSD-1.0:a"
+	if err := mdReq.Validate(); err != nil {
+		t.Errorf("Expected safe MDReqID to be accepted, got %v", err)
+	}
+	
+	mdReq.MDReqID = ""
+	if err := mdReq.Validate(); err == nil || !strings.Contains(err.Error(), "MDReqID is required") {
+		t.Errorf("Expected empty MDReqID to be rejected, got %v", err)
+	}
+}
+
+func TestRequestIDLimitsFromConfig(t *testing.T) {
+	config := &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "TEST_SENDER",
+		TargetCompID: "cServer",
+		TargetSubID:  "QUOTE",
+		SenderSubID:  "QUOTE",
+		Username:     "testuser",
+		Password:     "testpass",
+		HeartBeat:    30,
+		MaxIDLength:  8,
+		IDPattern:    regexp.MustCompile(`^[A-Z0-9]+$`),
+	}
+	
+	mdReq := NewMarketDataRequest(config)
+	mdReq.MDReqID = "MD123456789"
+	if err := mdReq.Validate(); err == nil || !strings.Contains(err.Error(), "exceeds 8") {
+		t.Errorf("Expected the configured length limit to apply, got %v", err)
+	}
+	
+	mdReq.MDReqID = "md_1"
+	if err := mdReq.Validate(); err == nil {
+		t.Error("Expected the configured pattern to apply")
+	}
+	
+	mdReq.MDReqID = "MD1"
+	if err := mdReq.Validate(); err != nil {
+		t.Errorf("Expected MDReqID within the configured limits to be accepted, got %v", err)
+	}
+}
+
+func TestDesignationRoundTrip(t *testing.T) {
+	config := &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "TEST_SENDER",
+		TargetCompID: "cServer",
+		TargetSubID:  "TRADE",
+		SenderSubID:  "TRADE",
+		Username:     "testuser",
+		Password:     "testpass",
+		HeartBeat:    30,
+	}
+	
+	order := NewOrderMsg(config)
+	order.ClOrdID = "ORD_1"
+	order.Symbol = "1"
+	order.Side = "1"
+	order.OrderQty = 1000
+	order.OrdType = "1"
+	order.Designation = "BOOK_A"
+	
+	sent := NewResponseMessage(order.GetMessage(2), "\x01")
+	if designation := sent.GetFieldValue(494); designation != "BOOK_A" {
+		t.Fatalf("Expected 494=BOOK_A in the order, got %v", designation)
+	}
+	
+	report := "8=FIX.4.4\x019=100\x0135=8\x0134=3\x0111=ORD_1\x0137=1\x01150=0\x0139=0\x01494=" + sent.first(494) + "\x0110=000\x01"
+	parsed, err := Parse(NewResponseMessage(report, "\x01"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if designation := parsed.(*ExecutionReport).Designation; designation != "BOOK_A" {
+		t.Errorf("Expected execution report designation BOOK_A, got %q", designation)
+	}
+	
+	posReq := NewRequestForPositions(config)
+	posReq.PosReqID = "POS_1"
+	posReq.Designation = "BOOK_A"
+	if !strings.Contains(posReq.GetMessage(3), "494=BOOK_A") {
+		t.Error("Position request should contain 494=BOOK_A")
+	}
+	
+	position := "8=FIX.4.4\x019=100\x0135=AP\x0134=4\x01710=POS_1\x0155=1\x01494=BOOK_A\x0110=000\x01"
+	parsed, err = Parse(NewResponseMessage(position, "\x01"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if designation := parsed.(*PositionReport).Designation; designation != "BOOK_A" {
+		t.Errorf("Expected position report designation BOOK_A, got %q", designation)
+	}
+}
+
+func TestMarketDataPricesAreNumeric(t *testing.T) {
+	message := "8=FIX.4.4\x019=100\x0135=W\x0134=2\x01262=MD_1\x0155=1\x01268=2\x01269=0\x01270=1.10000\x01269=1\x01270=1.10020\x0110=123\x01"
+	msg := NewResponseMessage(message, "\x01")
+	
+	// The values arrive as text, so asserting them to float64 panics.
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected asserting a raw field value to float64 to panic")
+			}
+		}()
+		_ = msg.GetFieldValue(55).(float64)
+	}()
+	
+	if symbol, ok := msg.GetFloat(55); !ok || symbol != 1 {
+		t.Errorf("Expected GetFloat(55) to return 1, got %v, %v", symbol, ok)
+	}
+	
+	if _, ok := msg.GetFloat(58); ok {
+		t.Error("Expected GetFloat to report false for a missing tag")
+	}
+	
+	bid, ask, ok := msg.BestBidAsk()
+	if !ok {
+		t.Fatal("Expected both bid and ask")
+	}
+	if bid != 1.10000 || ask != 1.10020 {
+		t.Errorf("Expected bid 1.10000 and ask 1.10020, got %v and %v", bid, ask)
+	}
+}
+
+func TestTextWithUnusualCharacters(t *testing.T) {
+	message := "8=FIX.4.4\x019=100\x0135=j\x0134=2\x0145=3\x01380=0\x0158=Price <= 0 & qty=1.5% — 'ignored'\x01see rule #4\x0110=123\x01"
+	msg := NewResponseMessage(message, "\x01")
+	
+	expected := "Price <= 0 & qty=1.5% — 'ignored'\x01see rule #4"
+	if text := msg.GetFieldValue(58); text != expected {
+		t.Errorf("Expected Text %q, got %q", expected, text)
+	}
+	
+	if checksum := msg.GetFieldValue(10); checksum != "123" {
+		t.Errorf("Expected fields after Text to parse, got CheckSum %v", checksum)
+	}
+	
+	if err := msg.checkIntegrity(); err != nil {
+		t.Errorf("Unexpected integrity error: %v", err)
+	}
+}
+
+func TestGroupCountMismatch(t *testing.T) {
+	// The second entry's price was split off by a delimiter inside a value.
+	message := "8=FIX.4.4\x019=100\x0135=W\x0134=2\x0155=1\x01268=2\x01269=0\x01270=1.10000\x01271=10\x01bad\x01269=1\x0110=123\x01"
+	msg := NewResponseMessage(message, "\x01")
+	
+	if err := msg.checkIntegrity(); !errors.Is(err, ErrMalformedMessage) {
+		t.Errorf("Expected ErrMalformedMessage for a stray fragment, got %v", err)
+	}
+	
+	message = "8=FIX.4.4\x019=100\x0135=W\x0134=2\x0155=1\x01268=2\x01269=0\x01270=1.10000\x0110=123\x01"
+	err := NewResponseMessage(message, "\x01").checkIntegrity()
+	if !errors.Is(err, ErrMalformedMessage) || !strings.Contains(err.Error(), "declares 2 entries but 1") {
+		t.Errorf("Expected a group count mismatch, got %v", err)
+	}
+}
+
+func TestTestRequestReplyBodies(t *testing.T) {
+	config := &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "TEST_SENDER",
+		TargetCompID: "cServer",
+		TargetSubID:  "QUOTE",
+		SenderSubID:  "QUOTE",
+		Username:     "testuser",
+		Password:     "testpass",
+		HeartBeat:    30,
+	}
+	
+	testRequest := NewResponseMessage("8=FIX.4.4\x019=20\x0135=1\x0134=5\x01112=TEST_5\x0110=000\x01", "\x01")
+	
+	reply := NewTestRequestReply(config, testRequest)
+	if body := reply.GetBody(); body != "112=TEST_5" {
+		t.Errorf("Expected reply body 112=TEST_5, got %q", body)
+	}
+	
+	// Periodic heartbeats built after a reply must not inherit its TestReqID.
+	periodic := NewHeartbeat(config)
+	if body := periodic.GetBody(); body != "" {
+		t.Errorf("Expected empty periodic heartbeat body, got %q", body)
+	}
+	if message := periodic.GetMessage(6); strings.Contains(message, "112=") {
+		t.Errorf("Periodic heartbeat should not contain TestReqID: %q", message)
+	}
+	
+	missing := NewResponseMessage("8=FIX.4.4\x019=10\x0135=1\x0134=7\x0110=000\x01", "\x01")
+	if body := NewTestRequestReply(config, missing).GetBody(); body != "" {
+		t.Errorf("Expected no TestReqID when the request has none, got %q", body)
+	}
+}
+
+func TestParseLogLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"pipe", "8=FIX.4.4|9=60|35=8|34=5|11=ORD_1|39=2|10=123|"},
+		{"pipe with timestamp", "2023-11-01 10:00:00.123 INFO 8=FIX.4.4|9=60|35=8|34=5|11=ORD_1|39=2|10=123|"},
+		{"soh", "8=FIX.4.4\x019=60\x0135=8\x0134=5\x0111=ORD_1\x0139=2\x0110=123\x01\n"},
+		{"soh with timestamp", "20231101-10:00:00.123 : 8=FIX.4.4\x019=60\x0135=8\x0134=5\x0111=ORD_1\x0139=2\x0110=123\x01"},
+		{"caret", "8=FIX.4.4^9=60^35=8^34=5^11=ORD_1^39=2^10=123^"},
+		{"recording", "2023-11-01T10:00:00.123456789Z < 8=FIX.4.4\x019=60\x0135=8\x0134=5\x0111=ORD_1\x0139=2\x0110=123\x01"},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := ParseLogLine(tt.line)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if msg.GetMessageType() != "8" || msg.GetFieldValue(11) != "ORD_1" || msg.GetFieldValue(10) != "123" {
+				t.Errorf("Unexpected fields: %q", msg.GetMessage())
+			}
+			if msg.GetFieldValue(8) != "FIX.4.4" {
+				t.Errorf("Expected the timestamp prefix to be stripped, got BeginString %v", msg.GetFieldValue(8))
+			}
+		})
+	}
+	
+	if _, err := ParseLogLine("2023-11-01 10:00:00 connection closed"); err == nil {
+		t.Error("Expected an error for a line without a FIX message")
+	}
+}
+
+func TestExecutionReportStatusPredicates(t *testing.T) {
+	tests := []struct {
+		ordStatus string
+		terminal  bool
+		working   bool
+	}{
+		{"0", false, true},  // New
+		{"1", false, true},  // Partially filled
+		{"2", true, false},  // Filled
+		{"3", true, false},  // Done for day
+		{"4", true, false},  // Canceled
+		{"5", false, true},  // Replaced
+		{"6", false, true},  // Pending cancel
+		{"7", false, false}, // Stopped
+		{"8", true, false},  // Rejected
+		{"9", false, false}, // Suspended
+		{"A", false, true},  // Pending new
+		{"B", false, false}, // Calculated
+		{"C", true, false},  // Expired
+		{"D", false, false}, // Accepted for bidding
+		{"E", false, true},  // Pending replace
+		{"", false, false},
+	}
+	
+	for _, tt := range tests {
+		report := &ExecutionReport{OrdStatus: tt.ordStatus}
+		if report.IsTerminal() != tt.terminal {
+			t.Errorf("OrdStatus %q: expected IsTerminal %v", tt.ordStatus, tt.terminal)
+		}
+		if report.IsWorking() != tt.working {
+			t.Errorf("OrdStatus %q: expected IsWorking %v", tt.ordStatus, tt.working)
+		}
+	}
+}
+
+func TestGetGroups(t *testing.T) {
+	snapshot := NewResponseMessage("8=FIX.4.4\x019=100\x0135=W\x0149=cServer\x0156=SENDER\x0134=2\x0152=20231101-10:00:00\x01262=MD_1\x0155=1\x01268=3\x01269=0\x01270=1.10000\x01271=1000000\x01269=0\x01270=1.09990\x01271=3000000\x01269=1\x01270=1.10020\x01271=2000000\x0110=123\x01", "\x01")
+	
+	entries := snapshot.GetGroups(268, 269)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d: %v", len(entries), entries)
+	}
+	expected := []map[int]string{
+		{269: "0", 270: "1.10000", 271: "1000000"},
+		{269: "0", 270: "1.09990", 271: "3000000"},
+		{269: "1", 270: "1.10020", 271: "2000000"},
+	}
+	for i, entry := range entries {
+		for tag, value := range expected[i] {
+			if entry[tag] != value {
+				t.Errorf("Entry %d: expected %d=%s, got %v", i, tag, value, entry)
+			}
+		}
+		if len(entry) != len(expected[i]) {
+			t.Errorf("Entry %d: unexpected fields %v", i, entry)
+		}
+	}
+	
+	incremental := NewResponseMessage("8=FIX.4.4\x019=100\x0135=X\x0149=cServer\x0156=SENDER\x0134=3\x0152=20231101-10:00:01\x01262=MD_1\x01268=2\x01279=0\x01269=1\x01278=ENTRY_2\x0155=1\x01270=1.10030\x01271=500000\x01279=2\x01269=0\x01278=ENTRY_1\x0155=1\x0110=123\x01", "\x01")
+	
+	updates := incremental.GetGroups(268, 279)
+	if len(updates) != 2 || updates[0][278] != "ENTRY_2" || updates[0][270] != "1.10030" || updates[1][279] != "2" || updates[1][278] != "ENTRY_1" {
+		t.Errorf("Unexpected incremental entries: %v", updates)
+	}
+	if _, exists := updates[1][270]; exists {
+		t.Errorf("Delete entry should not borrow a price from another entry: %v", updates[1])
+	}
+	
+	if groups := snapshot.GetGroups(146, 55); groups != nil {
+		t.Errorf("Expected no groups for an absent count tag, got %v", groups)
+	}
+}
+
+func TestTypedAccessors(t *testing.T) {
+	msg := NewResponseMessage("8=FIX.4.4\x019=100\x0135=8\x0134=7\x0152=20231101-10:00:00.123\x0111=ORD_1\x0144=1.10500\x0160=20231101-09:59:59\x0158=\x0110=123\x01", "\x01")
+	
+	if value, ok := msg.GetString(11); !ok || value != "ORD_1" {
+		t.Errorf("Expected GetString(11) = ORD_1, got %q, %v", value, ok)
+	}
+	if value, ok := msg.GetString(58); !ok || value != "" {
+		t.Errorf("Expected an empty but present Text, got %q, %v", value, ok)
+	}
+	if _, ok := msg.GetString(99); ok {
+		t.Error("Expected GetString to report a missing tag")
+	}
+	
+	if value, ok := msg.GetFloat(44); !ok || value != 1.105 {
+		t.Errorf("Expected GetFloat(44) = 1.105, got %v, %v", value, ok)
+	}
+	if _, ok := msg.GetFloat(11); ok {
+		t.Error("Expected GetFloat to report a non-numeric value")
+	}
+	
+	if value, ok := msg.GetInt(34); !ok || value != 7 {
+		t.Errorf("Expected GetInt(34) = 7, got %v, %v", value, ok)
+	}
+	if _, ok := msg.GetInt(44); ok {
+		t.Error("Expected GetInt to report a non-integer value")
+	}
+	if _, ok := msg.GetInt(99); ok {
+		t.Error("Expected GetInt to report a missing tag")
+	}
+	
+	if value, ok := msg.GetTime(52); !ok || !value.Equal(time.Date(2023, 11, 1, 10, 0, 0, 123e6, time.UTC)) {
+		t.Errorf("Expected GetTime(52) with milliseconds, got %v, %v", value, ok)
+	}
+	if value, ok := msg.GetTime(60); !ok || !value.Equal(time.Date(2023, 11, 1, 9, 59, 59, 0, time.UTC)) {
+		t.Errorf("Expected GetTime(60) without milliseconds, got %v, %v", value, ok)
+	}
+	if _, ok := msg.GetTime(11); ok {
+		t.Error("Expected GetTime to report a non-timestamp value")
+	}
+}
+
+func TestFieldValueShapes(t *testing.T) {
+	msg := NewResponseMessage("8=FIX.4.4\x019=100\x0135=W\x0149=cServer\x0156=SENDER\x0134=2\x0152=20231101-10:00:00\x01262=MD_1\x01268=2\x01269=0\x01270=1.10000\x01269=1\x01270=1.10020\x0110=123\x01", "\x01")
+	
+	// Single value
+	if values := msg.GetFieldValues(262); len(values) != 1 || values[0] != "MD_1" {
+		t.Errorf("Expected [MD_1], got %v", values)
+	}
+	if first := msg.GetFirst(262); first != "MD_1" {
+		t.Errorf("Expected MD_1, got %q", first)
+	}
+	if _, ok := msg.GetFieldValue(262).(string); !ok {
+		t.Errorf("Expected GetFieldValue to return a string for a single value, got %T", msg.GetFieldValue(262))
+	}
+	
+	// Multiple values
+	if values := msg.GetFieldValues(269); len(values) != 2 || values[0] != "0" || values[1] != "1" {
+		t.Errorf("Expected [0 1], got %v", values)
+	}
+	if first := msg.GetFirst(269); first != "0" {
+		t.Errorf("Expected the first MDEntryType 0, got %q", first)
+	}
+	if _, ok := msg.GetFieldValue(269).([]string); !ok {
+		t.Errorf("Expected GetFieldValue to return a []string for repeated values, got %T", msg.GetFieldValue(269))
+	}
+	
+	// Absent
+	if values := msg.GetFieldValues(55); values == nil || len(values) != 0 {
+		t.Errorf("Expected an empty slice, got %#v", values)
+	}
+	if first := msg.GetFirst(55); first != "" {
+		t.Errorf("Expected an empty string, got %q", first)
+	}
+	if value := msg.GetFieldValue(55); value != nil {
+		t.Errorf("Expected nil, got %v", value)
+	}
+	
+	msg.GetFieldValues(269)[0] = "X"
+	if msg.GetFirst(269) != "0" {
+		t.Error("Modifying the returned slice should not change the message")
+	}
+}
+
+func TestBodyLengthMatchesBytes(t *testing.T) {
+	config := &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "TEST_SENDER",
+		TargetCompID: "cServer",
+		TargetSubID:  "TRADE",
+		SenderSubID:  "TRADE",
+		Username:     "testuser",
+		Password:     "testpass",
+		HeartBeat:    30,
+	}
+
+	order := NewOrderMsg(config)
+	order.ClOrdID = "ORD_1"
+	order.Symbol = "1"
+	order.Side = "1"
+	order.OrderQty = 1000
+	order.OrdType = "1"
+	
+	messages := map[string]string{
+		"with body":    order.GetMessage(12),
+		"without body": NewHeartbeat(config).GetMessage(1),
+		"logon":        NewLogonRequest(config).GetMessage(1),
+	}
+	
+	for name, message := range messages {
+		bodyStart := strings.Index(message, "\x019=")
+		bodyStart += strings.Index(message[bodyStart+1:], "\x01") + 2
+		bodyEnd := strings.LastIndex(message, "\x0110=") + 1
+		
+		declared := NewResponseMessage(message, "\x01").GetFirst(9)
+		if actual := fmt.Sprint(bodyEnd - bodyStart); declared != actual {
+			t.Errorf("%s: BodyLength is %s but the body has %s bytes: %q", name, declared, actual, message)
+		}
+		if err := NewProtocol("\x01").ValidateMessage(message); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestParseMarketDataSnapshot(t *testing.T) {
+	msg := NewResponseMessage("8=FIX.4.4\x019=100\x0135=W\x0149=cServer\x0156=SENDER\x0134=2\x0152=20231101-10:00:00\x01262=MD_1\x0155=1\x01268=3\x01269=0\x01270=1.10000\x01271=1000000\x01269=0\x01270=1.09990\x01271=3000000\x01269=1\x01270=1.10020\x01271=2000000\x0110=123\x01", "\x01")
+	
+	snapshot, err := ParseMarketDataSnapshot(msg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if snapshot.MDReqID != "MD_1" || snapshot.Symbol != "1" {
+		t.Errorf("Unexpected snapshot header: %+v", snapshot)
+	}
+	
+	expected := []MDEntry{
+		{Type: MDEntryBid, Price: 1.10000, Size: 1000000, Level: 1},
+		{Type: MDEntryBid, Price: 1.09990, Size: 3000000, Level: 2},
+		{Type: MDEntryOffer, Price: 1.10020, Size: 2000000, Level: 1},
+	}
+	if len(snapshot.Entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %+v", len(expected), snapshot.Entries)
+	}
+	for i, entry := range snapshot.Entries {
+		if entry != expected[i] {
+			t.Errorf("Entry %d: expected %+v, got %+v", i, expected[i], entry)
+		}
+	}
+	if bids, asks := snapshot.Bids(), snapshot.Asks(); len(bids) != 2 || len(asks) != 1 || asks[0].Type.String() != "ask" {
+		t.Errorf("Unexpected sides: bids %+v, asks %+v", bids, asks)
+	}
+	
+	if parsed, err := Parse(msg); err != nil {
+		t.Errorf("Unexpected error from Parse: %v", err)
+	} else if _, ok := parsed.(*MarketDataSnapshot); !ok {
+		t.Errorf("Expected Parse to return a *MarketDataSnapshot, got %T", parsed)
+	}
+	
+	truncated := NewResponseMessage("8=FIX.4.4\x019=100\x0135=W\x0155=1\x01268=2\x01269=0\x01270=1.10000\x0110=123\x01", "\x01")
+	if _, err := ParseMarketDataSnapshot(truncated); !errors.Is(err, ErrMalformedMessage) {
+		t.Errorf("Expected ErrMalformedMessage for a short group, got %v", err)
+	}
+	
+	if _, err := ParseMarketDataSnapshot(NewResponseMessage("8=FIX.4.4\x0135=X\x0110=123\x01", "\x01")); err == nil {
+		t.Error("Expected an error for a message that is not a snapshot")
+	}
+}
+
+func TestOrderBookFromSnapshotAndIncrements(t *testing.T) {
+	book := NewOrderBook("1")
+	
+	snapshot, err := ParseMarketDataSnapshot(NewResponseMessage("8=FIX.4.4\x019=100\x0135=W\x0134=2\x01262=MD_1\x0155=1\x01268=3\x01269=0\x01278=B1\x01270=1.10000\x01271=1000000\x01269=0\x01278=B2\x01270=1.09990\x01271=3000000\x01269=1\x01278=A1\x01270=1.10020\x01271=2000000\x0110=123\x01", "\x01"))
+	if err != nil {
+		t.Fatalf("Unexpected snapshot error: %v", err)
+	}
+	book.ApplySnapshot(snapshot)
+	
+	increments := []string{
+		// A better bid arrives and the ask size changes.
+		"8=FIX.4.4\x019=100\x0135=X\x0134=3\x01262=MD_1\x01268=2\x01279=0\x01269=0\x01278=B3\x0155=1\x01270=1.10005\x01271=500000\x01279=1\x01269=1\x01278=A1\x0155=1\x01270=1.10020\x01271=4000000\x0110=123\x01",
+		// The old best bid is removed; an update for another symbol is ignored.
+		"8=FIX.4.4\x019=100\x0135=X\x0134=4\x01262=MD_1\x01268=2\x01279=2\x01278=B1\x0155=1\x01279=0\x01269=1\x01278=A9\x0155=2\x01270=1.30000\x01271=1000000\x0110=123\x01",
+	}
+	for i, raw := range increments {
+		refresh, err := ParseMarketDataIncrementalRefresh(NewResponseMessage(raw, "\x01"))
+		if err != nil {
+			t.Fatalf("Increment %d: unexpected error: %v", i, err)
+		}
+		if refresh.MDReqID != "MD_1" {
+			t.Errorf("Increment %d: expected MDReqID MD_1, got %q", i, refresh.MDReqID)
+		}
+		book.ApplyIncrement(refresh)
+	}
+	
+	bids := book.Bids()
+	if len(bids) != 2 || bids[0].EntryID != "B3" || bids[0].Level != 1 || bids[1].EntryID != "B2" || bids[1].Level != 2 {
+		t.Errorf("Unexpected bids: %+v", bids)
+	}
+	asks := book.Asks()
+	if len(asks) != 1 || asks[0].EntryID != "A1" || asks[0].Size != 4000000 {
+		t.Errorf("Unexpected asks: %+v", asks)
+	}
+	
+	bid, ask, ok := book.BestBidAsk()
+	if !ok || bid.Price != 1.10005 || ask.Price != 1.10020 {
+		t.Errorf("Expected best 1.10005/1.10020, got %v/%v (%v)", bid.Price, ask.Price, ok)
+	}
+	
+	refresh, _ := ParseMarketDataIncrementalRefresh(NewResponseMessage(increments[1], "\x01"))
+	if refresh.Entries[0].Action != MDUpdateDelete || refresh.Entries[1].Action != MDUpdateNew || refresh.Entries[1].Symbol != "2" {
+		t.Errorf("Unexpected parsed increments: %+v", refresh.Entries)
+	}
+}
+
+func TestParseSecurityList(t *testing.T) {
+	msg := NewResponseMessage("8=FIX.4.4\x019=100\x0135=y\x0134=2\x01320=SEC_1\x01560=0\x01146=3\x0155=1\x011007=EURUSD\x011008=5\x01969=0.00001\x01561=1000\x01562=1000\x0155=2\x011007=GBPUSD\x011008=5\x0155=3\x011007=USDJPY\x011008=3\x01969=0.001\x0110=123\x01", "\x01")
+	
+	securities := ParseSecurityList(msg)
+	expected := []Security{
+		{SymbolID: "1", SymbolName: "EURUSD", Digits: 5, TickSize: 0.00001, QtyStep: 1000, MinQty: 1000},
+		{SymbolID: "2", SymbolName: "GBPUSD", Digits: 5},
+		{SymbolID: "3", SymbolName: "USDJPY", Digits: 3, TickSize: 0.001},
+	}
+	if len(securities) != len(expected) {
+		t.Fatalf("Expected %d securities, got %+v", len(expected), securities)
+	}
+	for i, security := range securities {
+		if security != expected[i] {
+			t.Errorf("Security %d: expected %+v, got %+v", i, expected[i], security)
+		}
+	}
+	
+	if parsed, err := Parse(msg); err != nil {
+		t.Errorf("Unexpected error from Parse: %v", err)
+	} else if list, ok := parsed.([]Security); !ok || len(list) != 3 {
+		t.Errorf("Expected Parse to return the securities, got %#v", parsed)
+	}
+}
+
+func TestMarketDataRequestEntryTypes(t *testing.T) {
+	mdReq := NewMarketDataRequest(&Config{BeginString: "FIX.4.4", SenderCompID: "TEST_SENDER", TargetCompID: "cServer"})
+	mdReq.MDReqID = "MD_1"
+	mdReq.SubscriptionRequestType = "1"
+	mdReq.MDEntryTypes = []string{"0", "1"}
+	mdReq.Symbol = "1"
+	
+	body := mdReq.GetBody()
+	if !strings.Contains(body, "267=2\x01269=0\x01269=1\x01") {
+		t.Errorf("Expected 267=2 followed by 269=0 and 269=1, got %q", body)
+	}
+	
+	mdReq.MDEntryTypes = []string{"2"}
+	if body := mdReq.GetBody(); !strings.Contains(body, "267=1\x01269=2\x01") || strings.Count(body, "269=") != 1 {
+		t.Errorf("Expected a single trade entry type, got %q", body)
+	}
+	
+	mdReq.MDEntryTypes = nil
+	if body := mdReq.GetBody(); !strings.Contains(body, "267=2\x01269=0\x01269=1\x01") {
+		t.Errorf("Expected bid and offer by default, got %q", body)
+	}
+}
+
+func TestMarketDataRequestSymbols(t *testing.T) {
+	mdReq := NewMarketDataRequest(&Config{BeginString: "FIX.4.4", SenderCompID: "TEST_SENDER", TargetCompID: "cServer"})
+	mdReq.MDReqID = "MD_1"
+	mdReq.SubscriptionRequestType = "1"
+	mdReq.Symbols = []string{"1", "2"}
+	mdReq.Symbol = "3"
+	
+	body := mdReq.GetBody()
+	if !strings.Contains(body, "146=3\x0155=1\x0155=2\x0155=3") || strings.Count(body, "55=") != 3 {
+		t.Errorf("Expected 146=3 followed by three symbols, got %q", body)
+	}
+	
+	mdReq.Symbols = nil
+	if body := mdReq.GetBody(); !strings.Contains(body, "146=1\x0155=3") {
+		t.Errorf("Expected Symbol alone to be sent as a one-entry group, got %q", body)
+	}
+}
+
+func TestParseMarketDataRequestReject(t *testing.T) {
+	msg := NewResponseMessage("8=FIX.4.4\x019=100\x0135=Y\x0134=4\x01262=MD_1\x01281=3\x0158=Not entitled\x0110=123\x01", "\x01")
+	
+	reject, err := ParseMarketDataRequestReject(msg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reject.MDReqID != "MD_1" || reject.Reason != "3" || reject.Text != "Not entitled" {
+		t.Errorf("Unexpected reject: %+v", reject)
+	}
+	if got := reject.Error(); got != "market data request MD_1 rejected: insufficient permissions (281=3): Not entitled" {
+		t.Errorf("Unexpected error text: %q", got)
+	}
+	if name := NewProtocol("").GetFieldNames()[281]; name != "MDReqRejReason" {
+		t.Errorf("Expected tag 281 to be named MDReqRejReason, got %q", name)
+	}
+}
+
+func TestParseReject(t *testing.T) {
+	// A reject cServer sent for a logon carrying a Symbol (55).
+	raw := "8=FIX.4.4\x019=142\x0135=3\x0134=1\x0149=cServer\x0150=TRADE\x0152=20240115-10:30:00.123\x0156=demo.ctrader.1234567\x0157=TRADE\x0145=1\x0158=Tag not defined for this message type, field=55\x01371=55\x01372=A\x01373=2\x0110=061\x01"
+	
+	reject := ParseReject(NewResponseMessage(raw, "\x01"))
+	expected := Reject{RefSeqNum: 1, RefTagID: 55, SessionRejectReason: "2", Text: "Tag not defined for this message type, field=55"}
+	if *reject != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, *reject)
+	}
+	if got := reject.Error(); got != "message 1 rejected: tag not defined for this message type (373=2) on Symbol (55): Tag not defined for this message type, field=55" {
+		t.Errorf("Unexpected error text: %q", got)
+	}
+	if !errors.Is(reject, ErrRequestRejected) {
+		t.Error("Expected the reject to match ErrRequestRejected")
+	}
+	
+	fieldNames := NewProtocol("").GetFieldNames()
+	for tag, name := range map[int]string{45: "RefSeqNum", 58: "Text", 371: "RefTagID", 373: "SessionRejectReason"} {
+		if fieldNames[tag] != name {
+			t.Errorf("Expected tag %d to be named %s, got %q", tag, name, fieldNames[tag])
+		}
+	}
+}
+
+func TestParseBusinessReject(t *testing.T) {
+	raw := "8=FIX.4.4\x019=120\x0135=j\x0134=6\x0149=cServer\x0156=demo.ctrader.1234567\x0145=5\x0158=Unknown symbol\x01372=V\x01379=MD_1\x01380=2\x0110=123\x01"
+	
+	reject := ParseBusinessReject(NewResponseMessage(raw, "\x01"))
+	expected := BusinessReject{RefMsgType: "V", BusinessRejectReason: "2", BusinessRejectRefID: "MD_1", Text: "Unknown symbol"}
+	if *reject != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, *reject)
+	}
+	if got := reject.Error(); got != "MarketDataRequest MD_1 rejected: unknown security (380=2): Unknown symbol" {
+		t.Errorf("Unexpected error text: %q", got)
+	}
+	if !errors.Is(reject, ErrRequestRejected) {
+		t.Error("Expected the reject to match ErrRequestRejected")
+	}
+	
+	typed, err := Parse(NewResponseMessage(raw, "\x01"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := typed.(*BusinessReject); !ok {
+		t.Errorf("Expected Parse to return a *BusinessReject, got %T", typed)
+	}
+	
+	// "j" and "y" are easily confused; "8" is an execution report.
+	names := NewProtocol("").GetMessageTypeName()
+	for msgType, name := range map[string]string{"j": "BusinessMessageReject", "y": "SecurityList", "8": "ExecutionReport", "3": "Reject"} {
+		if names[msgType] != name {
+			t.Errorf("Expected MsgType %s to be named %s, got %q", msgType, name, names[msgType])
+		}
+	}
+}
+
+func TestParseExecutionReport(t *testing.T) {
+	partial := NewResponseMessage("8=FIX.4.4\x019=100\x0135=8\x0134=5\x0111=ORD_1\x0137=42\x01150=F\x0139=1\x0155=1\x0154=1\x0138=3000\x0114=1000\x01151=2000\x0132=1000\x0131=1.10012\x016=1.10012\x0110=123\x01", "\x01")
+	
+	report, err := ParseExecutionReport(partial)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := ExecutionReport{ClOrdID: "ORD_1", OrderID: "42", ExecType: "F", OrdStatus: "1", Symbol: "1", Side: "1",
+		OrderQty: 3000, CumQty: 1000, LeavesQty: 2000, LastQty: 1000, LastPx: 1.10012, AvgPx: 1.10012}
+	if *report != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *report)
+	}
+	
+	// A new-order ack carries no fills; the absent numbers are zero.
+	ack, err := ParseExecutionReport(NewResponseMessage("8=FIX.4.4\x019=100\x0135=8\x0111=ORD_2\x01150=0\x0139=0\x0110=123\x01", "\x01"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ack.AvgPx != 0 || ack.CumQty != 0 || ack.LastPx != 0 || ack.Text != "" {
+		t.Errorf("Expected zero values for absent fields, got %+v", ack)
+	}
+	
+	if _, err := ParseExecutionReport(NewResponseMessage("8=FIX.4.4\x0135=AP\x0110=123\x01", "\x01")); err == nil {
+		t.Error("Expected an error for a message that is not an execution report")
+	}
+}
+
+func TestParsePositionReport(t *testing.T) {
+	// As sent by cServer for an open EURUSD long.
+	msg := NewResponseMessage("8=FIX.4.4\x019=160\x0135=AP\x0149=cServer\x0156=TEST_SENDER\x0134=6\x0152=20231101-10:00:00.512\x01710=POS_1\x01721=5621049\x01727=2\x01728=0\x0155=1\x01702=1\x01703=TQ\x01704=2000\x01705=0\x01730=1.10012\x0110=123\x01", "\x01")
+	
+	report, err := ParsePositionReport(msg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.PosReqID != "POS_1" || report.PosMaintRptID != "5621049" || report.Symbol != "1" || report.TotalNumPosReports != 2 {
+		t.Errorf("Unexpected report header: %+v", report)
+	}
+	if report.LongQty != 2000 || report.ShortQty != 0 || report.SettlPrice != 1.10012 {
+		t.Errorf("Unexpected quantities or price: %+v", report)
+	}
+	if len(report.Positions) != 1 || report.Positions[0] != (PositionQty{PosType: "TQ", LongQty: 2000}) {
+		t.Errorf("Unexpected positions: %+v", report.Positions)
+	}
+	
+	// Several position types in one report.
+	multi := NewResponseMessage("8=FIX.4.4\x0135=AP\x01710=POS_2\x0155=2\x01702=2\x01703=TQ\x01704=0\x01705=1000\x01703=SOD\x01704=0\x01705=3000\x0110=123\x01", "\x01")
+	report, err = ParsePositionReport(multi)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []PositionQty{{PosType: "TQ", ShortQty: 1000}, {PosType: "SOD", ShortQty: 3000}}
+	if len(report.Positions) != len(expected) || report.Positions[0] != expected[0] || report.Positions[1] != expected[1] {
+		t.Errorf("Expected %+v, got %+v", expected, report.Positions)
+	}
+	if report.ShortQty != 1000 {
+		t.Errorf("Expected ShortQty of the first entry, got %v", report.ShortQty)
+	}
+	
+	if _, err := ParsePositionReport(NewResponseMessage("8=FIX.4.4\x0135=8\x0110=123\x01", "\x01")); err == nil {
+		t.Error("Expected an error for a message that is not a position report")
+	}
+}
+
+func TestParseRequestForPositionsAck(t *testing.T) {
+	msg := NewResponseMessage("8=FIX.4.4\x019=0\x0135=AO\x0149=cServer\x0156=TEST_SENDER\x0134=5\x01710=POS_1\x01721=POS_1\x01727=2\x01728=0\x01729=0\x0110=000\x01", "\x01")
+	
+	ack, err := ParseRequestForPositionsAck(msg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ack.PosReqID != "POS_1" || ack.TotalNumPosReports != 2 || ack.PosReqResult != "0" || ack.PosReqStatus != "0" {
+		t.Errorf("Unexpected ack: %+v", ack)
+	}
+	
+	report := NewResponseMessage("8=FIX.4.4\x019=0\x0135=AP\x01710=POS_1\x0155=1\x01704=1000\x01727=1\x01728=0\x0110=000\x01", "\x01")
+	if _, err := ParseRequestForPositionsAck(report); err == nil {
+		t.Error("Expected a position report not to parse as an ack")
+	}
+	
+	typed, err := Parse(msg)
+	if _, ok := typed.(*RequestForPositionsAck); err != nil || !ok {
+		t.Errorf("Expected Parse to return a *RequestForPositionsAck for AO, got %T (%v)", typed, err)
+	}
+	typed, err = Parse(report)
+	if _, ok := typed.(*PositionReport); err != nil || !ok {
+		t.Errorf("Expected Parse to return a *PositionReport for AP, got %T (%v)", typed, err)
+	}
+	names := NewProtocol("").GetMessageTypeName()
+	if names["AO"] != "RequestForPositionsAck" || names["AP"] != "PositionReport" {
+		t.Errorf("Expected AO/AP to be named RequestForPositionsAck/PositionReport, got %q/%q", names["AO"], names["AP"])
+	}
+}
+
+func TestOrderMassStatusRequest(t *testing.T) {
+	config := &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "TEST_SENDER",
+		TargetCompID: "cServer",
+		TargetSubID:  "TRADE",
+		SenderSubID:  "TRADE",
+	}
+	
+	request := NewOrderMassStatusRequest(config)
+	request.MassStatusReqID = "MASS_1"
+	
+	msg := NewResponseMessage(request.GetMessage(3), "\x01")
+	if msg.GetMessageType() != "AF" {
+		t.Errorf("Expected MsgType AF, got %q", msg.GetMessageType())
+	}
+	if reqID := msg.first(584); reqID != "MASS_1" {
+		t.Errorf("Expected 584=MASS_1, got %q", reqID)
+	}
+	if reqType := msg.first(585); reqType != "7" {
+		t.Errorf("Expected 585 to default to 7, got %q", reqType)
+	}
+	
+	request.MassStatusReqType = "1" // Status for orders of a security
+	if body := request.GetBody(); body != "584=MASS_1\x01585=1" {
+		t.Errorf("Unexpected body: %q", body)
+	}
+	
+	names := NewProtocol("").GetFieldNames()
+	if names[584] != "MassStatusReqID" || names[585] != "MassStatusReqType" {
+		t.Errorf("Expected names for 584 and 585, got %q and %q", names[584], names[585])
+	}
+}
+
+func TestSharedGetMessageMatchesPerTypeAssembly(t *testing.T) {
+	config := &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: "TEST_SENDER",
+		TargetCompID: "cServer",
+		TargetSubID:  "TRADE",
+		SenderSubID:  "TRADE",
+		Username:     "testuser",
+		Password:     "testpass",
+		HeartBeat:    30,
+	}
+	
+	// SendingTime (52) and with it the CheckSum may change between the two
+	// serializations if a second boundary passes.
+	normalize := func(message string) string {
+		var fields []string
+		for _, field := range strings.Split(message, "\x01") {
+			if strings.HasPrefix(field, "52=") || strings.HasPrefix(field, "10=") {
+				continue
+			}
+			fields = append(fields, field)
+		}
+		return strings.Join(fields, "\x01")
+	}
+	
+	type assembled interface {
+		Message
+		GetBody() string
+		assemble(body string, sequenceNumber int) string
+	}
+	
+	messages := map[string]Message{"generic": NewGenericMessage("BB", config).SetField(909, "COLL_1")}
+	for _, msgType := range SupportedOutboundTypes() {
+		message, err := NewMessage(msgType, config)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", msgType, err)
+		}
+		messages[msgType] = message
+	}
+	order := messages["D"].(*OrderMsg)
+	order.ClOrdID, order.Symbol, order.Side, order.OrderQty, order.OrdType, order.Price = "ORD_1", "1", "1", 1000, "2", 1.1
+	
+	for name, message := range messages {
+		typed, ok := message.(assembled)
+		if !ok {
+			t.Errorf("%s: %T does not embed RequestMessage", name, message)
+			continue
+		}
+		perType := typed.assemble(typed.GetBody(), 7)
+		if shared := message.GetMessage(7); normalize(shared) != normalize(perType) {
+			t.Errorf("%s: GetMessage changed the output\nshared:   %q\nper type: %q", name, shared, perType)
+		}
+		if typed.GetBody() != "" && !strings.Contains(message.GetMessage(7), typed.GetBody()) {
+			t.Errorf("%s: body missing from the message", name)
+		}
+	}
+}
+
+func TestResponseMessageToJSON(t *testing.T) {
+	raw := "8=FIX.4.4\x019=100\x0135=W\x0134=3\x01262=MD_1\x0155=1\x01268=2\x01269=0\x01270=1.1\x01271=100000\x01269=1\x01270=1.2\x0110=123\x01"
+	
+	data, err := NewResponseMessage(raw, "\x01").ToJSON()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(string(data), `{"BeginString":"FIX.4.4","BodyLength":"100","MsgType":"W","MsgTypeName":"MarketDataSnapshotFullRefresh",`) {
+		t.Errorf("Expected fields in wire order, got %s", data)
+	}
+	
+	var decoded struct {
+		MDReqID     string
+		Symbol      string
+		CheckSum    string
+		NoMDEntries []map[string]string
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("ToJSON produced invalid JSON %s: %v", data, err)
+	}
+	if decoded.MDReqID != "MD_1" || decoded.Symbol != "1" || decoded.CheckSum != "123" {
+		t.Errorf("Unexpected fields: %+v", decoded)
+	}
+	expected := []map[string]string{
+		{"MDEntryType": "0", "MDEntryPx": "1.1", "MDEntrySize": "100000"},
+		{"MDEntryType": "1", "MDEntryPx": "1.2"},
+	}
+	if !reflect.DeepEqual(decoded.NoMDEntries, expected) {
+		t.Errorf("Expected the entries as an array %v, got %v", expected, decoded.NoMDEntries)
+	}
+	
+	unknown, err := NewResponseMessage("8=FIX.4.4\x0135=0\x019999=x\x01", "\x01").ToJSON()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(string(unknown), `"Field9999":"x"`) {
+		t.Errorf("Expected unknown tags as Field<tag>, got %s", unknown)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := func() *Config {
+		return &Config{
+			BeginString:  "FIX.4.4",
+			SenderCompID: "demo.ctrader.123",
+			TargetCompID: "cServer",
+			TargetSubID:  "TRADE",
+			SenderSubID:  "TRADE",
+			Username:     "123",
+			Password:     "secret",
+			HeartBeat:    30,
+		}
+	}
+	
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("Expected a complete config to be valid, got %v", err)
+	}
+	
+	tests := []struct {
+		field  string
+		mutate func(*Config)
+	}{
+		{"BeginString", func(c *Config) { c.BeginString = "" }},
+		{"SenderCompID", func(c *Config) { c.SenderCompID = "" }},
+		{"TargetCompID", func(c *Config) { c.TargetCompID = "" }},
+		{"Username", func(c *Config) { c.Username = " " }},
+		{"Password", func(c *Config) { c.Password = "" }},
+		{"HeartBeat", func(c *Config) { c.HeartBeat = 0 }},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			config := valid()
+			tt.mutate(config)
+			
+			err := config.Validate()
+			if !errors.Is(err, ErrInvalidConfig) {
+				t.Fatalf("Expected ErrInvalidConfig, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.field) {
+				t.Errorf("Expected the error to name %s, got %q", tt.field, err)
+			}
+			
+			// Connect fails before dialing the unreachable address.
+			client := NewClient("127.0.0.1", 1, config)
+			if err := client.Connect(); !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("Expected Connect to return ErrInvalidConfig, got %v", err)
+			}
+		})
+	}
+	
+	var missing *Config
+	if err := missing.Validate(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig for a nil config, got %v", err)
+	}
+}
+
+func TestSessionClientConstructors(t *testing.T) {
+	config := NewDemoConfig("demo.ctrader.123", "123", "secret")
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Expected a valid demo config, got %v", err)
+	}
+	if config.TargetCompID != "cServer" {
+		t.Errorf("Expected TargetCompID cServer, got %s", config.TargetCompID)
+	}
+	if *NewLiveConfig("live.ctrader.123", "123", "secret") == *config {
+		t.Error("Expected the live config to carry its own credentials")
+	}
+	
+	quote := NewQuoteClient(DemoHost, config)
+	trade := NewTradeClient(DemoHost, config, WithDelimiter("|"))
+	
+	tests := []struct {
+		client *Client
+		port   int
+		subID  string
+	}{
+		{quote, 5211, "QUOTE"},
+		{trade, 5212, "TRADE"},
+	}
+	for _, tt := range tests {
+		if tt.client.host != DemoHost || tt.client.port != tt.port || !tt.client.ssl {
+			t.Errorf("Expected %s:%d over TLS, got %s:%d ssl=%v", DemoHost, tt.port, tt.client.host, tt.client.port, tt.client.ssl)
+		}
+		if tt.client.Config().SenderSubID != tt.subID || tt.client.Config().TargetSubID != tt.subID {
+			t.Errorf("Expected sub-IDs %s, got %s/%s", tt.subID, tt.client.Config().SenderSubID, tt.client.Config().TargetSubID)
+		}
+	}
+	
+	if config.SenderSubID != "" || config.TargetSubID != "" {
+		t.Error("Expected the shared config to be left unchanged")
+	}
+	if trade.delimiter != "|" {
+		t.Error("Expected the options to be applied")
+	}
+}
+
+func TestOrderAccount(t *testing.T) {
+	config := NewDemoConfig("demo.ctrader.123", "123", "secret")
+	
+	order := NewOrderMsg(config)
+	order.ClOrdID = "ORD_1"
+	cancel := NewOrderCancelRequest(config)
+	cancel.ClOrdID = "CXL_1"
+	replace := NewOrderCancelReplaceRequest(config)
+	replace.ClOrdID = "RPL_1"
+	
+	for _, msg := range []Message{order, cancel, replace} {
+		if sent := NewResponseMessage(msg.GetMessage(1), "\x01"); sent.first(1) != "" {
+			t.Errorf("Expected no Account (1) without one configured, got %q in %s", sent.first(1), sent.GetMessageType())
+		}
+	}
+	
+	config.Account = "ACC_1"
+	order = NewOrderMsg(config)
+	cancel = NewOrderCancelRequest(config)
+	replace = NewOrderCancelReplaceRequest(config)
+	replace.Account = "ACC_2"
+	
+	expected := map[string]string{"D": "ACC_1", "F": "ACC_1", "G": "ACC_2"}
+	for _, msg := range []Message{order, cancel, replace} {
+		sent := NewResponseMessage(msg.GetMessage(1), "\x01")
+		if want := expected[sent.GetMessageType()]; sent.first(1) != want {
+			t.Errorf("Expected Account %s in %s, got %q", want, sent.GetMessageType(), sent.first(1))
+		}
+	}
+}
+
+func TestPositionUnrealizedPnL(t *testing.T) {
+	tests := []struct {
+		name         string
+		position     Position
+		bid, ask     float64
+		contractSize float64
+		digits       int
+		expected     float64
+	}{
+		{"long EURUSD", Position{Side: "1", Quantity: 0.1, EntryPrice: 1.1000}, 1.1010, 1.1012, 100000, 5, 10},
+		{"short EURUSD closes at the ask", Position{Side: "2", Quantity: 0.1, EntryPrice: 1.1000}, 1.0990, 1.0992, 100000, 5, 8},
+		{"long USDJPY in yen", Position{Side: "1", Quantity: 1, EntryPrice: 150.000}, 150.250, 150.260, 100000, 3, 25000},
+		{"short gold with its own contract size", Position{Side: "2", Quantity: 2, EntryPrice: 2000.00}, 2009.50, 2010.00, 100, 2, -2000},
+		{"prices rounded to digits", Position{Side: "1", Quantity: 1, EntryPrice: 1.100004}, 1.100996, 1.101, 100000, 5, 100},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.position.UnrealizedPnL(tt.bid, tt.ask, tt.contractSize, tt.digits)
+			if diff := got - tt.expected; diff > 1e-6 || diff < -1e-6 {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+	
+	msg := NewResponseMessage("8=FIX.4.4\x0135=y\x01320=SEC_1\x01146=1\x0155=4\x011007=USDJPY\x011008=3\x01231=100000\x0110=000\x01", "\x01")
+	security := ParseSecurityList(msg)[0]
+	if security.ContractSize != 100000 {
+		t.Fatalf("Expected ContractSize 100000 from tag 231, got %v", security.ContractSize)
+	}
+	position := Position{Symbol: "4", Side: "2", Quantity: 0.5, EntryPrice: 150.000}
+	if got := position.SecurityPnL(149.790, 149.800, security); got < 9999.99 || got > 10000.01 {
+		t.Errorf("Expected 10000 yen from the security metadata, got %v", got)
+	}
+}
+
+func TestRejectReasonTexts(t *testing.T) {
+	if text := OrdRejReasonText("1"); text != "unknown symbol" {
+		t.Errorf("Expected unknown symbol for OrdRejReason 1, got %q", text)
+	}
+	if text := OrdRejReasonText("3"); text != "order exceeds limit" {
+		t.Errorf("Expected order exceeds limit for OrdRejReason 3, got %q", text)
+	}
+	if text := CxlRejReasonText("0"); text != "too late to cancel" {
+		t.Errorf("Expected too late to cancel for CxlRejReason 0, got %q", text)
+	}
+	if text := OrdRejReasonText("42"); text != "42" {
+		t.Errorf("Expected an unknown code to be returned as is, got %q", text)
+	}
+	
+	msg := NewResponseMessage("8=FIX.4.4\x0135=8\x0111=ORD_1\x01150=8\x0139=8\x01103=2\x0158=Market closed\x0110=000\x01", "\x01")
+	report, err := ParseExecutionReport(msg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.OrdRejReason != "2" || OrdRejReasonText(report.OrdRejReason) != "exchange closed" {
+		t.Errorf("Expected OrdRejReason 2 (exchange closed) from tag 103, got %q", report.OrdRejReason)
+	}
+}
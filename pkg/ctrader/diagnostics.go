@@ -0,0 +1,46 @@
+package ctrader
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// WithChecksumDiagnostics cross-checks the declared checksum (tag 10) of every
+// inbound message against our own computation. Matches are logged to the
+// client's Logger; mismatches are reported on the error channel as
+// ErrChecksumMismatch together with a hex dump of the frame. Messages are
+// still delivered, so this can be used to observe corruption (e.g. from a
+// wrong delimiter) without dropping traffic.
+func WithChecksumDiagnostics(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.checksumDiagnostics = enabled
+	}
+}
+
+// WithChecksumValidation drops every inbound message whose declared checksum
+// (tag 10) does not match its contents and reports ErrChecksumMismatch on the
+// error channel instead of delivering it. A dropped message leaves a gap in
+// the inbound sequence, which is then recovered with a ResendRequest.
+func WithChecksumValidation(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.checksumValidation = enabled
+	}
+}
+
+// verifyChecksum implements WithChecksumDiagnostics and WithChecksumValidation
+// for one framed message and reports whether its checksum is correct.
+func (c *Client) verifyChecksum(raw string) bool {
+	err := NewProtocol(c.delimiter).validateChecksum(raw)
+	if err != nil {
+		c.metrics.countChecksumFailure()
+	}
+	switch {
+	case err != nil && c.checksumDiagnostics:
+		c.reportError(fmt.Errorf("%w: %v\n%s", ErrChecksumMismatch, err, hex.Dump([]byte(raw))))
+	case err != nil:
+		c.reportError(fmt.Errorf("%w: dropped inbound message: %v", ErrChecksumMismatch, err))
+	case c.checksumDiagnostics:
+		c.logger.LogEvent(LogLevelInfo, "checksum ok for inbound message", "bytes", len(raw))
+	}
+	return err == nil
+}
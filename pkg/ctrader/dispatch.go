@@ -0,0 +1,75 @@
+package ctrader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// callbackQueue hands inbound messages from the reading goroutine to the
+// message callback's own goroutine in the order they arrived. It is
+// unbounded, so a slow callback delays only itself, never the reading of the
+// connection.
+type callbackQueue struct {
+	mu      sync.Mutex
+	pending []*ResponseMessage
+	ready   chan struct{}
+}
+
+func newCallbackQueue() *callbackQueue {
+	return &callbackQueue{ready: make(chan struct{}, 1)}
+}
+
+func (q *callbackQueue) push(msg *ResponseMessage) {
+	q.mu.Lock()
+	q.pending = append(q.pending, msg)
+	q.mu.Unlock()
+	
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// take removes and returns everything queued so far.
+func (q *callbackQueue) take() []*ResponseMessage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	
+	batch := q.pending
+	q.pending = nil
+	return batch
+}
+
+// dispatchMessages passes the messages queued by readMessages to the message
+// callback until the session ends, and then whatever was still queued.
+func (c *Client) dispatchMessages(ctx context.Context, queue *callbackQueue) {
+	for {
+		select {
+		case <-queue.ready:
+			c.runMessageCallback(queue.take())
+		case <-ctx.Done():
+			c.runMessageCallback(queue.take())
+			return
+		}
+	}
+}
+
+func (c *Client) runMessageCallback(batch []*ResponseMessage) {
+	for _, msg := range batch {
+		if callback := c.onMessage; callback != nil {
+			c.callMessageCallback(callback, msg)
+		}
+	}
+}
+
+// callMessageCallback calls callback with msg, reporting a panic as an error
+// so one bad message does not stop the delivery of the rest.
+func (c *Client) callMessageCallback(callback func(*ResponseMessage), msg *ResponseMessage) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.reportError(fmt.Errorf("panic in message callback (MsgType %s): %v", msg.GetMessageType(), r))
+		}
+	}()
+	callback(msg)
+}
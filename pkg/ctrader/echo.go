@@ -0,0 +1,28 @@
+package ctrader
+
+// WithEchoOutbound delivers every successfully sent message on Messages() as
+// well, parsed into a ResponseMessage whose IsOutbound reports true, so the
+// channel gives a single chronological view of the session. Echoed messages
+// never reach SendAndWait or other correlation matchers.
+func WithEchoOutbound(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.echoOutbound = enabled
+	}
+}
+
+// echo queues a sent frame on the message channel, dropping and reporting it
+// when the channel is full like inbound messages.
+func (c *Client) echo(raw string) {
+	if !c.echoOutbound {
+		return
+	}
+	
+	msg := NewResponseMessage(raw, c.delimiter)
+	msg.outbound = true
+	
+	select {
+	case c.channelFor(msg) <- msg:
+	default:
+		c.dropMessage(msg)
+	}
+}
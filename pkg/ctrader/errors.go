@@ -0,0 +1,137 @@
+package ctrader
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrUnsupportedMessage is matched by errors.Is when the server rejects a
+// request because its message type is not accepted on the current session.
+var ErrUnsupportedMessage = errors.New("message type not supported on this session")
+
+// ErrRequestRejected is matched by errors.Is when the server rejects a request
+// with a session (35=3) or business (35=j) reject.
+var ErrRequestRejected = errors.New("request rejected")
+
+// ErrOrderRejected is matched by errors.Is when an order is rejected or ends
+// without being filled.
+var ErrOrderRejected = errors.New("order rejected")
+
+// ErrOrderNotFound is matched by errors.Is when the server does not know the
+// order named in a status request.
+var ErrOrderNotFound = errors.New("order not found")
+
+// ErrMessageTooLarge is matched by errors.Is when Send refuses a message that
+// exceeds the maximum message size.
+var ErrMessageTooLarge = errors.New("message too large")
+
+// ErrConnectionLost is matched by errors.Is when the connection to the server
+// drops, including when it drops while SendAndWait, Request, WaitForAck or
+// PlaceOrderAsync is still waiting for a response. Errors wrapping it are
+// worth a reconnect.
+var ErrConnectionLost = errors.New("connection lost")
+
+// ErrReadTimeout is matched by errors.Is, alongside ErrConnectionLost, when
+// the connection is dropped because nothing arrived within the read timeout.
+var ErrReadTimeout = errors.New("read timeout")
+
+// ErrNotConnected is returned by Send when the client has no open connection.
+var ErrNotConnected = errors.New("client is not connected")
+
+// ErrSeqResetIgnored is reported on the error channel when a logon requested
+// ResetSeqNum but the server's response did not restart at sequence 1.
+var ErrSeqResetIgnored = errors.New("sequence reset not honored by server")
+
+// ErrSeqNumTooLow is reported on the error channel when the server sends a
+// MsgSeqNum below the expected one without PossDupFlag; the client logs out.
+var ErrSeqNumTooLow = errors.New("MsgSeqNum too low")
+
+// ErrChecksumMismatch is matched by errors.Is when an inbound message's
+// declared checksum (tag 10) does not match its contents.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ErrMalformedMessage is reported on the error channel when an inbound
+// message does not split into consistent fields, typically because a value
+// contains the delimiter byte.
+var ErrMalformedMessage = errors.New("malformed message")
+
+// ErrMessageDropped is reported on the error channel when a message could
+// not be delivered because the consumer of Messages (or AdminMessages) fell
+// behind and the channel was full. WithMessageBuffer sizes the channels.
+var ErrMessageDropped = errors.New("message dropped")
+
+// ErrInvalidConfig is returned by Config.Validate, and by Connect before
+// dialing, when a required Config field is missing.
+var ErrInvalidConfig = errors.New("invalid config")
+
+// ErrRateLimited is returned by Send when an order message would exceed the
+// limit set with WithRateLimit and WithRateLimitFailFast is enabled.
+var ErrRateLimited = errors.New("rate limited")
+
+// ErrMarketDataGap is matched by errors.Is when an incremental refresh of a
+// subscription skips or repeats a RptSeq (83); see MarketDataGapError.
+var ErrMarketDataGap = errors.New("market data gap")
+
+// ErrTLS is matched by errors.Is when a read or write fails inside the TLS
+// layer (bad record, alert, certificate problem) rather than at the TCP level.
+var ErrTLS = errors.New("tls error")
+
+// UnsupportedMessageError reports a request sent to a session that does not
+// handle its message type, e.g. a SecurityListRequest sent to TRADE.
+type UnsupportedMessageError struct {
+	Session string
+	MsgType string
+	Text    string
+}
+
+func (e *UnsupportedMessageError) Error() string {
+	name := e.MsgType
+	if typeName, exists := NewProtocol("").GetMessageTypeName()[e.MsgType]; exists {
+		name = fmt.Sprintf("%s (35=%s)", typeName, e.MsgType)
+	}
+	msg := fmt.Sprintf("%s session does not support %s", e.Session, name)
+	if e.Text != "" {
+		msg += ": " + e.Text
+	}
+	return msg
+}
+
+func (e *UnsupportedMessageError) Is(target error) bool {
+	return target == ErrUnsupportedMessage
+}
+
+// isTLSError reports whether err originated in the TLS layer.
+func isTLSError(err error) bool {
+	var recordHeaderErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var verificationErr *tls.CertificateVerificationError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCertErr x509.CertificateInvalidError
+	
+	if errors.As(err, &recordHeaderErr) || errors.As(err, &alertErr) || errors.As(err, &verificationErr) ||
+		errors.As(err, &unknownAuthorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidCertErr) {
+		return true
+	}
+	
+	// crypto/tls reports alerts sent or received mid-session as
+	// *net.OpError with these operations.
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return opErr.Op == "local error" || opErr.Op == "remote error"
+	}
+	
+	return false
+}
+
+// wrapConnError annotates a read or write error with op and, for failures in
+// the TLS layer, ErrTLS.
+func wrapConnError(op string, err error) error {
+	if isTLSError(err) {
+		return fmt.Errorf("%s error: %w: %w", op, ErrTLS, err)
+	}
+	return fmt.Errorf("%s error: %w", op, err)
+}
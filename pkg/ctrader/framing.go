@@ -0,0 +1,75 @@
+package ctrader
+
+import "bytes"
+
+// beginStringPrefix starts every FIX message and anchors framing.
+var beginStringPrefix = []byte("8=FIX")
+
+// nextFrame splits the first complete message off buffer, returning it with
+// the unconsumed rest and the number of bytes dropped before it. Messages
+// start at a BeginString (8) and end at the CheckSum (10), so any number of
+// messages may be coalesced in one read or split across several. Bytes
+// outside a message, and a message cut short by the start of another, are
+// dropped. An empty message means buffer holds no complete message yet.
+func (c *Client) nextFrame(buffer []byte) (message string, rest []byte, discarded int) {
+	for {
+		start := c.findMessageStart(buffer, 0)
+		if start == -1 {
+			// Keep a trailing partial BeginString for the next read.
+			keep := 0
+			for k := len(beginStringPrefix) - 1; k > 0; k-- {
+				if len(buffer) >= k && bytes.HasPrefix(beginStringPrefix, buffer[len(buffer)-k:]) {
+					keep = k
+					break
+				}
+			}
+			return "", buffer[len(buffer)-keep:], discarded + len(buffer) - keep
+		}
+		discarded += start
+		buffer = buffer[start:]
+		
+		end := c.findMessageEnd(buffer)
+		if end == -1 {
+			return "", buffer, discarded
+		}
+		if next := c.findMessageStart(buffer, 1); next != -1 && next < end {
+			discarded += next
+			buffer = buffer[next:]
+			continue
+		}
+		return string(buffer[:end]), buffer[end:], discarded
+	}
+}
+
+// findMessageStart returns the index of the first BeginString at or after
+// from, or -1. A preceding digit means the bytes belong to another tag, such
+// as 58=FIX..., and are not a BeginString.
+func (c *Client) findMessageStart(buffer []byte, from int) int {
+	for i := from; i+len(beginStringPrefix) <= len(buffer); i++ {
+		if (i == 0 || !isDigit(buffer[i-1])) && bytes.HasPrefix(buffer[i:], beginStringPrefix) {
+			return i
+		}
+	}
+	return -1
+}
+
+// findMessageEnd returns the length of the first complete message in buffer,
+// or -1 if it has none yet. A message ends with the CheckSum field, which is
+// only recognized as a delimiter, "10=", exactly three digits and another
+// delimiter, so "10=" inside a value never ends a message early.
+func (c *Client) findMessageEnd(buffer []byte) int {
+	delimiter := c.delimiter[0]
+	for i := 1; i+7 <= len(buffer); i++ {
+		if buffer[i-1] != delimiter || buffer[i] != '1' || buffer[i+1] != '0' || buffer[i+2] != '=' {
+			continue
+		}
+		if isDigit(buffer[i+3]) && isDigit(buffer[i+4]) && isDigit(buffer[i+5]) && buffer[i+6] == delimiter {
+			return i + 7
+		}
+	}
+	return -1
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
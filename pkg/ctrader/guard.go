@@ -0,0 +1,49 @@
+package ctrader
+
+import (
+	"strings"
+)
+
+// tradeOnlyTypes and quoteOnlyTypes list the message types cServer accepts
+// on only one of its sessions.
+var (
+	tradeOnlyTypes = map[string]bool{"D": true, "F": true, "G": true, "H": true, "AD": true, "AF": true, "AN": true}
+	quoteOnlyTypes = map[string]bool{"V": true}
+)
+
+// WithSessionGuard makes Send refuse, without writing anything, messages the
+// configured session (TargetSubID QUOTE or TRADE) does not accept, such as an
+// order on a QUOTE session.
+func WithSessionGuard(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.sessionGuard = enabled
+	}
+}
+
+// checkSession returns an *UnsupportedMessageError when message cannot be
+// sent on the configured session.
+func (c *Client) checkSession(message Message) error {
+	typed, ok := message.(interface{ MsgType() string })
+	if !ok {
+		return nil
+	}
+	
+	msgType := typed.MsgType()
+	session := c.sessionName()
+	
+	if (session == "QUOTE" && tradeOnlyTypes[msgType]) || (session == "TRADE" && quoteOnlyTypes[msgType]) {
+		return &UnsupportedMessageError{
+			Session: session,
+			MsgType: msgType,
+			Text:    "refused by session guard",
+		}
+	}
+	return nil
+}
+
+// sessionName returns the cServer session the client is configured for,
+// QUOTE or TRADE, as named by TargetSubID. The session guard and reject
+// errors both use it.
+func (c *Client) sessionName() string {
+	return strings.ToUpper(c.config.TargetSubID)
+}
@@ -0,0 +1,167 @@
+package ctrader
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// withHeartbeatPeriod overrides the interval at which the server is expected
+// to send heartbeats, so tests need not wait whole seconds.
+func withHeartbeatPeriod(period time.Duration) ClientOption {
+	return func(c *Client) {
+		c.heartbeatOverride = period
+	}
+}
+
+// heartbeatPeriod returns the interval at which the server is expected to
+// send heartbeats: the HeartBtInt (108) of its last logon response, else the
+// configured HeartBeat. Callers must hold c.mu.
+func (c *Client) heartbeatPeriod() time.Duration {
+	if c.heartbeatOverride > 0 {
+		return c.heartbeatOverride
+	}
+	if c.sessionInfo.HeartBtInt > 0 {
+		return time.Duration(c.sessionInfo.HeartBtInt) * time.Second
+	}
+	return time.Duration(c.config.HeartBeat) * time.Second
+}
+
+// watchHeartbeats forces a reconnect once the server has been silent for
+// maxMissedHeartbeats heartbeat intervals, following the interval the server
+// announces on logon.
+func (c *Client) watchHeartbeats(ctx context.Context, conn net.Conn) {
+	c.mu.RLock()
+	interval := c.heartbeatPeriod()
+	c.mu.RUnlock()
+	if interval <= 0 {
+		return
+	}
+	
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.mu.RLock()
+			silence := time.Since(c.lastInbound)
+			if period := c.heartbeatPeriod(); period > 0 && period != interval {
+				interval = period
+				ticker.Reset(interval)
+			}
+			c.mu.RUnlock()
+			
+			if silence >= time.Duration(c.maxMissedHeartbeats)*interval {
+				go c.reconnect(conn, fmt.Errorf("%w: no message from server for %s (%d missed heartbeats)", ErrConnectionLost, silence.Round(time.Millisecond), c.maxMissedHeartbeats))
+				return
+			}
+		}
+	}
+}
+
+// WithAutoHeartbeat makes the client send a Heartbeat (35=0) after every
+// successful logon whenever nothing else has been sent for a heartbeat
+// interval, so applications need no heartbeat ticker of their own. It stops
+// when the connection ends.
+func WithAutoHeartbeat(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.autoHeartbeat = enabled
+	}
+}
+
+// WithHeartbeatInterval overrides the interval at which WithAutoHeartbeat
+// sends heartbeats, for brokers that want a shorter beat than the negotiated
+// HeartBeat.
+func WithHeartbeatInterval(interval time.Duration) ClientOption {
+	return func(c *Client) {
+		c.outboundHeartbeat = interval
+	}
+}
+
+// NegotiatedHeartbeat returns the HeartBtInt (108) of the server's last logon
+// response, or 0 before the first logon is acknowledged. cServer may answer
+// with a different interval than Config.HeartBeat; WithAutoHeartbeat then
+// beats at the server's interval.
+func (c *Client) NegotiatedHeartbeat() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return time.Duration(c.sessionInfo.HeartBtInt) * time.Second
+}
+
+// outboundHeartbeatPeriod returns the interval at which heartbeats are sent:
+// WithHeartbeatInterval, else the negotiated HeartBtInt, else the configured
+// HeartBeat. Callers must hold c.mu.
+func (c *Client) outboundHeartbeatPeriod() time.Duration {
+	if c.outboundHeartbeat > 0 {
+		return c.outboundHeartbeat
+	}
+	return c.heartbeatPeriod()
+}
+
+// startHeartbeating starts sendHeartbeats for the current connection,
+// replacing any heartbeat loop started by an earlier logon on it.
+func (c *Client) startHeartbeating(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	if c.stopHeartbeats != nil {
+		c.stopHeartbeats()
+	}
+	ctx, c.stopHeartbeats = context.WithCancel(ctx)
+	go c.sendHeartbeats(ctx)
+}
+
+// sendHeartbeats sends a heartbeat whenever the client has been silent for
+// the heartbeat interval; every Send pushes the next heartbeat back.
+func (c *Client) sendHeartbeats(ctx context.Context) {
+	c.mu.RLock()
+	interval := c.outboundHeartbeatPeriod()
+	c.mu.RUnlock()
+	if interval <= 0 {
+		return
+	}
+	
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			c.mu.RLock()
+			silence := time.Since(c.lastOutbound)
+			c.mu.RUnlock()
+			
+			if silence < interval {
+				timer.Reset(interval - silence)
+				continue
+			}
+			if err := c.Send(NewHeartbeat(c.config)); err != nil {
+				c.reportError(fmt.Errorf("failed to send heartbeat: %w", err))
+			}
+			timer.Reset(interval)
+		}
+	}
+}
+
+// WithAutoTestRequestReply makes the client answer every TestRequest (35=1)
+// with a Heartbeat echoing its TestReqID (112), including test requests that
+// arrive before the logon is acknowledged. Test requests are still delivered
+// to the application, which must then not reply itself.
+func WithAutoTestRequestReply(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.autoTestRequestReply = enabled
+	}
+}
+
+// replyToTestRequest sends the heartbeat answering testRequest.
+func (c *Client) replyToTestRequest(testRequest *ResponseMessage) {
+	if err := c.Send(NewTestRequestReply(c.config, testRequest)); err != nil {
+		c.reportError(fmt.Errorf("failed to answer test request %q: %w", testRequest.first(112), err))
+	}
+}
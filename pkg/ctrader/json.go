@@ -0,0 +1,129 @@
+package ctrader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// jsonMember is one key of a jsonObject.
+type jsonMember struct {
+	key   string
+	value interface{}
+}
+
+// jsonObject is a JSON object that keeps its keys in insertion order, so
+// fields appear in wire order.
+type jsonObject []jsonMember
+
+// set adds key, turning the value into an array if key repeats.
+func (o *jsonObject) set(key string, value interface{}) {
+	for i, member := range *o {
+		if member.key != key {
+			continue
+		}
+		if values, ok := member.value.([]interface{}); ok {
+			(*o)[i].value = append(values, value)
+		} else {
+			(*o)[i].value = []interface{}{member.value, value}
+		}
+		return
+	}
+	*o = append(*o, jsonMember{key: key, value: value})
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, member := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(member.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(member.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// ToJSON renders the message as a JSON object keyed by field name in wire
+// order, e.g. {"BeginString":"FIX.4.4",...}. Tags without a known name
+// appear as "Field<tag>" and the name of the MsgType is added as
+// "MsgTypeName". Instances of known repeating groups become an array of
+// objects under the name of the group's count field. All values are strings.
+func (rm *ResponseMessage) ToJSON() ([]byte, error) {
+	protocol := NewProtocol("")
+	fieldNames := protocol.GetFieldNames()
+	messageTypes := protocol.GetMessageTypeName()
+	
+	fieldName := func(tag int) string {
+		if name, exists := fieldNames[tag]; exists {
+			return name
+		}
+		return fmt.Sprintf("Field%d", tag)
+	}
+	
+	var result jsonObject
+	var members map[int]bool
+	var instances []jsonObject
+	groupName, delimiterTag := "", 0
+	
+	endGroup := func() {
+		if members == nil {
+			return
+		}
+		group := make([]interface{}, len(instances))
+		for i, instance := range instances {
+			group[i] = instance
+		}
+		result.set(groupName, group)
+		members = nil
+	}
+	
+	for _, field := range rm.Fields() {
+		if members != nil && !members[field.Tag] {
+			endGroup()
+		}
+		
+		if members == nil {
+			if groupTags, exists := repeatingGroups[field.Tag]; exists {
+				members = make(map[int]bool)
+				for _, tag := range groupTags {
+					members[tag] = true
+				}
+				instances = nil
+				groupName, delimiterTag = fieldName(field.Tag), 0
+				continue
+			}
+			
+			result.set(fieldName(field.Tag), field.Value)
+			if field.Tag == 35 {
+				if msgTypeName, exists := messageTypes[field.Value]; exists {
+					result.set("MsgTypeName", msgTypeName)
+				}
+			}
+			continue
+		}
+		
+		// The first field of a group starts every instance.
+		if delimiterTag == 0 {
+			delimiterTag = field.Tag
+		}
+		if field.Tag == delimiterTag {
+			instances = append(instances, nil)
+		}
+		instances[len(instances)-1].set(fieldName(field.Tag), field.Value)
+	}
+	endGroup()
+	
+	return json.Marshal(result)
+}
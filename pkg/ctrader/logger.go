@@ -0,0 +1,96 @@
+package ctrader
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Log levels passed to Logger.LogEvent.
+const (
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+// Logger receives the session traffic and lifecycle events of a client, e.g.
+// for an audit trail. LogOutbound and LogInbound get every raw message sent
+// and received; LogEvent gets connection events and errors with key-value
+// pairs. Methods may be called from several goroutines.
+type Logger interface {
+	LogOutbound(raw string)
+	LogInbound(raw string)
+	LogEvent(level, msg string, kv ...any)
+}
+
+// WithLogger sends session traffic and events to l. The default logger
+// discards everything.
+func WithLogger(l Logger) ClientOption {
+	return func(c *Client) {
+		if l == nil {
+			l = NopLogger{}
+		}
+		c.logger = l
+	}
+}
+
+// NopLogger is a Logger that discards everything.
+type NopLogger struct{}
+
+func (NopLogger) LogOutbound(raw string)                {}
+func (NopLogger) LogInbound(raw string)                 {}
+func (NopLogger) LogEvent(level, msg string, kv ...any) {}
+
+// WriterLogger is a Logger that pretty-prints messages with
+// Protocol.FormatMessage and writes events as single lines.
+type WriterLogger struct {
+	w        io.Writer
+	protocol *Protocol
+	mu       sync.Mutex
+}
+
+// NewStdoutLogger returns a WriterLogger printing to standard output.
+// delimiter is the client's field delimiter; empty means SOH.
+func NewStdoutLogger(delimiter string) *WriterLogger {
+	return NewWriterLogger(os.Stdout, delimiter)
+}
+
+// NewWriterLogger returns a WriterLogger printing to w.
+func NewWriterLogger(w io.Writer, delimiter string) *WriterLogger {
+	return &WriterLogger{w: w, protocol: NewProtocol(delimiter)}
+}
+
+func (l *WriterLogger) LogOutbound(raw string) {
+	l.write(fmt.Sprintf("%s >>> outbound\n%s", l.timestamp(), l.protocol.FormatMessage(raw)))
+}
+
+func (l *WriterLogger) LogInbound(raw string) {
+	l.write(fmt.Sprintf("%s <<< inbound\n%s", l.timestamp(), l.protocol.FormatMessage(raw)))
+}
+
+func (l *WriterLogger) LogEvent(level, msg string, kv ...any) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s [%s] %s", l.timestamp(), level, msg)
+	for i := 0; i < len(kv); i += 2 {
+		if i+1 < len(kv) {
+			fmt.Fprintf(&b, " %v=%v", kv[i], kv[i+1])
+		} else {
+			fmt.Fprintf(&b, " %v", kv[i])
+		}
+	}
+	b.WriteString("\n")
+	l.write(b.String())
+}
+
+func (l *WriterLogger) timestamp() string {
+	return time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+func (l *WriterLogger) write(s string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.w, s)
+}
@@ -0,0 +1,45 @@
+package ctrader
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// beginStringPattern finds the start of a FIX message in a log line, skipping
+// timestamps and other prefixes.
+var beginStringPattern = regexp.MustCompile(`(^|[^0-9])8=FIX`)
+
+// logDelimiters are the field separators recognized by ParseLogLine, in
+// order of preference.
+var logDelimiters = []string{"\x01", "|", "^"}
+
+// ParseLogLine parses a FIX message copied from a log, such as a broker log
+// or a WithRecorder recording. Anything before BeginString (8), like a
+// timestamp, is ignored and the delimiter (SOH, "|" or "^") is detected from
+// the message.
+func ParseLogLine(line string) (*ResponseMessage, error) {
+	loc := beginStringPattern.FindStringIndex(line)
+	if loc == nil {
+		return nil, fmt.Errorf("no FIX message found in log line")
+	}
+	
+	message := strings.TrimRight(line[loc[1]-len("8=FIX"):], "\r\n")
+	
+	delimiter := ""
+	end := len(message)
+	for _, candidate := range logDelimiters {
+		if index := strings.Index(message, candidate); index != -1 && index < end {
+			delimiter, end = candidate, index
+		}
+	}
+	if delimiter == "" {
+		return nil, fmt.Errorf("no field delimiter found in log line")
+	}
+	
+	msg := NewResponseMessage(message, delimiter)
+	if msg.GetMessageType() == "" {
+		return nil, fmt.Errorf("log line has no MsgType (35)")
+	}
+	return msg, nil
+}
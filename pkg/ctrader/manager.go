@@ -0,0 +1,213 @@
+package ctrader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// SessionManager runs the QUOTE and TRADE sessions of one account together.
+// cTrader serves market data and trading on separate sessions (ports 5211 and
+// 5212), so a bot needs both; the manager connects and logs on both, routes
+// market data from QUOTE and execution reports from TRADE to one set of
+// callbacks and lets both clients resolve symbols from one shared cache.
+//
+// The manager consumes the clients' Messages, AdminMessages and Errors
+// channels; use the callbacks instead of reading them.
+type SessionManager struct {
+	quote *Client
+	trade *Client
+	
+	mu                sync.RWMutex
+	onMarketData      func(*MarketDataSnapshot)
+	onIncremental     func(*MarketDataIncrementalRefresh)
+	onExecutionReport func(*ExecutionReport)
+	onError           func(session string, err error)
+	
+	stop      chan struct{}
+	stopOnce  sync.Once
+	routeOnce sync.Once
+	routing   sync.WaitGroup
+}
+
+// NewSessionManager combines quote and trade, clients for the QUOTE and
+// TRADE sessions of the same account. From here on both resolve symbols from
+// one cache, so a security list loaded on either session serves both.
+func NewSessionManager(quote, trade *Client) *SessionManager {
+	if quote != trade {
+		quote.symbols.merge(trade.symbols)
+		trade.mu.Lock()
+		trade.symbols = quote.symbols
+		trade.mu.Unlock()
+	}
+	
+	return &SessionManager{
+		quote: quote,
+		trade: trade,
+		stop:  make(chan struct{}),
+	}
+}
+
+// Quote returns the client of the QUOTE session.
+func (m *SessionManager) Quote() *Client {
+	return m.quote
+}
+
+// Trade returns the client of the TRADE session.
+func (m *SessionManager) Trade() *Client {
+	return m.trade
+}
+
+// SetMarketDataCallback sets the function called with every market data
+// snapshot (35=W) received on the QUOTE session.
+func (m *SessionManager) SetMarketDataCallback(callback func(*MarketDataSnapshot)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onMarketData = callback
+}
+
+// SetIncrementalRefreshCallback sets the function called with every market
+// data incremental refresh (35=X) received on the QUOTE session.
+func (m *SessionManager) SetIncrementalRefreshCallback(callback func(*MarketDataIncrementalRefresh)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onIncremental = callback
+}
+
+// SetExecutionReportCallback sets the function called with every execution
+// report (35=8) received on the TRADE session.
+func (m *SessionManager) SetExecutionReportCallback(callback func(*ExecutionReport)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onExecutionReport = callback
+}
+
+// SetErrorCallback sets the function called with the errors of both
+// sessions. session is the TargetSubID of the client that failed, e.g.
+// "QUOTE".
+func (m *SessionManager) SetErrorCallback(callback func(session string, err error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onError = callback
+}
+
+// Connect connects both sessions and logs on with ResetSeqNumFlag (141=Y),
+// QUOTE first, waiting for each logon to be answered. If either fails, both
+// are disconnected.
+func (m *SessionManager) Connect(ctx context.Context) error {
+	m.routeOnce.Do(func() {
+		m.routing.Add(2)
+		go m.route(m.quote, m.routeQuote)
+		go m.route(m.trade, m.routeTrade)
+	})
+	
+	for _, client := range []*Client{m.quote, m.trade} {
+		if err := m.logon(ctx, client); err != nil {
+			m.quote.Disconnect()
+			m.trade.Disconnect()
+			return err
+		}
+	}
+	return nil
+}
+
+// logon connects client and waits for the server's Logon (35=A).
+func (m *SessionManager) logon(ctx context.Context, client *Client) error {
+	session := client.sessionName()
+	
+	if err := client.ConnectContext(ctx); err != nil {
+		return fmt.Errorf("%s session: %w", session, err)
+	}
+	
+	logon := NewLogonRequest(client.config)
+	logon.ResetSeqNum = true
+	if _, err := client.SendAndWait(ctx, logon, func(msg *ResponseMessage) bool {
+		return msg.GetMessageType() == "A"
+	}); err != nil {
+		return fmt.Errorf("%s session logon: %w", session, err)
+	}
+	return nil
+}
+
+// Disconnect closes both sessions and stops routing their messages.
+func (m *SessionManager) Disconnect() {
+	m.quote.Disconnect()
+	m.trade.Disconnect()
+	m.stopOnce.Do(func() { close(m.stop) })
+	m.routing.Wait()
+}
+
+// route passes the messages and errors of client to handle and the error
+// callback until the manager is disconnected.
+func (m *SessionManager) route(client *Client, handle func(*ResponseMessage) error) {
+	defer m.routing.Done()
+	
+	session := client.sessionName()
+	for {
+		select {
+		case msg := <-client.Messages():
+			if err := handle(msg); err != nil {
+				m.reportError(session, err)
+			}
+		case <-client.AdminMessages():
+		case err := <-client.Errors():
+			m.reportError(session, err)
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+func (m *SessionManager) routeQuote(msg *ResponseMessage) error {
+	m.mu.RLock()
+	onMarketData, onIncremental := m.onMarketData, m.onIncremental
+	m.mu.RUnlock()
+	
+	switch msg.GetMessageType() {
+	case "W":
+		if onMarketData == nil {
+			return nil
+		}
+		snapshot, err := ParseMarketDataSnapshot(msg)
+		if err != nil {
+			return err
+		}
+		onMarketData(snapshot)
+	case "X":
+		if onIncremental == nil {
+			return nil
+		}
+		refresh, err := ParseMarketDataIncrementalRefresh(msg)
+		if err != nil {
+			return err
+		}
+		onIncremental(refresh)
+	}
+	return nil
+}
+
+func (m *SessionManager) routeTrade(msg *ResponseMessage) error {
+	m.mu.RLock()
+	onExecutionReport := m.onExecutionReport
+	m.mu.RUnlock()
+	
+	if msg.GetMessageType() != "8" || onExecutionReport == nil {
+		return nil
+	}
+	report, err := ParseExecutionReport(msg)
+	if err != nil {
+		return err
+	}
+	onExecutionReport(report)
+	return nil
+}
+
+func (m *SessionManager) reportError(session string, err error) {
+	m.mu.RLock()
+	onError := m.onError
+	m.mu.RUnlock()
+	
+	if onError != nil {
+		onError(session, err)
+	}
+}
@@ -0,0 +1,350 @@
+package ctrader
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// MDEntryType is the MDEntryType (269) of a market data entry.
+type MDEntryType string
+
+const (
+	MDEntryBid   MDEntryType = "0"
+	MDEntryOffer MDEntryType = "1"
+	MDEntryTrade MDEntryType = "2"
+)
+
+func (t MDEntryType) String() string {
+	switch t {
+	case MDEntryBid:
+		return "bid"
+	case MDEntryOffer:
+		return "ask"
+	case MDEntryTrade:
+		return "trade"
+	}
+	return string(t)
+}
+
+// MDEntry is one price level of a market data message.
+type MDEntry struct {
+	Type    MDEntryType
+	Price   float64
+	Size    float64
+	EntryID string // MDEntryID (278), set on depth subscriptions
+	
+	// Level is the 1-based depth of the entry on its side: MDPriceLevel
+	// (1023) when the server sends it, otherwise the entry's position among
+	// the entries of the same type in the message.
+	Level int
+}
+
+func newMDEntry(entry mdEntry, level int) MDEntry {
+	return MDEntry{
+		Type:    MDEntryType(entry.entryType),
+		Price:   entry.price,
+		Size:    entry.size,
+		EntryID: entry.entryID,
+		Level:   level,
+	}
+}
+
+// MarketDataSnapshot is a parsed market data snapshot (35=W).
+type MarketDataSnapshot struct {
+	MDReqID string
+	Symbol  string
+	Entries []MDEntry
+}
+
+func newMarketDataSnapshot(msg *ResponseMessage) *MarketDataSnapshot {
+	snapshot := &MarketDataSnapshot{
+		MDReqID: msg.first(262),
+		Symbol:  msg.first(55),
+	}
+	
+	levels := make(map[string]int)
+	for _, entry := range mdEntries(msg) {
+		levels[entry.entryType]++
+		level := entry.level
+		if level == 0 {
+			level = levels[entry.entryType]
+		}
+		snapshot.Entries = append(snapshot.Entries, newMDEntry(entry, level))
+	}
+	
+	return snapshot
+}
+
+// ParseMarketDataSnapshot parses a market data snapshot (35=W), walking its
+// NoMDEntries (268) group in wire order so that every entry keeps its own
+// type, price and size.
+func ParseMarketDataSnapshot(msg *ResponseMessage) (*MarketDataSnapshot, error) {
+	if msgType := msg.GetMessageType(); msgType != "W" {
+		return nil, fmt.Errorf("expected a market data snapshot (35=W), got MsgType %q", msgType)
+	}
+	
+	snapshot := newMarketDataSnapshot(msg)
+	if declared, err := strconv.Atoi(msg.first(268)); err == nil && declared != len(snapshot.Entries) {
+		return nil, fmt.Errorf("%w: NoMDEntries declares %d entries but %d were parsed", ErrMalformedMessage, declared, len(snapshot.Entries))
+	}
+	return snapshot, nil
+}
+
+// Bids returns the bid entries of the snapshot in wire order.
+func (s *MarketDataSnapshot) Bids() []MDEntry {
+	return s.entriesOfType(MDEntryBid)
+}
+
+// Asks returns the offer entries of the snapshot in wire order.
+func (s *MarketDataSnapshot) Asks() []MDEntry {
+	return s.entriesOfType(MDEntryOffer)
+}
+
+func (s *MarketDataSnapshot) entriesOfType(entryType MDEntryType) []MDEntry {
+	var entries []MDEntry
+	for _, entry := range s.Entries {
+		if entry.Type == entryType {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// mdReqRejReasons describes the MDReqRejReason (281) codes.
+var mdReqRejReasons = map[string]string{
+	"0": "unknown symbol",
+	"1": "duplicate MDReqID",
+	"2": "insufficient bandwidth",
+	"3": "insufficient permissions",
+	"4": "unsupported SubscriptionRequestType",
+	"5": "unsupported MarketDepth",
+	"6": "unsupported MDUpdateType",
+	"7": "unsupported AggregatedBook",
+	"8": "unsupported MDEntryType",
+}
+
+// MarketDataRequestReject is a parsed market data request reject (35=Y). It
+// is also the error reported when a subscription is rejected and matches
+// ErrRequestRejected with errors.Is.
+type MarketDataRequestReject struct {
+	MDReqID string
+	Reason  string // MDReqRejReason (281)
+	Text    string
+}
+
+func newMarketDataRequestReject(msg *ResponseMessage) *MarketDataRequestReject {
+	return &MarketDataRequestReject{
+		MDReqID: msg.first(262),
+		Reason:  msg.first(281),
+		Text:    msg.first(58),
+	}
+}
+
+// ParseMarketDataRequestReject parses a market data request reject (35=Y).
+func ParseMarketDataRequestReject(msg *ResponseMessage) (*MarketDataRequestReject, error) {
+	if msgType := msg.GetMessageType(); msgType != "Y" {
+		return nil, fmt.Errorf("expected a market data request reject (35=Y), got MsgType %q", msgType)
+	}
+	return newMarketDataRequestReject(msg), nil
+}
+
+// ReasonText describes Reason, e.g. "unknown symbol" for 0.
+func (r *MarketDataRequestReject) ReasonText() string {
+	if text, exists := mdReqRejReasons[r.Reason]; exists {
+		return text
+	}
+	return r.Reason
+}
+
+func (r *MarketDataRequestReject) Error() string {
+	msg := fmt.Sprintf("market data request %s rejected", r.MDReqID)
+	if r.Reason != "" {
+		msg += fmt.Sprintf(": %s (281=%s)", r.ReasonText(), r.Reason)
+	}
+	if r.Text != "" {
+		msg += ": " + r.Text
+	}
+	return msg
+}
+
+func (r *MarketDataRequestReject) Is(target error) bool {
+	return target == ErrRequestRejected
+}
+
+// MDUpdateAction is the MDUpdateAction (279) of an incremental entry.
+type MDUpdateAction string
+
+const (
+	MDUpdateNew    MDUpdateAction = "0"
+	MDUpdateChange MDUpdateAction = "1"
+	MDUpdateDelete MDUpdateAction = "2"
+)
+
+// MDIncrement is one entry of an incremental refresh. Level is only set when
+// the server sends MDPriceLevel (1023).
+type MDIncrement struct {
+	MDEntry
+	Action MDUpdateAction
+	Symbol string
+}
+
+// MarketDataIncrementalRefresh is a parsed market data incremental refresh
+// (35=X).
+type MarketDataIncrementalRefresh struct {
+	MDReqID string
+	Entries []MDIncrement
+}
+
+func newMarketDataIncrementalRefresh(msg *ResponseMessage) *MarketDataIncrementalRefresh {
+	refresh := &MarketDataIncrementalRefresh{MDReqID: msg.first(262)}
+	for _, entry := range mdEntries(msg) {
+		refresh.Entries = append(refresh.Entries, MDIncrement{
+			MDEntry: newMDEntry(entry, entry.level),
+			Action:  MDUpdateAction(entry.updateAction),
+			Symbol:  entry.symbol,
+		})
+	}
+	return refresh
+}
+
+// ParseMarketDataIncrementalRefresh parses a market data incremental refresh
+// (35=X) into its entries in wire order.
+func ParseMarketDataIncrementalRefresh(msg *ResponseMessage) (*MarketDataIncrementalRefresh, error) {
+	if msgType := msg.GetMessageType(); msgType != "X" {
+		return nil, fmt.Errorf("expected a market data incremental refresh (35=X), got MsgType %q", msgType)
+	}
+	
+	refresh := newMarketDataIncrementalRefresh(msg)
+	if declared, err := strconv.Atoi(msg.first(268)); err == nil && declared != len(refresh.Entries) {
+		return nil, fmt.Errorf("%w: NoMDEntries declares %d entries but %d were parsed", ErrMalformedMessage, declared, len(refresh.Entries))
+	}
+	return refresh, nil
+}
+
+// OrderBook maintains the book of one symbol from a snapshot followed by
+// incremental refreshes. Entries are identified by MDEntryID (278), or by
+// price when the server sends none. It is safe for concurrent use.
+type OrderBook struct {
+	Symbol string
+	
+	mu   sync.RWMutex
+	bids map[string]MDEntry
+	asks map[string]MDEntry
+}
+
+// NewOrderBook returns an empty book for symbol.
+func NewOrderBook(symbol string) *OrderBook {
+	return &OrderBook{
+		Symbol: symbol,
+		bids:   make(map[string]MDEntry),
+		asks:   make(map[string]MDEntry),
+	}
+}
+
+// bookKey identifies entry within its side of the book.
+func bookKey(entry MDEntry) string {
+	if entry.EntryID != "" {
+		return entry.EntryID
+	}
+	return strconv.FormatFloat(entry.Price, 'f', -1, 64)
+}
+
+// side returns the half of the book holding entries of entryType, or nil for
+// trades and other types the book does not keep.
+func (b *OrderBook) side(entryType MDEntryType) map[string]MDEntry {
+	switch entryType {
+	case MDEntryBid:
+		return b.bids
+	case MDEntryOffer:
+		return b.asks
+	}
+	return nil
+}
+
+// ApplySnapshot replaces the contents of the book with snapshot. Snapshots
+// for other symbols are ignored.
+func (b *OrderBook) ApplySnapshot(snapshot *MarketDataSnapshot) {
+	if snapshot.Symbol != b.Symbol {
+		return
+	}
+	
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	
+	b.bids = make(map[string]MDEntry)
+	b.asks = make(map[string]MDEntry)
+	for _, entry := range snapshot.Entries {
+		if side := b.side(entry.Type); side != nil {
+			side[bookKey(entry)] = entry
+		}
+	}
+}
+
+// ApplyIncrement applies the entries of refresh that belong to the book's
+// symbol. A delete without an MDEntryType removes the entry from either side.
+func (b *OrderBook) ApplyIncrement(refresh *MarketDataIncrementalRefresh) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	
+	for _, update := range refresh.Entries {
+		if update.Symbol != "" && update.Symbol != b.Symbol {
+			continue
+		}
+		key := bookKey(update.MDEntry)
+		
+		if update.Action == MDUpdateDelete {
+			if side := b.side(update.Type); side != nil {
+				delete(side, key)
+			} else {
+				delete(b.bids, key)
+				delete(b.asks, key)
+			}
+			continue
+		}
+		if side := b.side(update.Type); side != nil {
+			side[key] = update.MDEntry
+		}
+	}
+}
+
+// Bids returns the bids from best (highest) to worst with Level numbered
+// from 1.
+func (b *OrderBook) Bids() []MDEntry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return sortedLevels(b.bids, func(x, y float64) bool { return x > y })
+}
+
+// Asks returns the offers from best (lowest) to worst with Level numbered
+// from 1.
+func (b *OrderBook) Asks() []MDEntry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return sortedLevels(b.asks, func(x, y float64) bool { return x < y })
+}
+
+func sortedLevels(side map[string]MDEntry, better func(x, y float64) bool) []MDEntry {
+	entries := make([]MDEntry, 0, len(side))
+	for _, entry := range side {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return better(entries[i].Price, entries[j].Price)
+	})
+	for i := range entries {
+		entries[i].Level = i + 1
+	}
+	return entries
+}
+
+// BestBidAsk returns the top of the book. It reports false unless both sides
+// have at least one entry.
+func (b *OrderBook) BestBidAsk() (bid, ask MDEntry, ok bool) {
+	bids, asks := b.Bids(), b.Asks()
+	if len(bids) == 0 || len(asks) == 0 {
+		return MDEntry{}, MDEntry{}, false
+	}
+	return bids[0], asks[0], true
+}
@@ -2,6 +2,7 @@ package ctrader
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -16,16 +17,49 @@ type Config struct {
 	Username     string
 	Password     string
 	HeartBeat    int
+	
+	// Account (1) is put on every order, cancel and replace built with this
+	// config, for accounts whose orders are routed by it. Empty omits it.
+	Account string
+	
+	// EncryptMethod (98) and ResetSeqNum (141=Y) are the defaults of every
+	// LogonRequest built with this config; a request can still override them.
+	// cTrader only supports EncryptMethod 0 (none).
+	EncryptMethod int
+	ResetSeqNum   bool
+	
+	// MaxIDLength and IDPattern limit the client-assigned IDs (ClOrdID,
+	// MDReqID, SecurityReqID, PosReqID, ...) of requests built with this
+	// config. Zero and nil mean cServer's limits of 50 characters from
+	// [A-Za-z0-9_.:-]. An empty ID is always refused.
+	MaxIDLength int
+	IDPattern   *regexp.Regexp
 }
 
 type ResponseMessage struct {
-	message string
-	fields  map[int][]string
+	message   string
+	fields    map[int][]string
+	ordered   []Field
+	fragments int
+	outbound  bool
+}
+
+// Field is a single tag=value pair of a message.
+type Field struct {
+	Tag   int
+	Value string
 }
 
+// NewResponseMessage parses a raw FIX message split on delimiter. FIX has no
+// escaping, so a value containing the delimiter byte cannot be told apart
+// from a field boundary; the one exception handled is free text in Text (58),
+// where a fragment that is not a tag=value pair is kept as part of the text.
+// Other stray fragments are counted and reported by checkIntegrity.
 func NewResponseMessage(message, delimiter string) *ResponseMessage {
 	processedMessage := strings.ReplaceAll(message, delimiter, "|")
 	fields := make(map[int][]string)
+	var ordered []Field
+	fragments := 0
 	
 	parts := strings.Split(message, delimiter)
 	for _, part := range parts {
@@ -37,16 +71,141 @@ func NewResponseMessage(message, delimiter string) *ResponseMessage {
 			fieldValue := part[eqIndex+1:]
 			if fieldNum, err := strconv.Atoi(fieldNumStr); err == nil {
 				fields[fieldNum] = append(fields[fieldNum], fieldValue)
+				ordered = append(ordered, Field{Tag: fieldNum, Value: fieldValue})
+				continue
 			}
 		}
+		
+		// Text (58) is the only free-form field cServer sends, so a fragment
+		// right after it is most likely its own content.
+		if last := len(ordered) - 1; last >= 0 && ordered[last].Tag == 58 {
+			ordered[last].Value += delimiter + part
+			texts := fields[58]
+			texts[len(texts)-1] = ordered[last].Value
+			continue
+		}
+		fragments++
 	}
 	
 	return &ResponseMessage{
-		message: processedMessage,
-		fields:  fields,
+		message:   processedMessage,
+		fields:    fields,
+		ordered:   ordered,
+		fragments: fragments,
+	}
+}
+
+// groupInstances splits the repeating group whose count field is at index
+// countIndex of the ordered fields into its instances. The first field after
+// the count starts every instance and the group ends at the first field that
+// is not one of its members.
+func (rm *ResponseMessage) groupInstances(countIndex int) [][]Field {
+	memberTags := make(map[int]bool)
+	for _, tag := range repeatingGroups[rm.ordered[countIndex].Tag] {
+		memberTags[tag] = true
+	}
+	
+	var instances [][]Field
+	delimiterTag := 0
+	for _, field := range rm.ordered[countIndex+1:] {
+		if !memberTags[field.Tag] {
+			break
+		}
+		if delimiterTag == 0 {
+			delimiterTag = field.Tag
+		}
+		if field.Tag == delimiterTag {
+			instances = append(instances, nil)
+		}
+		instances[len(instances)-1] = append(instances[len(instances)-1], field)
+	}
+	return instances
+}
+
+// GetGroups returns the instances of the first repeating group counted by
+// countTag, one map per instance, keeping each instance's fields together:
+// msg.GetGroups(268, 269) returns every market data entry with its 269, 270
+// and 271. delimiterTag is the tag that starts each instance. Groups the
+// library knows end at the first non-member field; for others the last
+// instance ends at a repeated tag or the trailer. Nil is returned when the
+// message has no such group.
+func (rm *ResponseMessage) GetGroups(countTag int, delimiterTag int) []map[int]string {
+	countIndex := -1
+	for i, field := range rm.ordered {
+		if field.Tag == countTag {
+			countIndex = i
+			break
+		}
+	}
+	if countIndex == -1 {
+		return nil
+	}
+	count, _ := strconv.Atoi(rm.ordered[countIndex].Value)
+	
+	members, known := repeatingGroups[countTag]
+	memberTags := make(map[int]bool)
+	for _, tag := range members {
+		memberTags[tag] = true
+	}
+	
+	var groups []map[int]string
+	for _, field := range rm.ordered[countIndex+1:] {
+		if known && !memberTags[field.Tag] {
+			break
+		}
+		if field.Tag == delimiterTag {
+			if len(groups) == count {
+				break
+			}
+			groups = append(groups, make(map[int]string))
+		} else if len(groups) == 0 {
+			break
+		}
+		
+		current := groups[len(groups)-1]
+		if _, repeated := current[field.Tag]; repeated || field.Tag == 10 {
+			break
+		}
+		current[field.Tag] = field.Value
+	}
+	return groups
+}
+
+// checkIntegrity reports signs of a value containing the delimiter: fragments
+// that are not tag=value pairs, or repeating groups whose instance count
+// differs from their declared count.
+func (rm *ResponseMessage) checkIntegrity() error {
+	if rm.fragments > 0 {
+		return fmt.Errorf("%w: %d fragment(s) without a tag", ErrMalformedMessage, rm.fragments)
+	}
+	
+	for i, field := range rm.ordered {
+		if _, isGroup := repeatingGroups[field.Tag]; !isGroup {
+			continue
+		}
+		declared, err := strconv.Atoi(field.Value)
+		if err != nil {
+			return fmt.Errorf("%w: group count %d=%q is not a number", ErrMalformedMessage, field.Tag, field.Value)
+		}
+		
+		if instances := len(rm.groupInstances(i)); instances != declared {
+			return fmt.Errorf("%w: group %d declares %d entries but %d were parsed", ErrMalformedMessage, field.Tag, declared, instances)
+		}
 	}
+	
+	return nil
 }
 
+// Fields returns the message's fields in wire order, including every
+// instance of repeating-group fields.
+func (rm *ResponseMessage) Fields() []Field {
+	return rm.ordered
+}
+
+// GetFieldValue returns nil when the tag is absent, a string when it occurs
+// once and a []string when it repeats, as in repeating groups. A .(string)
+// assertion therefore panics on a snapshot with several entries; prefer
+// GetFirst, GetFieldValues or the typed accessors.
 func (rm *ResponseMessage) GetFieldValue(fieldNumber int) interface{} {
 	values, exists := rm.fields[fieldNumber]
 	if !exists {
@@ -58,6 +217,97 @@ func (rm *ResponseMessage) GetFieldValue(fieldNumber int) interface{} {
 	return values
 }
 
+// GetString returns the first value of tag, reporting false when the tag is
+// absent.
+func (rm *ResponseMessage) GetString(tag int) (string, bool) {
+	values, exists := rm.fields[tag]
+	if !exists || len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// GetFloat returns the first value of tag parsed as a number. It reports false
+// when the tag is absent or not numeric. Values on the wire are always text,
+// so use it rather than asserting GetFieldValue's result to float64.
+func (rm *ResponseMessage) GetFloat(tag int) (float64, bool) {
+	value, err := strconv.ParseFloat(rm.first(tag), 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// GetInt returns the first value of tag parsed as an integer. It reports false
+// when the tag is absent or not an integer.
+func (rm *ResponseMessage) GetInt(tag int) (int, bool) {
+	value, err := strconv.Atoi(rm.first(tag))
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// GetTime returns the first value of tag parsed as a FIX UTCTimestamp
+// (20060102-15:04:05 with optional milliseconds). It reports false when the
+// tag is absent or not a timestamp.
+func (rm *ResponseMessage) GetTime(tag int) (time.Time, bool) {
+	value := parseUTCTimestamp(rm.first(tag))
+	return value, !value.IsZero()
+}
+
+// BestBidAsk returns the first bid (269=0) and offer (269=1) prices of a
+// market data message. It reports false unless both sides are present.
+func (rm *ResponseMessage) BestBidAsk() (bid, ask float64, ok bool) {
+	hasBid, hasAsk := false, false
+	
+	for _, entry := range mdEntries(rm) {
+		if !entry.hasPrice {
+			continue
+		}
+		if entry.entryType == "0" && !hasBid {
+			bid, hasBid = entry.price, true
+		} else if entry.entryType == "1" && !hasAsk {
+			ask, hasAsk = entry.price, true
+		}
+	}
+	
+	return bid, ask, hasBid && hasAsk
+}
+
+// GetFieldValues returns every value of tag in wire order, or an empty slice
+// when it is absent.
+func (rm *ResponseMessage) GetFieldValues(tag int) []string {
+	return append([]string{}, rm.fields[tag]...)
+}
+
+// GetFirst returns the first value of tag, or "" when it is absent.
+func (rm *ResponseMessage) GetFirst(tag int) string {
+	return rm.first(tag)
+}
+
+// first returns the first value of tag, or "" if it is absent.
+func (rm *ResponseMessage) first(tag int) string {
+	if values, exists := rm.fields[tag]; exists && len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// PossDup reports whether PossDupFlag (43) is set, i.e. the message is a
+// resend of a sequence number that may already have been received.
+func (rm *ResponseMessage) PossDup() bool {
+	return rm.first(43) == "Y"
+}
+
+// PossResend reports whether PossResendFlag (97) is set, i.e. the message
+// may have been sent before under another sequence number. The client
+// delivers such messages; spotting application-level duplicates, e.g. by
+// ExecID, is up to the application.
+func (rm *ResponseMessage) PossResend() bool {
+	return rm.first(97) == "Y"
+}
+
 func (rm *ResponseMessage) GetMessageType() string {
 	if values, exists := rm.fields[35]; exists && len(values) > 0 {
 		return values[0]
@@ -69,17 +319,35 @@ func (rm *ResponseMessage) GetMessage() string {
 	return rm.message
 }
 
+// IsOutbound reports whether the message was sent by this client and echoed
+// on Messages() by WithEchoOutbound, rather than received.
+func (rm *ResponseMessage) IsOutbound() bool {
+	return rm.outbound
+}
+
+// Message is anything Client.Send can send: it serializes itself as a
+// complete frame carrying the given MsgSeqNum (34). Every request type of
+// this package implements it, as can application types.
+type Message interface {
+	GetMessage(sequenceNumber int) string
+}
+
 type RequestMessageInterface interface {
 	GetMessage(sequenceNumber int) string
 	getBody() string
-	getHeader(lenBody int, sequenceNumber int) string
+	getHeader(sequenceNumber int) string
 	getTrailer(headerAndBody string) string
 }
 
+// RequestMessage holds what all requests share: the header fields and the
+// framing done by GetMessage. Request constructors set body to the concrete
+// type's GetBody so the embedding type only has to supply its body.
 type RequestMessage struct {
 	messageType string
 	config      *Config
 	delimiter   string
+	body        func() string
+	clock       clock
 }
 
 func NewRequestMessage(messageType string, config *Config) *RequestMessage {
@@ -90,36 +358,48 @@ func NewRequestMessage(messageType string, config *Config) *RequestMessage {
 	}
 }
 
+// MsgType returns the FIX MsgType (tag 35) of the request.
+func (rm *RequestMessage) MsgType() string {
+	return rm.messageType
+}
+
 func (rm *RequestMessage) GetMessage(sequenceNumber int) string {
-	body := rm.getBody()
-	var headerAndBody string
-	if body != "" {
-		header := rm.getHeader(len(body), sequenceNumber)
-		headerAndBody = fmt.Sprintf("%s%s%s%s", header, rm.delimiter, body, rm.delimiter)
-	} else {
-		header := rm.getHeader(0, sequenceNumber)
-		headerAndBody = fmt.Sprintf("%s%s", header, rm.delimiter)
-	}
-	trailer := rm.getTrailer(headerAndBody)
-	return fmt.Sprintf("%s%s%s", headerAndBody, trailer, rm.delimiter)
+	return rm.assemble(rm.getBody(), sequenceNumber)
 }
 
 func (rm *RequestMessage) getBody() string {
-	return ""
+	if rm.body == nil {
+		return ""
+	}
+	return rm.body()
 }
 
-func (rm *RequestMessage) getHeader(lenBody int, sequenceNumber int) string {
+// getHeader returns the standard header fields from MsgType (35) on. The
+// BeginString (8) and BodyLength (9) are added by assemble.
+func (rm *RequestMessage) getHeader(sequenceNumber int) string {
 	var fields []string
 	fields = append(fields, fmt.Sprintf("35=%s", rm.messageType))
 	fields = append(fields, fmt.Sprintf("49=%s", rm.config.SenderCompID))
 	fields = append(fields, fmt.Sprintf("56=%s", rm.config.TargetCompID))
-	fields = append(fields, fmt.Sprintf("57=%s", rm.config.TargetSubID))
-	fields = append(fields, fmt.Sprintf("50=%s", rm.config.SenderSubID))
 	fields = append(fields, fmt.Sprintf("34=%d", sequenceNumber))
-	fields = append(fields, fmt.Sprintf("52=%s", time.Now().UTC().Format("20060102-15:04:05")))
+	fields = append(fields, fmt.Sprintf("50=%s", rm.config.SenderSubID))
+	fields = append(fields, fmt.Sprintf("57=%s", rm.config.TargetSubID))
+	fields = append(fields, fmt.Sprintf("52=%s", rm.clock.format(rm.clock.now())))
+	
+	return strings.Join(fields, rm.delimiter)
+}
+
+// assemble frames body into a complete message. BodyLength (9) is the byte
+// count of everything after its own delimiter up to and including the
+// delimiter before CheckSum (10), measured on the assembled payload.
+func (rm *RequestMessage) assemble(body string, sequenceNumber int) string {
+	payload := rm.getHeader(sequenceNumber) + rm.delimiter
+	if body != "" {
+		payload += body + rm.delimiter
+	}
 	
-	fieldsJoined := strings.Join(fields, rm.delimiter)
-	return fmt.Sprintf("8=%s%s9=%d%s%s", rm.config.BeginString, rm.delimiter, lenBody+len(fieldsJoined)+2, rm.delimiter, fieldsJoined)
+	headerAndBody := fmt.Sprintf("8=%s%s9=%d%s%s", rm.config.BeginString, rm.delimiter, len(payload), rm.delimiter, payload)
+	return fmt.Sprintf("%s%s%s", headerAndBody, rm.getTrailer(headerAndBody), rm.delimiter)
 }
 
 func (rm *RequestMessage) getTrailer(headerAndBody string) string {
@@ -139,25 +419,13 @@ type LogonRequest struct {
 }
 
 func NewLogonRequest(config *Config) *LogonRequest {
-	return &LogonRequest{
-		RequestMessage:  NewRequestMessage("A", config),
-		EncryptionScheme: 0,
-		ResetSeqNum:      false,
-	}
-}
-
-func (lr *LogonRequest) GetMessage(sequenceNumber int) string {
-	body := lr.GetBody()
-	var headerAndBody string
-	if body != "" {
-		header := lr.RequestMessage.getHeader(len(body), sequenceNumber)
-		headerAndBody = fmt.Sprintf("%s%s%s%s", header, lr.delimiter, body, lr.delimiter)
-	} else {
-		header := lr.RequestMessage.getHeader(0, sequenceNumber)
-		headerAndBody = fmt.Sprintf("%s%s", header, lr.delimiter)
+	lr := &LogonRequest{
+		RequestMessage:   NewRequestMessage("A", config),
+		EncryptionScheme: config.EncryptMethod,
+		ResetSeqNum:      config.ResetSeqNum,
 	}
-	trailer := lr.RequestMessage.getTrailer(headerAndBody)
-	return fmt.Sprintf("%s%s%s", headerAndBody, trailer, lr.delimiter)
+	lr.body = lr.GetBody
+	return lr
 }
 
 func (lr *LogonRequest) GetBody() string {
@@ -178,23 +446,20 @@ type Heartbeat struct {
 }
 
 func NewHeartbeat(config *Config) *Heartbeat {
-	return &Heartbeat{
+	h := &Heartbeat{
 		RequestMessage: NewRequestMessage("0", config),
 	}
+	h.body = h.GetBody
+	return h
 }
 
-func (h *Heartbeat) GetMessage(sequenceNumber int) string {
-	body := h.GetBody()
-	var headerAndBody string
-	if body != "" {
-		header := h.RequestMessage.getHeader(len(body), sequenceNumber)
-		headerAndBody = fmt.Sprintf("%s%s%s%s", header, h.delimiter, body, h.delimiter)
-	} else {
-		header := h.RequestMessage.getHeader(0, sequenceNumber)
-		headerAndBody = fmt.Sprintf("%s%s", header, h.delimiter)
-	}
-	trailer := h.RequestMessage.getTrailer(headerAndBody)
-	return fmt.Sprintf("%s%s%s", headerAndBody, trailer, h.delimiter)
+// NewTestRequestReply returns the heartbeat answering testRequest (35=1),
+// echoing its TestReqID (112). Unsolicited heartbeats should be created with
+// NewHeartbeat so they never carry a TestReqID.
+func NewTestRequestReply(config *Config, testRequest *ResponseMessage) *Heartbeat {
+	heartbeat := NewHeartbeat(config)
+	heartbeat.TestReqID = testRequest.first(112)
+	return heartbeat
 }
 
 func (h *Heartbeat) GetBody() string {
@@ -210,23 +475,11 @@ type TestRequest struct {
 }
 
 func NewTestRequest(config *Config) *TestRequest {
-	return &TestRequest{
+	tr := &TestRequest{
 		RequestMessage: NewRequestMessage("1", config),
 	}
-}
-
-func (tr *TestRequest) GetMessage(sequenceNumber int) string {
-	body := tr.GetBody()
-	var headerAndBody string
-	if body != "" {
-		header := tr.RequestMessage.getHeader(len(body), sequenceNumber)
-		headerAndBody = fmt.Sprintf("%s%s%s%s", header, tr.delimiter, body, tr.delimiter)
-	} else {
-		header := tr.RequestMessage.getHeader(0, sequenceNumber)
-		headerAndBody = fmt.Sprintf("%s%s", header, tr.delimiter)
-	}
-	trailer := tr.RequestMessage.getTrailer(headerAndBody)
-	return fmt.Sprintf("%s%s%s", headerAndBody, trailer, tr.delimiter)
+	tr.body = tr.GetBody
+	return tr
 }
 
 func (tr *TestRequest) GetBody() string {
@@ -235,101 +488,227 @@ func (tr *TestRequest) GetBody() string {
 
 type LogoutRequest struct {
 	*RequestMessage
-}
-
-func (lr *LogoutRequest) GetMessage(sequenceNumber int) string {
-	body := lr.GetBody()
-	var headerAndBody string
-	if body != "" {
-		header := lr.RequestMessage.getHeader(len(body), sequenceNumber)
-		headerAndBody = fmt.Sprintf("%s%s%s%s", header, lr.delimiter, body, lr.delimiter)
-	} else {
-		header := lr.RequestMessage.getHeader(0, sequenceNumber)
-		headerAndBody = fmt.Sprintf("%s%s", header, lr.delimiter)
-	}
-	trailer := lr.RequestMessage.getTrailer(headerAndBody)
-	return fmt.Sprintf("%s%s%s", headerAndBody, trailer, lr.delimiter)
+	Text string
 }
 
 func (lr *LogoutRequest) GetBody() string {
+	if lr.Text != "" {
+		return fmt.Sprintf("58=%s", lr.Text)
+	}
 	return ""
 }
 
 func NewLogoutRequest(config *Config) *LogoutRequest {
-	return &LogoutRequest{
+	lr := &LogoutRequest{
 		RequestMessage: NewRequestMessage("5", config),
 	}
+	lr.body = lr.GetBody
+	return lr
 }
 
-type OrderMsg struct {
+type ResendRequest struct {
 	*RequestMessage
-	ClOrdID  string
-	Symbol   string
-	Side     string
-	OrderQty float64
-	OrdType  string
-	Price    float64
+	BeginSeqNo int
+	EndSeqNo   int
 }
 
-func NewOrderMsg(config *Config) *OrderMsg {
-	return &OrderMsg{
-		RequestMessage: NewRequestMessage("D", config),
+func NewResendRequest(config *Config) *ResendRequest {
+	rr := &ResendRequest{
+		RequestMessage: NewRequestMessage("2", config),
 	}
+	rr.body = rr.GetBody
+	return rr
 }
 
-func (nos *OrderMsg) GetMessage(sequenceNumber int) string {
-	body := nos.GetBody()
-	var headerAndBody string
-	if body != "" {
-		header := nos.RequestMessage.getHeader(len(body), sequenceNumber)
-		headerAndBody = fmt.Sprintf("%s%s%s%s", header, nos.delimiter, body, nos.delimiter)
-	} else {
-		header := nos.RequestMessage.getHeader(0, sequenceNumber)
-		headerAndBody = fmt.Sprintf("%s%s", header, nos.delimiter)
+func (rr *ResendRequest) GetBody() string {
+	var fields []string
+	fields = append(fields, fmt.Sprintf("7=%d", rr.BeginSeqNo))
+	fields = append(fields, fmt.Sprintf("16=%d", rr.EndSeqNo))
+	return strings.Join(fields, rr.delimiter)
+}
+
+// SequenceReset (35=4) moves the receiver's expected MsgSeqNum to NewSeqNo.
+// With GapFillFlag set it only skips over messages that will not be resent;
+// without it, it is a hard reset.
+type SequenceReset struct {
+	*RequestMessage
+	NewSeqNo    int
+	GapFillFlag bool
+}
+
+func NewSequenceReset(config *Config) *SequenceReset {
+	sr := &SequenceReset{
+		RequestMessage: NewRequestMessage("4", config),
+	}
+	sr.body = sr.GetBody
+	return sr
+}
+
+func (sr *SequenceReset) GetBody() string {
+	var fields []string
+	if sr.GapFillFlag {
+		fields = append(fields, "123=Y")
+	}
+	fields = append(fields, fmt.Sprintf("36=%d", sr.NewSeqNo))
+	return strings.Join(fields, sr.delimiter)
+}
+
+type OrderMsg struct {
+	*RequestMessage
+	ClOrdID       string
+	Symbol        string
+	Side          string
+	OrderQty      float64
+	OrdType       string
+	Price         float64
+	StopPx        float64
+	TimeInForce   string
+	PosMaintRptID string
+	Designation   string
+	Account       string // Account (1); defaults to Config.Account, omitted when empty
+	
+	// QtyStep is the symbol's quantity step used to format OrderQty; see
+	// Security.QtyStep. The client fills it from known security metadata.
+	QtyStep float64
+	
+	// TransactTime is stamped into tag 60; the current time is used when zero.
+	TransactTime time.Time
+	
+	// ExpireTime (126) is when a GTD order expires; it is required with
+	// TimeInForceGTD and not allowed otherwise.
+	ExpireTime time.Time
+	
+	// Bracket holds protective orders that PlaceBracketOrder submits once
+	// this order fills. It is not part of the serialized message.
+	Bracket *Bracket
+}
+
+// TimeInForce (59) values accepted by cServer.
+const (
+	TimeInForceDay = "0"
+	TimeInForceGTC = "1"
+	TimeInForceIOC = "3"
+	TimeInForceFOK = "4"
+	TimeInForceGTD = "6"
+)
+
+// Bracket describes the stop-loss and take-profit child orders attached to
+// an entry order. A zero price omits that child.
+type Bracket struct {
+	StopLoss   float64
+	TakeProfit float64
+}
+
+func NewOrderMsg(config *Config) *OrderMsg {
+	nos := &OrderMsg{
+		RequestMessage: NewRequestMessage("D", config),
+		Account:        config.Account,
 	}
-	trailer := nos.RequestMessage.getTrailer(headerAndBody)
-	return fmt.Sprintf("%s%s%s", headerAndBody, trailer, nos.delimiter)
+	nos.body = nos.GetBody
+	return nos
 }
 
 func (nos *OrderMsg) GetBody() string {
 	var fields []string
 	fields = append(fields, fmt.Sprintf("11=%s", nos.ClOrdID))
+	if nos.Account != "" {
+		fields = append(fields, fmt.Sprintf("1=%s", nos.Account))
+	}
 	fields = append(fields, fmt.Sprintf("55=%s", nos.Symbol))
 	fields = append(fields, fmt.Sprintf("54=%s", nos.Side))
-	fields = append(fields, fmt.Sprintf("60=%s", time.Now().UTC().Format("20060102-15:04:05")))
-	fields = append(fields, fmt.Sprintf("38=%.2f", nos.OrderQty))
+	transactTime := nos.TransactTime
+	if transactTime.IsZero() {
+		transactTime = nos.clock.now()
+	}
+	fields = append(fields, fmt.Sprintf("60=%s", nos.clock.format(transactTime)))
+	fields = append(fields, fmt.Sprintf("38=%s", formatQuantity(nos.OrderQty, nos.QtyStep)))
 	fields = append(fields, fmt.Sprintf("40=%s", nos.OrdType))
 	if nos.Price != 0 {
-		fields = append(fields, fmt.Sprintf("44=%.5f", nos.Price))
+		fields = append(fields, fmt.Sprintf("44=%s", formatPrice(nos.Price)))
+	}
+	if nos.StopPx != 0 && (nos.OrdType == "3" || nos.OrdType == "4") {
+		fields = append(fields, fmt.Sprintf("99=%s", formatPrice(nos.StopPx)))
+	}
+	if nos.TimeInForce != "" {
+		fields = append(fields, fmt.Sprintf("59=%s", nos.TimeInForce))
+	}
+	if !nos.ExpireTime.IsZero() {
+		fields = append(fields, fmt.Sprintf("126=%s", nos.ExpireTime.UTC().Format("20060102-15:04:05")))
+	}
+	if nos.PosMaintRptID != "" {
+		fields = append(fields, fmt.Sprintf("721=%s", nos.PosMaintRptID))
+	}
+	if nos.Designation != "" {
+		fields = append(fields, fmt.Sprintf("494=%s", nos.Designation))
 	}
 	return strings.Join(fields, nos.delimiter)
 }
 
+// Validate checks that the order is complete, that the prices required by
+// its OrdType are set and that GTD orders carry an expire time.
+func (nos *OrderMsg) Validate() error {
+	if err := validateID(nos.config, "ClOrdID", nos.ClOrdID); err != nil {
+		return err
+	}
+	return nos.validateTerms()
+}
+
+// validateTerms is Validate without the ClOrdID check, for orders whose
+// ClOrdID is still to be generated.
+func (nos *OrderMsg) validateTerms() error {
+	if nos.Symbol == "" {
+		return fmt.Errorf("order symbol is required")
+	}
+	if nos.Side != "1" && nos.Side != "2" {
+		return fmt.Errorf("order side must be 1 (buy) or 2 (sell), got %q", nos.Side)
+	}
+	if nos.OrderQty <= 0 {
+		return fmt.Errorf("order quantity must be positive, got %v", nos.OrderQty)
+	}
+	
+	switch nos.OrdType {
+	case "1":
+	case "2":
+		if nos.Price <= 0 {
+			return fmt.Errorf("limit order requires a price")
+		}
+	case "3":
+		if nos.StopPx <= 0 {
+			return fmt.Errorf("stop order requires a stop price")
+		}
+	case "4":
+		if nos.Price <= 0 || nos.StopPx <= 0 {
+			return fmt.Errorf("stop-limit order requires a price and a stop price")
+		}
+	default:
+		return fmt.Errorf("unsupported order type %q", nos.OrdType)
+	}
+	
+	if nos.TimeInForce == TimeInForceGTD && nos.ExpireTime.IsZero() {
+		return fmt.Errorf("GTD order requires an expire time")
+	}
+	if nos.TimeInForce != TimeInForceGTD && !nos.ExpireTime.IsZero() {
+		return fmt.Errorf("expire time is only allowed on GTD orders")
+	}
+	
+	return nil
+}
+
 type OrderCancelRequest struct {
 	*RequestMessage
 	OrigClOrdID string
 	OrderID     string
 	ClOrdID     string
+	Account     string // Account (1); defaults to Config.Account, omitted when empty
 }
 
 func NewOrderCancelRequest(config *Config) *OrderCancelRequest {
-	return &OrderCancelRequest{
+	ocr := &OrderCancelRequest{
 		RequestMessage: NewRequestMessage("F", config),
+		Account:        config.Account,
 	}
-}
-
-func (ocr *OrderCancelRequest) GetMessage(sequenceNumber int) string {
-	body := ocr.GetBody()
-	var headerAndBody string
-	if body != "" {
-		header := ocr.RequestMessage.getHeader(len(body), sequenceNumber)
-		headerAndBody = fmt.Sprintf("%s%s%s%s", header, ocr.delimiter, body, ocr.delimiter)
-	} else {
-		header := ocr.RequestMessage.getHeader(0, sequenceNumber)
-		headerAndBody = fmt.Sprintf("%s%s", header, ocr.delimiter)
-	}
-	trailer := ocr.RequestMessage.getTrailer(headerAndBody)
-	return fmt.Sprintf("%s%s%s", headerAndBody, trailer, ocr.delimiter)
+	ocr.body = ocr.GetBody
+	return ocr
 }
 
 func (ocr *OrderCancelRequest) GetBody() string {
@@ -339,38 +718,81 @@ func (ocr *OrderCancelRequest) GetBody() string {
 		fields = append(fields, fmt.Sprintf("37=%s", ocr.OrderID))
 	}
 	fields = append(fields, fmt.Sprintf("11=%s", ocr.ClOrdID))
+	if ocr.Account != "" {
+		fields = append(fields, fmt.Sprintf("1=%s", ocr.Account))
+	}
 	return strings.Join(fields, ocr.delimiter)
 }
 
+type OrderCancelReplaceRequest struct {
+	*RequestMessage
+	OrigClOrdID string
+	OrderID     string
+	ClOrdID     string
+	Symbol      string
+	Side        string
+	OrderQty    float64
+	OrdType     string
+	Price       float64
+	StopPx      float64
+	QtyStep     float64
+	Account     string // Account (1); defaults to Config.Account, omitted when empty
+}
+
+func NewOrderCancelReplaceRequest(config *Config) *OrderCancelReplaceRequest {
+	ocrr := &OrderCancelReplaceRequest{
+		RequestMessage: NewRequestMessage("G", config),
+		Account:        config.Account,
+	}
+	ocrr.body = ocrr.GetBody
+	return ocrr
+}
+
+func (ocrr *OrderCancelReplaceRequest) GetBody() string {
+	var fields []string
+	fields = append(fields, fmt.Sprintf("41=%s", ocrr.OrigClOrdID))
+	if ocrr.OrderID != "" {
+		fields = append(fields, fmt.Sprintf("37=%s", ocrr.OrderID))
+	}
+	fields = append(fields, fmt.Sprintf("11=%s", ocrr.ClOrdID))
+	if ocrr.Account != "" {
+		fields = append(fields, fmt.Sprintf("1=%s", ocrr.Account))
+	}
+	if ocrr.Symbol != "" {
+		fields = append(fields, fmt.Sprintf("55=%s", ocrr.Symbol))
+	}
+	if ocrr.Side != "" {
+		fields = append(fields, fmt.Sprintf("54=%s", ocrr.Side))
+	}
+	fields = append(fields, fmt.Sprintf("38=%s", formatQuantity(ocrr.OrderQty, ocrr.QtyStep)))
+	if ocrr.OrdType != "" {
+		fields = append(fields, fmt.Sprintf("40=%s", ocrr.OrdType))
+	}
+	if ocrr.Price != 0 {
+		fields = append(fields, fmt.Sprintf("44=%s", formatPrice(ocrr.Price)))
+	}
+	if ocrr.StopPx != 0 {
+		fields = append(fields, fmt.Sprintf("99=%s", formatPrice(ocrr.StopPx)))
+	}
+	return strings.Join(fields, ocrr.delimiter)
+}
+
 type MarketDataRequest struct {
 	*RequestMessage
 	MDReqID                 string
 	SubscriptionRequestType string
 	MarketDepth             int
-	NoMDEntryTypes          int
-	MDEntryType             string
-	NoRelatedSym            int
-	Symbol                  string
+	MDEntryTypes            []string // 269 values in order, counted by 267; empty requests bid and offer
+	Symbols                 []string // 55 values, counted by 146
+	Symbol                  string   // Shortcut for a single symbol, sent after Symbols
 }
 
 func NewMarketDataRequest(config *Config) *MarketDataRequest {
-	return &MarketDataRequest{
+	mdr := &MarketDataRequest{
 		RequestMessage: NewRequestMessage("V", config),
 	}
-}
-
-func (mdr *MarketDataRequest) GetMessage(sequenceNumber int) string {
-	body := mdr.GetBody()
-	var headerAndBody string
-	if body != "" {
-		header := mdr.RequestMessage.getHeader(len(body), sequenceNumber)
-		headerAndBody = fmt.Sprintf("%s%s%s%s", header, mdr.delimiter, body, mdr.delimiter)
-	} else {
-		header := mdr.RequestMessage.getHeader(0, sequenceNumber)
-		headerAndBody = fmt.Sprintf("%s%s", header, mdr.delimiter)
-	}
-	trailer := mdr.RequestMessage.getTrailer(headerAndBody)
-	return fmt.Sprintf("%s%s%s", headerAndBody, trailer, mdr.delimiter)
+	mdr.body = mdr.GetBody
+	return mdr
 }
 
 func (mdr *MarketDataRequest) GetBody() string {
@@ -378,13 +800,36 @@ func (mdr *MarketDataRequest) GetBody() string {
 	fields = append(fields, fmt.Sprintf("262=%s", mdr.MDReqID))
 	fields = append(fields, fmt.Sprintf("263=%s", mdr.SubscriptionRequestType))
 	fields = append(fields, fmt.Sprintf("264=%d", mdr.MarketDepth))
-	fields = append(fields, fmt.Sprintf("267=%d", mdr.NoMDEntryTypes))
-	fields = append(fields, fmt.Sprintf("269=%s", mdr.MDEntryType))
-	fields = append(fields, fmt.Sprintf("146=%d", mdr.NoRelatedSym))
-	fields = append(fields, fmt.Sprintf("55=%s", mdr.Symbol))
+	entryTypes := mdr.MDEntryTypes
+	if len(entryTypes) == 0 {
+		entryTypes = []string{string(MDEntryBid), string(MDEntryOffer)}
+	}
+	fields = append(fields, fmt.Sprintf("267=%d", len(entryTypes)))
+	for _, entryType := range entryTypes {
+		fields = append(fields, fmt.Sprintf("269=%s", entryType))
+	}
+	symbols := mdr.symbols()
+	fields = append(fields, fmt.Sprintf("146=%d", len(symbols)))
+	for _, symbol := range symbols {
+		fields = append(fields, fmt.Sprintf("55=%s", symbol))
+	}
 	return strings.Join(fields, mdr.delimiter)
 }
 
+// symbols returns Symbols followed by Symbol unless it is already listed.
+func (mdr *MarketDataRequest) symbols() []string {
+	symbols := append([]string{}, mdr.Symbols...)
+	if mdr.Symbol == "" {
+		return symbols
+	}
+	for _, symbol := range symbols {
+		if symbol == mdr.Symbol {
+			return symbols
+		}
+	}
+	return append(symbols, mdr.Symbol)
+}
+
 type SecurityListRequest struct {
 	*RequestMessage
 	SecurityReqID           string
@@ -393,23 +838,11 @@ type SecurityListRequest struct {
 }
 
 func NewSecurityListRequest(config *Config) *SecurityListRequest {
-	return &SecurityListRequest{
+	slr := &SecurityListRequest{
 		RequestMessage: NewRequestMessage("x", config),
 	}
-}
-
-func (slr *SecurityListRequest) GetMessage(sequenceNumber int) string {
-	body := slr.GetBody()
-	var headerAndBody string
-	if body != "" {
-		header := slr.RequestMessage.getHeader(len(body), sequenceNumber)
-		headerAndBody = fmt.Sprintf("%s%s%s%s", header, slr.delimiter, body, slr.delimiter)
-	} else {
-		header := slr.RequestMessage.getHeader(0, sequenceNumber)
-		headerAndBody = fmt.Sprintf("%s%s", header, slr.delimiter)
-	}
-	trailer := slr.RequestMessage.getTrailer(headerAndBody)
-	return fmt.Sprintf("%s%s%s", headerAndBody, trailer, slr.delimiter)
+	slr.body = slr.GetBody
+	return slr
 }
 
 func (slr *SecurityListRequest) GetBody() string {
@@ -426,26 +859,15 @@ type RequestForPositions struct {
 	*RequestMessage
 	PosReqID      string
 	PosMaintRptID string
+	Designation   string
 }
 
 func NewRequestForPositions(config *Config) *RequestForPositions {
-	return &RequestForPositions{
+	rfp := &RequestForPositions{
 		RequestMessage: NewRequestMessage("AN", config),
 	}
-}
-
-func (rfp *RequestForPositions) GetMessage(sequenceNumber int) string {
-	body := rfp.GetBody()
-	var headerAndBody string
-	if body != "" {
-		header := rfp.RequestMessage.getHeader(len(body), sequenceNumber)
-		headerAndBody = fmt.Sprintf("%s%s%s%s", header, rfp.delimiter, body, rfp.delimiter)
-	} else {
-		header := rfp.RequestMessage.getHeader(0, sequenceNumber)
-		headerAndBody = fmt.Sprintf("%s%s", header, rfp.delimiter)
-	}
-	trailer := rfp.RequestMessage.getTrailer(headerAndBody)
-	return fmt.Sprintf("%s%s%s", headerAndBody, trailer, rfp.delimiter)
+	rfp.body = rfp.GetBody
+	return rfp
 }
 
 func (rfp *RequestForPositions) GetBody() string {
@@ -454,5 +876,145 @@ func (rfp *RequestForPositions) GetBody() string {
 	if rfp.PosMaintRptID != "" {
 		fields = append(fields, fmt.Sprintf("721=%s", rfp.PosMaintRptID))
 	}
+	if rfp.Designation != "" {
+		fields = append(fields, fmt.Sprintf("494=%s", rfp.Designation))
+	}
 	return strings.Join(fields, rfp.delimiter)
 }
+
+type OrderStatusRequest struct {
+	*RequestMessage
+	ClOrdID string
+	OrderID string
+	Symbol  string
+	Side    string
+}
+
+func NewOrderStatusRequest(config *Config) *OrderStatusRequest {
+	osr := &OrderStatusRequest{
+		RequestMessage: NewRequestMessage("H", config),
+	}
+	osr.body = osr.GetBody
+	return osr
+}
+
+func (osr *OrderStatusRequest) GetBody() string {
+	var fields []string
+	fields = append(fields, fmt.Sprintf("11=%s", osr.ClOrdID))
+	if osr.OrderID != "" {
+		fields = append(fields, fmt.Sprintf("37=%s", osr.OrderID))
+	}
+	if osr.Symbol != "" {
+		fields = append(fields, fmt.Sprintf("55=%s", osr.Symbol))
+	}
+	if osr.Side != "" {
+		fields = append(fields, fmt.Sprintf("54=%s", osr.Side))
+	}
+	return strings.Join(fields, osr.delimiter)
+}
+
+// OrderMassStatusRequest (35=AF) asks for the status of many orders at once;
+// MassStatusReqType defaults to 7, all orders. The server answers with one
+// execution report (35=8) per order, each echoing MassStatusReqID (584).
+type OrderMassStatusRequest struct {
+	*RequestMessage
+	MassStatusReqID   string
+	MassStatusReqType string
+}
+
+func NewOrderMassStatusRequest(config *Config) *OrderMassStatusRequest {
+	omsr := &OrderMassStatusRequest{
+		RequestMessage:    NewRequestMessage("AF", config),
+		MassStatusReqType: "7",
+	}
+	omsr.body = omsr.GetBody
+	return omsr
+}
+
+func (omsr *OrderMassStatusRequest) GetBody() string {
+	var fields []string
+	fields = append(fields, fmt.Sprintf("584=%s", omsr.MassStatusReqID))
+	fields = append(fields, fmt.Sprintf("585=%s", omsr.MassStatusReqType))
+	return strings.Join(fields, omsr.delimiter)
+}
+
+// TradeCaptureReportRequest (35=AD) asks for trade capture reports (35=AR),
+// such as the fills between StartTime and EndTime. A zero time leaves that
+// end of the range open.
+type TradeCaptureReportRequest struct {
+	*RequestMessage
+	TradeRequestID   string
+	TradeRequestType string
+	StartTime        time.Time
+	EndTime          time.Time
+}
+
+func NewTradeCaptureReportRequest(config *Config) *TradeCaptureReportRequest {
+	tcrr := &TradeCaptureReportRequest{
+		RequestMessage:   NewRequestMessage("AD", config),
+		TradeRequestType: "0", // All trades
+	}
+	tcrr.body = tcrr.GetBody
+	return tcrr
+}
+
+func (tcrr *TradeCaptureReportRequest) GetBody() string {
+	var fields []string
+	fields = append(fields, fmt.Sprintf("568=%s", tcrr.TradeRequestID))
+	fields = append(fields, fmt.Sprintf("569=%s", tcrr.TradeRequestType))
+	
+	var dates []string
+	for _, date := range []time.Time{tcrr.StartTime, tcrr.EndTime} {
+		if !date.IsZero() {
+			dates = append(dates, fmt.Sprintf("60=%s", tcrr.clock.format(date)))
+		}
+	}
+	if len(dates) > 0 {
+		fields = append(fields, fmt.Sprintf("580=%d", len(dates)))
+		fields = append(fields, dates...)
+	}
+	return strings.Join(fields, tcrr.delimiter)
+}
+
+// GenericMessage is a request of any MsgType built field by field, for
+// message types the library has no dedicated type for. The standard header
+// and trailer are generated as for every other request.
+type GenericMessage struct {
+	*RequestMessage
+	fields []Field
+}
+
+// NewGenericMessage returns an empty request of msgType.
+func NewGenericMessage(msgType string, config *Config) *GenericMessage {
+	gm := &GenericMessage{
+		RequestMessage: NewRequestMessage(msgType, config),
+	}
+	gm.body = gm.GetBody
+	return gm
+}
+
+// SetField sets tag to value. A new tag is appended after the fields set so
+// far; setting an existing tag replaces its value in place.
+func (gm *GenericMessage) SetField(tag int, value string) *GenericMessage {
+	for i := range gm.fields {
+		if gm.fields[i].Tag == tag {
+			gm.fields[i].Value = value
+			return gm
+		}
+	}
+	gm.fields = append(gm.fields, Field{Tag: tag, Value: value})
+	return gm
+}
+
+// Fields returns the body fields in the order they will be sent.
+func (gm *GenericMessage) Fields() []Field {
+	return append([]Field{}, gm.fields...)
+}
+
+func (gm *GenericMessage) GetBody() string {
+	var fields []string
+	for _, field := range gm.fields {
+		fields = append(fields, fmt.Sprintf("%d=%s", field.Tag, field.Value))
+	}
+	return strings.Join(fields, gm.delimiter)
+}
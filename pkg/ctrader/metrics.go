@@ -0,0 +1,107 @@
+package ctrader
+
+import (
+	"sync"
+	"time"
+)
+
+// Metrics is a snapshot of a client's counters for monitoring, e.g. to be
+// exported to Prometheus by the application. Counters cover the lifetime of
+// the client, across reconnects.
+type Metrics struct {
+	Sent             map[string]uint64 // messages sent by MsgType
+	Received         map[string]uint64 // messages received by MsgType
+	Reconnects       uint64
+	OutboundSeqNum   int
+	InboundSeqNum    int
+	LastHeartbeat    time.Time // when the server's last Heartbeat (35=0) arrived
+	ChecksumFailures uint64    // only counted with checksum diagnostics or validation
+	Rejects          uint64    // session (35=3) and business (35=j) rejects
+	DroppedMessages  uint64    // messages dropped because their channel was full
+}
+
+// metrics holds the counters behind Client.Metrics.
+type metrics struct {
+	mu               sync.Mutex
+	sent             map[string]uint64
+	received         map[string]uint64
+	reconnects       uint64
+	lastHeartbeat    time.Time
+	checksumFailures uint64
+	rejects          uint64
+	dropped          uint64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		sent:     make(map[string]uint64),
+		received: make(map[string]uint64),
+	}
+}
+
+func (m *metrics) countSent(msgType string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent[msgType]++
+}
+
+func (m *metrics) countReceived(msg *ResponseMessage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	msgType := msg.GetMessageType()
+	m.received[msgType]++
+	switch msgType {
+	case "0":
+		m.lastHeartbeat = time.Now()
+	case "3", "j":
+		m.rejects++
+	}
+}
+
+func (m *metrics) countReconnect() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reconnects++
+}
+
+func (m *metrics) countDropped() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dropped++
+}
+
+func (m *metrics) countChecksumFailure() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checksumFailures++
+}
+
+// Metrics returns a snapshot of the client's counters.
+func (c *Client) Metrics() Metrics {
+	c.mu.RLock()
+	snapshot := Metrics{
+		OutboundSeqNum: c.messageSequenceNum,
+		InboundSeqNum:  c.inboundSeqNum,
+	}
+	c.mu.RUnlock()
+	
+	m := c.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	snapshot.Sent = make(map[string]uint64, len(m.sent))
+	for msgType, n := range m.sent {
+		snapshot.Sent[msgType] = n
+	}
+	snapshot.Received = make(map[string]uint64, len(m.received))
+	for msgType, n := range m.received {
+		snapshot.Received[msgType] = n
+	}
+	snapshot.Reconnects = m.reconnects
+	snapshot.LastHeartbeat = m.lastHeartbeat
+	snapshot.ChecksumFailures = m.checksumFailures
+	snapshot.Rejects = m.rejects
+	snapshot.DroppedMessages = m.dropped
+	return snapshot
+}
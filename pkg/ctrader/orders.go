@@ -0,0 +1,287 @@
+package ctrader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// BracketOrder is the result of PlaceBracketOrder: the fill of the entry and
+// the child orders that were submitted against the resulting position.
+type BracketOrder struct {
+	Entry      *ExecutionReport
+	StopLoss   *OrderMsg
+	TakeProfit *OrderMsg
+}
+
+// PlaceOrder sends order and waits for its first execution report. A rejected
+// order returns an error wrapping ErrOrderRejected. A ClOrdID is generated if
+// the order does not have one. Orders with a Bracket must be placed with
+// PlaceBracketOrder instead.
+func (c *Client) PlaceOrder(ctx context.Context, order *OrderMsg) (*ExecutionReport, error) {
+	if err := checkNoBracket(order); err != nil {
+		return nil, err
+	}
+	if order.ClOrdID == "" {
+		order.ClOrdID = c.nextRequestID("ORD")
+	}
+	
+	pending, err := c.sendPending(ctx, order, matchExecutionReport(order.ClOrdID))
+	if err != nil {
+		return nil, err
+	}
+	return collectFirstReport(ctx, pending)
+}
+
+// collectFirstReport waits for the first execution report of a pending order.
+func collectFirstReport(ctx context.Context, pending *pendingRequest) (*ExecutionReport, error) {
+	var report *ExecutionReport
+	err := pending.collect(ctx, func(msg *ResponseMessage) (bool, error) {
+		report = newExecutionReport(msg)
+		if report.OrdStatus == "8" {
+			return true, fmt.Errorf("%w: %s", ErrOrderRejected, report.Text)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	return report, nil
+}
+
+// OrderFuture is the pending result of PlaceOrderAsync.
+type OrderFuture struct {
+	clOrdID string
+	done    chan struct{}
+	report  *ExecutionReport
+	err     error
+}
+
+// ClOrdID returns the ClOrdID the order was sent with.
+func (f *OrderFuture) ClOrdID() string {
+	return f.clOrdID
+}
+
+// Done returns a channel that is closed once the result is available.
+func (f *OrderFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// Result waits for and returns the order's first execution report, with the
+// same errors as PlaceOrder. If the session ends first the error wraps
+// ErrConnectionLost.
+func (f *OrderFuture) Result() (*ExecutionReport, error) {
+	<-f.done
+	return f.report, f.err
+}
+
+// PlaceOrderAsync sends order and returns without waiting for its execution
+// report, so several orders can be placed and awaited together. Errors
+// sending the order are returned directly, as for PlaceOrder.
+func (c *Client) PlaceOrderAsync(order *OrderMsg) (*OrderFuture, error) {
+	if err := checkNoBracket(order); err != nil {
+		return nil, err
+	}
+	if order.ClOrdID == "" {
+		order.ClOrdID = c.nextRequestID("ORD")
+	}
+	
+	sessionDone := c.Done()
+	pending, err := c.sendPending(context.Background(), order, matchExecutionReport(order.ClOrdID))
+	if err != nil {
+		return nil, err
+	}
+	
+	future := &OrderFuture{
+		clOrdID: order.ClOrdID,
+		done:    make(chan struct{}),
+	}
+	
+	ctx, cancel := context.WithCancelCause(context.Background())
+	go func() {
+		select {
+		case <-sessionDone:
+			cancel(fmt.Errorf("%w: order %s was not acknowledged", ErrConnectionLost, order.ClOrdID))
+		case <-future.done:
+		}
+	}()
+	
+	go func() {
+		future.report, future.err = collectFirstReport(ctx, pending)
+		cancel(nil)
+		close(future.done)
+	}()
+	
+	return future, nil
+}
+
+// checkNoBracket refuses an order whose Bracket would otherwise be dropped,
+// leaving the position it opens unprotected.
+func checkNoBracket(message Message) error {
+	if order, ok := message.(*OrderMsg); ok && order.Bracket != nil {
+		return fmt.Errorf("order %s has a bracket; place it with PlaceBracketOrder so the stop-loss and take-profit are sent", order.ClOrdID)
+	}
+	return nil
+}
+
+// PlaceBracketOrder sends the entry order and, only after it is fully filled,
+// submits the stop-loss and take-profit described by order.Bracket against the
+// filled position. If the entry is rejected or canceled no child orders are
+// sent.
+func (c *Client) PlaceBracketOrder(ctx context.Context, order *OrderMsg) (*BracketOrder, error) {
+	if order.Bracket == nil {
+		return nil, fmt.Errorf("order has no bracket")
+	}
+	if order.ClOrdID == "" {
+		order.ClOrdID = c.nextRequestID("ORD")
+	}
+	
+	var entry *ExecutionReport
+	err := c.sendAndCollect(ctx, order, matchExecutionReport(order.ClOrdID), func(msg *ResponseMessage) (bool, error) {
+		report := newExecutionReport(msg)
+		switch {
+		case report.OrdStatus == "2": // Filled
+			entry = report
+			return true, nil
+		case report.IsTerminal():
+			return true, fmt.Errorf("%w: entry %s ended with status %s: %s", ErrOrderRejected, order.ClOrdID, report.OrdStatus, report.Text)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	bracket := &BracketOrder{Entry: entry}
+	
+	if order.Bracket.StopLoss != 0 {
+		bracket.StopLoss = c.newChildOrder(order, entry, "SL")
+		bracket.StopLoss.OrdType = "3"
+		bracket.StopLoss.StopPx = order.Bracket.StopLoss
+		if err := c.Send(bracket.StopLoss); err != nil {
+			return bracket, fmt.Errorf("failed to send stop loss: %w", err)
+		}
+	}
+	
+	if order.Bracket.TakeProfit != 0 {
+		bracket.TakeProfit = c.newChildOrder(order, entry, "TP")
+		bracket.TakeProfit.OrdType = "2"
+		bracket.TakeProfit.Price = order.Bracket.TakeProfit
+		if err := c.Send(bracket.TakeProfit); err != nil {
+			return bracket, fmt.Errorf("failed to send take profit: %w", err)
+		}
+	}
+	
+	return bracket, nil
+}
+
+// newChildOrder returns an order closing the position opened by entry.
+func (c *Client) newChildOrder(order *OrderMsg, entry *ExecutionReport, suffix string) *OrderMsg {
+	child := NewOrderMsg(order.config)
+	child.ClOrdID = order.ClOrdID + "_" + suffix
+	child.Symbol = order.Symbol
+	child.Side = oppositeSide(order.Side)
+	child.OrderQty = entry.CumQty
+	child.PosMaintRptID = entry.PosMaintRptID
+	return child
+}
+
+// QueryOrder sends an OrderStatusRequest and returns the execution report
+// describing the order's current state. It is the way to find out whether an
+// order is still working after a reconnect. An unknown order returns an error
+// wrapping ErrOrderNotFound.
+func (c *Client) QueryOrder(ctx context.Context, clOrdID, orderID string) (*ExecutionReport, error) {
+	request := NewOrderStatusRequest(c.config)
+	request.ClOrdID = clOrdID
+	request.OrderID = orderID
+	
+	var report *ExecutionReport
+	err := c.sendAndCollect(ctx, request, func(msg *ResponseMessage) bool {
+		if msg.GetMessageType() != "8" {
+			return false
+		}
+		return msg.first(11) == clOrdID || (orderID != "" && msg.first(37) == orderID)
+	}, func(msg *ResponseMessage) (bool, error) {
+		report = newExecutionReport(msg)
+		if report.ExecType == "8" {
+			return true, fmt.Errorf("%w: %s: %s", ErrOrderNotFound, clOrdID, report.Text)
+		}
+		return true, nil
+	})
+	if errors.Is(err, ErrRequestRejected) {
+		return nil, fmt.Errorf("%w: %s: %w", ErrOrderNotFound, clOrdID, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	
+	return report, nil
+}
+
+// ReduceOrder lowers the quantity of a working order with a cancel/replace
+// and waits for the server to confirm the replacement. newQty must be below
+// the order's current quantity and above what has already been filled.
+func (c *Client) ReduceOrder(ctx context.Context, origClOrdID string, newQty float64) (*ExecutionReport, error) {
+	current, err := c.QueryOrder(ctx, origClOrdID, "")
+	if err != nil {
+		return nil, err
+	}
+	
+	if newQty >= current.OrderQty {
+		return nil, fmt.Errorf("new quantity %v must be below the current quantity %v", newQty, current.OrderQty)
+	}
+	if newQty <= current.CumQty {
+		return nil, fmt.Errorf("new quantity %v must be above the filled quantity %v", newQty, current.CumQty)
+	}
+	
+	replace := NewOrderCancelReplaceRequest(c.config)
+	replace.OrigClOrdID = origClOrdID
+	replace.OrderID = current.OrderID
+	replace.ClOrdID = c.nextRequestID("RPL")
+	replace.Symbol = current.Symbol
+	replace.Side = current.Side
+	replace.OrderQty = newQty
+	replace.OrdType = current.OrdType
+	replace.Price = current.Price
+	replace.StopPx = current.StopPx
+	
+	var report *ExecutionReport
+	err = c.sendAndCollect(ctx, replace, func(msg *ResponseMessage) bool {
+		switch msg.GetMessageType() {
+		case "8", "9":
+			return msg.first(11) == replace.ClOrdID
+		}
+		return false
+	}, func(msg *ResponseMessage) (bool, error) {
+		if msg.GetMessageType() == "9" {
+			return true, fmt.Errorf("%w: replace of %s: %s", ErrRequestRejected, origClOrdID, msg.first(58))
+		}
+		report = newExecutionReport(msg)
+		switch report.ExecType {
+		case "5": // Replaced
+			return true, nil
+		case "8":
+			return true, fmt.Errorf("%w: replace of %s: %s", ErrRequestRejected, origClOrdID, report.Text)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	return report, nil
+}
+
+func matchExecutionReport(clOrdID string) func(*ResponseMessage) bool {
+	return func(msg *ResponseMessage) bool {
+		return msg.GetMessageType() == "8" && msg.first(11) == clOrdID
+	}
+}
+
+func oppositeSide(side string) string {
+	if side == "1" {
+		return "2"
+	}
+	return "1"
+}
@@ -0,0 +1,51 @@
+package ctrader
+
+import "math"
+
+// Position is an open position in one symbol.
+type Position struct {
+	Symbol     string
+	Side       string  // "1" long (bought), "2" short (sold)
+	Quantity   float64 // in lots of contractSize units
+	EntryPrice float64
+}
+
+// UnrealizedPnL returns the profit or loss of closing the position at the
+// current prices, in the quote currency of the symbol: a long position is
+// closed at currentBid and a short one at currentAsk. contractSize is the
+// number of units per lot (100000 for a standard forex lot, 1 when Quantity
+// is already in units), so JPY pairs and non-forex instruments come out right
+// given their own contract size. Prices are rounded to digits, the symbol's
+// price precision, before use; a negative digits leaves them as they are.
+func (p Position) UnrealizedPnL(currentBid, currentAsk float64, contractSize float64, digits int) float64 {
+	entry := roundPrice(p.EntryPrice, digits)
+	units := p.Quantity * contractSize
+	
+	if p.Side == "2" {
+		return (entry - roundPrice(currentAsk, digits)) * units
+	}
+	return (roundPrice(currentBid, digits) - entry) * units
+}
+
+// SecurityPnL is UnrealizedPnL with the contract size and digits of
+// security, as parsed from the security list. Without a ContractSize,
+// Quantity is taken to be in units; without Digits prices are not rounded.
+func (p Position) SecurityPnL(currentBid, currentAsk float64, security Security) float64 {
+	contractSize := security.ContractSize
+	if contractSize <= 0 {
+		contractSize = 1
+	}
+	digits := security.Digits
+	if digits == 0 {
+		digits = -1
+	}
+	return p.UnrealizedPnL(currentBid, currentAsk, contractSize, digits)
+}
+
+func roundPrice(price float64, digits int) float64 {
+	if digits < 0 {
+		return price
+	}
+	scale := math.Pow(10, float64(digits))
+	return math.Round(price*scale) / scale
+}
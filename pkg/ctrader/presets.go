@@ -0,0 +1,61 @@
+package ctrader
+
+// Ports of the cTrader FIX sessions. Market data is served on the QUOTE
+// session and trading on the TRADE session; each needs its own client.
+const (
+	QuotePort = 5211
+	TradePort = 5212
+)
+
+// DemoHost is the FIX host of cTrader demo accounts.
+const DemoHost = "demo-uk-eqx-01.p.c-trader.com"
+
+// NewDemoConfig returns the Config of a demo account: FIX.4.4, TargetCompID
+// "cServer" and a 30 second heartbeat. The sub-IDs are left to
+// NewQuoteClient and NewTradeClient, so one Config serves both sessions.
+func NewDemoConfig(senderCompID, username, password string) *Config {
+	return newAccountConfig(senderCompID, username, password)
+}
+
+// NewLiveConfig returns the Config of a live account. cServer expects the
+// same session settings as for demo accounts; only the credentials and the
+// host differ.
+func NewLiveConfig(senderCompID, username, password string) *Config {
+	return newAccountConfig(senderCompID, username, password)
+}
+
+func newAccountConfig(senderCompID, username, password string) *Config {
+	return &Config{
+		BeginString:  "FIX.4.4",
+		SenderCompID: senderCompID,
+		TargetCompID: "cServer",
+		Username:     username,
+		Password:     password,
+		HeartBeat:    30,
+	}
+}
+
+// NewQuoteClient returns a client for the QUOTE session of host: port 5211
+// over TLS, with SenderSubID and TargetSubID "QUOTE". The client works on a
+// copy of config, returned by Config, so config itself is not changed. opts
+// are applied after WithSSL(true).
+func NewQuoteClient(host string, config *Config, opts ...ClientOption) *Client {
+	return newSessionClient(host, QuotePort, "QUOTE", config, opts)
+}
+
+// NewTradeClient is NewQuoteClient for the TRADE session: port 5212, with
+// SenderSubID and TargetSubID "TRADE".
+func NewTradeClient(host string, config *Config, opts ...ClientOption) *Client {
+	return newSessionClient(host, TradePort, "TRADE", config, opts)
+}
+
+func newSessionClient(host string, port int, subID string, config *Config, opts []ClientOption) *Client {
+	var sessionConfig Config
+	if config != nil {
+		sessionConfig = *config
+	}
+	sessionConfig.SenderSubID = subID
+	sessionConfig.TargetSubID = subID
+	
+	return NewClient(host, port, &sessionConfig, append([]ClientOption{WithSSL(true)}, opts...)...)
+}
@@ -49,6 +49,66 @@ func (p *Protocol) ValidateMessage(message string) error {
 	return nil
 }
 
+// headerOrder is the order of the FIX 4.4 StandardHeader fields.
+var headerOrder = []int{
+	8, 9, 35, 49, 56, 115, 128, 90, 91, 34, 50, 142, 57, 143, 116, 144,
+	129, 145, 43, 97, 52, 122, 212, 213, 347, 369, 627,
+}
+
+// ValidateOutbound checks that message is framed and ordered as FIX 4.4
+// requires before it is sent: BeginString (8), BodyLength (9) and MsgType (35)
+// first, the other header fields in StandardHeader order and ahead of the
+// body, and CheckSum (10) last.
+func (p *Protocol) ValidateOutbound(message string) error {
+	rank := make(map[int]int, len(headerOrder))
+	for i, tag := range headerOrder {
+		rank[tag] = i
+	}
+	
+	var tags []int
+	for _, part := range strings.Split(message, p.delimiter) {
+		if part == "" {
+			continue
+		}
+		eqIndex := strings.Index(part, "=")
+		if eqIndex == -1 {
+			return fmt.Errorf("malformed field %q", part)
+		}
+		tag, err := strconv.Atoi(part[:eqIndex])
+		if err != nil {
+			return fmt.Errorf("malformed field %q", part)
+		}
+		tags = append(tags, tag)
+	}
+	
+	for i, tag := range []int{8, 9, 35} {
+		if i >= len(tags) || tags[i] != tag {
+			return fmt.Errorf("field %d (%s) must be field %d of the message", tag, p.GetFieldNames()[tag], i+1)
+		}
+	}
+	if tags[len(tags)-1] != 10 {
+		return fmt.Errorf("CheckSum field (10) must be the last field")
+	}
+	
+	last := rank[35]
+	inBody := false
+	for _, tag := range tags[3 : len(tags)-1] {
+		r, isHeader := rank[tag]
+		switch {
+		case !isHeader:
+			inBody = true
+		case inBody:
+			return fmt.Errorf("header field %d (%s) follows the message body", tag, p.GetFieldNames()[tag])
+		case r <= last:
+			return fmt.Errorf("header field %d (%s) is out of order", tag, p.GetFieldNames()[tag])
+		default:
+			last = r
+		}
+	}
+	
+	return nil
+}
+
 func (p *Protocol) parseFields(message string) map[int][]string {
 	fields := make(map[int][]string)
 	
@@ -112,52 +172,97 @@ func (p *Protocol) calculateChecksum(message string) int {
 
 func (p *Protocol) GetFieldNames() map[int]string {
 	return map[int]string{
-		8:   "BeginString",
-		9:   "BodyLength",
-		35:  "MsgType",
-		49:  "SenderCompID",
-		50:  "SenderSubID",
-		56:  "TargetCompID",
-		57:  "TargetSubID",
-		34:  "MsgSeqNum",
-		52:  "SendingTime",
-		10:  "CheckSum",
-		98:  "EncryptMethod",
-		108: "HeartBtInt",
-		141: "ResetSeqNumFlag",
-		553: "Username",
-		554: "Password",
-		112: "TestReqID",
-		7:   "BeginSeqNo",
-		16:  "EndSeqNo",
-		123: "GapFillFlag",
-		36:  "NewSeqNo",
-		262: "MDReqID",
-		263: "SubscriptionRequestType",
-		264: "MarketDepth",
-		265: "MDUpdateType",
-		267: "NoMDEntryTypes",
-		269: "MDEntryType",
-		146: "NoRelatedSym",
-		55:  "Symbol",
-		11:  "ClOrdID",
-		54:  "Side",
-		60:  "TransactTime",
-		38:  "OrderQty",
-		40:  "OrdType",
-		44:  "Price",
-		99:  "StopPx",
-		126: "ExpireTime",
-		721: "PosMaintRptID",
-		494: "Designation",
-		584: "MassStatusReqID",
-		585: "MassStatusReqType",
-		225: "IssueDate",
-		710: "PosReqID",
-		37:  "OrderID",
-		41:  "OrigClOrdID",
-		320: "SecurityReqID",
-		559: "SecurityListRequestType",
+		1:    "Account",
+		8:    "BeginString",
+		9:    "BodyLength",
+		35:   "MsgType",
+		49:   "SenderCompID",
+		50:   "SenderSubID",
+		56:   "TargetCompID",
+		57:   "TargetSubID",
+		34:   "MsgSeqNum",
+		52:   "SendingTime",
+		10:   "CheckSum",
+		98:   "EncryptMethod",
+		108:  "HeartBtInt",
+		141:  "ResetSeqNumFlag",
+		553:  "Username",
+		554:  "Password",
+		112:  "TestReqID",
+		7:    "BeginSeqNo",
+		16:   "EndSeqNo",
+		123:  "GapFillFlag",
+		36:   "NewSeqNo",
+		262:  "MDReqID",
+		263:  "SubscriptionRequestType",
+		264:  "MarketDepth",
+		265:  "MDUpdateType",
+		267:  "NoMDEntryTypes",
+		269:  "MDEntryType",
+		146:  "NoRelatedSym",
+		55:   "Symbol",
+		11:   "ClOrdID",
+		54:   "Side",
+		60:   "TransactTime",
+		38:   "OrderQty",
+		40:   "OrdType",
+		44:   "Price",
+		99:   "StopPx",
+		83:   "RptSeq",
+		126:  "ExpireTime",
+		721:  "PosMaintRptID",
+		494:  "Designation",
+		584:  "MassStatusReqID",
+		585:  "MassStatusReqType",
+		225:  "IssueDate",
+		710:  "PosReqID",
+		37:   "OrderID",
+		41:   "OrigClOrdID",
+		320:  "SecurityReqID",
+		559:  "SecurityListRequestType",
+		383:  "MaxMessageSize",
+		1685: "ThrottleInst",
+		1686: "ThrottleNoMsgs",
+		1687: "ThrottleTimeInterval",
+		1688: "ThrottleTimeUnit",
+		268:  "NoMDEntries",
+		270:  "MDEntryPx",
+		271:  "MDEntrySize",
+		279:  "MDUpdateAction",
+		281:  "MDReqRejReason",
+		278:  "MDEntryID",
+		335:  "TradSesReqID",
+		336:  "TradingSessionID",
+		340:  "TradSesStatus",
+		341:  "TradSesStartTime",
+		342:  "TradSesOpenTime",
+		344:  "TradSesCloseTime",
+		393:  "TotNoRelatedSym",
+		560:  "SecurityRequestResult",
+		893:  "LastFragment",
+		1007: "SymbolName",
+		1008: "SymbolDigits",
+		969:  "MinPriceIncrement",
+		561:  "RoundLot",
+		562:  "MinTradeVol",
+		231:  "ContractMultiplier",
+		911:  "TotNumReports",
+		912:  "LastRptRequested",
+		568:  "TradeRequestID",
+		569:  "TradeRequestType",
+		580:  "NoDates",
+		45:   "RefSeqNum",
+		58:   "Text",
+		102:  "CxlRejReason",
+		103:  "OrdRejReason",
+		371:  "RefTagID",
+		373:  "SessionRejectReason",
+		372:  "RefMsgType",
+		379:  "BusinessRejectRefID",
+		380:  "BusinessRejectReason",
+		43:   "PossDupFlag",
+		97:   "PossResend",
+		122:  "OrigSendingTime",
 	}
 }
 
@@ -169,7 +274,7 @@ func (p *Protocol) GetMessageTypeName() map[string]string {
 		"3":  "Reject",
 		"4":  "SequenceReset",
 		"5":  "Logout",
-		"8":  "BusinessMessageReject",
+		"8":  "ExecutionReport",
 		"A":  "Logon",
 		"D":  "NewOrderSingle",
 		"F":  "OrderCancelRequest",
@@ -182,45 +287,89 @@ func (p *Protocol) GetMessageTypeName() map[string]string {
 		"W":  "MarketDataSnapshotFullRefresh",
 		"X":  "MarketDataIncrementalRefresh",
 		"Y":  "MarketDataRequestReject",
+		"AD": "TradeCaptureReportRequest",
 		"AF": "OrderMassStatusRequest",
 		"AN": "RequestForPositions",
-		"AO": "PositionReport",
-		"AP": "TradeCaptureReportRequest",
+		"AO": "RequestForPositionsAck",
+		"AP": "PositionReport",
+		"AQ": "TradeCaptureReportRequestAck",
 		"AR": "TradeCaptureReport",
+		"g":  "TradingSessionStatusRequest",
+		"h":  "TradingSessionStatus",
+		"j":  "BusinessMessageReject",
 		"x":  "SecurityListRequest",
 		"y":  "SecurityList",
 		"z":  "SecurityListResponse",
 	}
 }
 
+// repeatingGroups maps the count tag of each repeating group FormatMessage
+// nests to the tags that may appear inside an instance of the group.
+var repeatingGroups = map[int][]int{
+	146: {55, 48, 22, 460, 1007, 1008, 969, 561, 562, 231}, // NoRelatedSym
+	267: {269},                                             // NoMDEntryTypes
+	268: {279, 269, 278, 55, 270, 271, 290, 299, 83},       // NoMDEntries
+	580: {60},                                              // NoDates
+	702: {703, 704, 705},                                   // NoPositions
+}
+
+// FormatMessage renders message one field per line in wire order. Instances
+// of known repeating groups are indented under their count field.
 func (p *Protocol) FormatMessage(message string) string {
-	fields := p.parseFields(message)
+	fields := NewResponseMessage(message, p.delimiter).Fields()
 	fieldNames := p.GetFieldNames()
 	messageTypes := p.GetMessageTypeName()
 	
 	var result strings.Builder
 	
-	if msgTypeValues, exists := fields[35]; exists && len(msgTypeValues) > 0 {
-		if msgTypeName, exists := messageTypes[msgTypeValues[0]]; exists {
-			result.WriteString(fmt.Sprintf("Message Type: %s (%s)\n", msgTypeName, msgTypeValues[0]))
-		} else {
-			result.WriteString(fmt.Sprintf("Message Type: %s\n", msgTypeValues[0]))
+	for _, field := range fields {
+		if field.Tag == 35 {
+			if msgTypeName, exists := messageTypes[field.Value]; exists {
+				result.WriteString(fmt.Sprintf("Message Type: %s (%s)\n", msgTypeName, field.Value))
+			} else {
+				result.WriteString(fmt.Sprintf("Message Type: %s\n", field.Value))
+			}
+			break
+		}
+	}
+	
+	fieldName := func(tag int) string {
+		if name, exists := fieldNames[tag]; exists {
+			return name
 		}
+		return fmt.Sprintf("Field%d", tag)
 	}
 	
-	for fieldNum, values := range fields {
-		fieldName := fmt.Sprintf("Field%d", fieldNum)
-		if name, exists := fieldNames[fieldNum]; exists {
-			fieldName = name
+	var members map[int]bool
+	delimiterTag, instance := 0, 0
+	
+	for _, field := range fields {
+		if members != nil && !members[field.Tag] {
+			members = nil
 		}
 		
-		for i, value := range values {
-			if len(values) > 1 {
-				result.WriteString(fmt.Sprintf("%s[%d]: %s\n", fieldName, i, value))
-			} else {
-				result.WriteString(fmt.Sprintf("%s: %s\n", fieldName, value))
+		if members == nil {
+			result.WriteString(fmt.Sprintf("%s: %s\n", fieldName(field.Tag), field.Value))
+			
+			if groupTags, exists := repeatingGroups[field.Tag]; exists {
+				members = make(map[int]bool)
+				for _, tag := range groupTags {
+					members[tag] = true
+				}
+				delimiterTag, instance = 0, 0
 			}
+			continue
+		}
+		
+		// The first field of a group starts every instance.
+		if delimiterTag == 0 {
+			delimiterTag = field.Tag
+		}
+		if field.Tag == delimiterTag {
+			instance++
+			result.WriteString(fmt.Sprintf("  [%d]\n", instance))
 		}
+		result.WriteString(fmt.Sprintf("    %s: %s\n", fieldName(field.Tag), field.Value))
 	}
 	
 	return result.String()
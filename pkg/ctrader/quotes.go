@@ -0,0 +1,140 @@
+package ctrader
+
+import (
+	"strconv"
+	"time"
+)
+
+// mdEntry is one entry of a market data message's NoMDEntries (268) group.
+type mdEntry struct {
+	updateAction string
+	entryType    string
+	symbol       string
+	entryID      string
+	price        float64
+	hasPrice     bool
+	size         float64
+	level        int
+}
+
+// mdEntries splits the NoMDEntries group of msg into entries. Entries without
+// their own Symbol (55) inherit the message's.
+func mdEntries(msg *ResponseMessage) []mdEntry {
+	var entries []mdEntry
+	symbol := ""
+	inGroup := false
+	
+	for _, field := range msg.Fields() {
+		if !inGroup {
+			switch field.Tag {
+			case 55:
+				symbol = field.Value
+			case 268:
+				inGroup = true
+			}
+			continue
+		}
+		
+		// MDUpdateAction (279) leads incremental entries and MDEntryType
+		// (269) leads snapshot entries.
+		last := len(entries) - 1
+		startsEntry := field.Tag == 279 || (field.Tag == 269 && (last < 0 || entries[last].entryType != ""))
+		if startsEntry {
+			entries = append(entries, mdEntry{symbol: symbol})
+			last++
+		}
+		if last < 0 {
+			continue
+		}
+		
+		switch field.Tag {
+		case 279:
+			entries[last].updateAction = field.Value
+		case 269:
+			entries[last].entryType = field.Value
+		case 55:
+			entries[last].symbol = field.Value
+		case 278:
+			entries[last].entryID = field.Value
+		case 270:
+			if price, err := strconv.ParseFloat(field.Value, 64); err == nil {
+				entries[last].price, entries[last].hasPrice = price, true
+			}
+		case 271:
+			entries[last].size, _ = strconv.ParseFloat(field.Value, 64)
+		case 1023:
+			entries[last].level, _ = strconv.Atoi(field.Value)
+		}
+	}
+	
+	return entries
+}
+
+// quote is the book of a symbol kept for BestPrices.
+type quote struct {
+	book    *OrderBook
+	updated time.Time
+}
+
+// trackQuote applies a market data snapshot (35=W) or incremental refresh
+// (35=X) to the books of the symbols it names, so BestPrices reflects every
+// level and delete rather than the last entry of each message.
+func (c *Client) trackQuote(msg *ResponseMessage) {
+	now := time.Now()
+	
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	if c.quotes == nil {
+		c.quotes = make(map[string]*quote)
+	}
+	quoteFor := func(symbol string) *quote {
+		q, exists := c.quotes[symbol]
+		if !exists {
+			q = &quote{book: NewOrderBook(symbol)}
+			c.quotes[symbol] = q
+		}
+		return q
+	}
+	
+	switch msg.GetMessageType() {
+	case "W":
+		snapshot := newMarketDataSnapshot(msg)
+		if snapshot.Symbol == "" {
+			return
+		}
+		q := quoteFor(snapshot.Symbol)
+		q.book.ApplySnapshot(snapshot)
+		q.updated = now
+	case "X":
+		refresh := newMarketDataIncrementalRefresh(msg)
+		applied := make(map[string]bool)
+		for _, update := range refresh.Entries {
+			if update.Symbol == "" || applied[update.Symbol] {
+				continue
+			}
+			applied[update.Symbol] = true
+			q := quoteFor(update.Symbol)
+			q.book.ApplyIncrement(refresh)
+			q.updated = now
+		}
+	}
+}
+
+// BestPrices returns the highest bid and lowest ask in the book of symbol and
+// how long ago the book was last updated, so callers can refuse to trade on a
+// stale feed. It reports false until the book holds both a bid and an ask.
+func (c *Client) BestPrices(symbol string) (bid, ask float64, age time.Duration, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	
+	q, exists := c.quotes[symbol]
+	if !exists {
+		return 0, 0, 0, false
+	}
+	bestBid, bestAsk, ok := q.book.BestBidAsk()
+	if !ok {
+		return 0, 0, 0, false
+	}
+	return bestBid.Price, bestAsk.Price, time.Since(q.updated), true
+}
@@ -0,0 +1,102 @@
+package ctrader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// orderMsgTypes are the order messages counted by WithRateLimit: new order
+// (D), cancel (F) and cancel/replace (G).
+var orderMsgTypes = map[string]bool{"D": true, "F": true, "G": true}
+
+// WithRateLimit allows at most maxMessages order messages (35=D, F and G) in
+// any window of length per, below the throttle that gets a cTrader session
+// throttled or disconnected. Other messages are not counted. By default Send
+// waits until the window has room; see WithRateLimitFailFast. The limit
+// applies in addition to the one announced in the server's logon response.
+func WithRateLimit(maxMessages int, per time.Duration) ClientOption {
+	return func(c *Client) {
+		if maxMessages > 0 && per > 0 {
+			c.orderLimiter = newRateLimiter(maxMessages, per)
+		}
+	}
+}
+
+// WithRateLimitFailFast makes Send return ErrRateLimited instead of waiting
+// when an order message would exceed the limit set with WithRateLimit.
+func WithRateLimitFailFast(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.rateLimitFailFast = enabled
+	}
+}
+
+// waitOrderLimit takes a slot of the order rate limit for message, waiting
+// for one unless the client fails fast. Other messages pass immediately.
+func (c *Client) waitOrderLimit(ctx context.Context, message Message) error {
+	limiter := c.orderLimiter
+	if limiter == nil {
+		return nil
+	}
+	typed, ok := message.(interface{ MsgType() string })
+	if !ok || !orderMsgTypes[typed.MsgType()] {
+		return nil
+	}
+	
+	if c.rateLimitFailFast {
+		if limiter.reserve() != 0 {
+			return fmt.Errorf("%w: %d order messages per %s", ErrRateLimited, limiter.max, limiter.per)
+		}
+		return nil
+	}
+	return limiter.wait(ctx)
+}
+
+// rateLimiter allows at most max events in any sliding window of length per.
+type rateLimiter struct {
+	max  int
+	per  time.Duration
+	mu   sync.Mutex
+	sent []time.Time
+}
+
+func newRateLimiter(max int, per time.Duration) *rateLimiter {
+	return &rateLimiter{max: max, per: per}
+}
+
+// wait blocks until another event is allowed or ctx is done.
+func (rl *rateLimiter) wait(ctx context.Context) error {
+	for {
+		delay := rl.reserve()
+		if delay == 0 {
+			return nil
+		}
+		
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// reserve records an event and returns 0 if the window has room, otherwise
+// it returns how long until the oldest event leaves the window.
+func (rl *rateLimiter) reserve() time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	
+	now := time.Now()
+	for len(rl.sent) > 0 && now.Sub(rl.sent[0]) >= rl.per {
+		rl.sent = rl.sent[1:]
+	}
+	
+	if len(rl.sent) < rl.max {
+		rl.sent = append(rl.sent, now)
+		return 0
+	}
+	return rl.per - now.Sub(rl.sent[0])
+}
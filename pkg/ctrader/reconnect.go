@@ -0,0 +1,121 @@
+package ctrader
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// maxReconnectBackoff caps the exponential backoff of WithAutoReconnect.
+const maxReconnectBackoff = time.Minute
+
+// WithAutoReconnect makes the client reconnect and re-send its last logon
+// when the connection drops unexpectedly. Attempts are spaced by backoff,
+// doubling after each failure up to a minute, and stop after Disconnect or
+// after maxAttempts failures (zero retries forever), at which point Done is
+// closed. Sequence numbers resume from the SequenceStore, if any.
+func WithAutoReconnect(maxAttempts int, backoff time.Duration) ClientOption {
+	return func(c *Client) {
+		c.autoReconnect = true
+		c.reconnectMaxAttempts = maxAttempts
+		c.reconnectBackoff = backoff
+	}
+}
+
+// SetReconnectingCallback sets a callback invoked before every reconnect
+// attempt, numbered from 1. It runs on the reconnecting goroutine and should
+// return quickly.
+func (c *Client) SetReconnectingCallback(callback func(attempt int)) {
+	c.onReconnecting = callback
+}
+
+// reconnect replaces conn with a fresh connection and re-sends the last logon.
+// Without WithAutoReconnect it retries every heartbeat interval until it
+// succeeds or Disconnect is called. It does nothing if conn has already been
+// replaced.
+func (c *Client) reconnect(conn net.Conn, cause error) {
+	c.mu.Lock()
+	if c.conn != conn || c.closing {
+		c.mu.Unlock()
+		return
+	}
+	c.cancel()
+	conn.Close()
+	c.isConnected = false
+	c.mu.Unlock()
+	
+	c.reportError(cause)
+	if c.onDisconnected != nil {
+		go c.onDisconnected(cause)
+	}
+	
+	c.mu.RLock()
+	retryDelay := c.heartbeatPeriod()
+	c.mu.RUnlock()
+	if c.autoReconnect {
+		retryDelay = c.reconnectBackoff
+	}
+	if retryDelay <= 0 {
+		retryDelay = time.Second
+	}
+	
+	for attempt := 1; ; attempt++ {
+		c.mu.Lock()
+		if c.closing {
+			c.mu.Unlock()
+			return
+		}
+		if c.autoReconnect && c.reconnectMaxAttempts > 0 && attempt > c.reconnectMaxAttempts {
+			c.endSession()
+			c.mu.Unlock()
+			c.reportError(fmt.Errorf("%w: gave up after %d reconnect attempts", ErrConnectionLost, c.reconnectMaxAttempts))
+			return
+		}
+		c.mu.Unlock()
+		
+		c.logger.LogEvent(LogLevelInfo, "reconnecting", "attempt", attempt)
+		if c.onReconnecting != nil {
+			c.onReconnecting(attempt)
+		}
+		
+		c.mu.Lock()
+		if c.closing {
+			c.mu.Unlock()
+			return
+		}
+		err := c.start(context.Background())
+		logon := c.lastLogon
+		c.mu.Unlock()
+		
+		if err == nil {
+			c.metrics.countReconnect()
+			if logon != nil && c.reconcileOnReconnect {
+				if err := c.logonAndReconcile(logon); err != nil {
+					c.reportError(err)
+				}
+			} else if logon != nil {
+				if err := c.Send(logon); err != nil {
+					c.reportError(err)
+				}
+			}
+			return
+		}
+		
+		c.reportError(err)
+		time.Sleep(retryDelay)
+		if c.autoReconnect {
+			retryDelay = min(2*retryDelay, maxReconnectBackoff)
+		}
+	}
+}
+
+// reportError logs err and delivers it on the error channel without blocking
+// if nobody is draining it.
+func (c *Client) reportError(err error) {
+	c.logger.LogEvent(LogLevelError, err.Error())
+	select {
+	case c.errorChan <- err:
+	default:
+	}
+}
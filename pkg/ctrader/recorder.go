@@ -0,0 +1,164 @@
+package ctrader
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recording line markers for outbound and inbound frames.
+const (
+	recordOutbound = ">"
+	recordInbound  = "<"
+)
+
+// RecordedMessage is one frame of a session recording.
+type RecordedMessage struct {
+	Time     time.Time
+	Outbound bool
+	Raw      string
+}
+
+// WithRecorder writes every frame sent and received to w, one per line as
+// "<RFC3339 timestamp> <direction> <raw message>", where direction is ">" for
+// outbound and "<" for inbound frames. Recordings can be read back with
+// ReadRecording and replayed with a Replayer.
+func WithRecorder(w io.Writer) ClientOption {
+	return func(c *Client) {
+		c.recorder = w
+	}
+}
+
+// record appends raw to the recording, if any.
+func (c *Client) record(direction, raw string) {
+	if c.recorder == nil {
+		return
+	}
+	
+	c.recordMu.Lock()
+	defer c.recordMu.Unlock()
+	
+	line := fmt.Sprintf("%s %s %s\n", time.Now().UTC().Format(time.RFC3339Nano), direction, raw)
+	if _, err := io.WriteString(c.recorder, line); err != nil {
+		c.reportError(fmt.Errorf("failed to record message: %w", err))
+	}
+}
+
+// ReadRecording parses a recording written by WithRecorder.
+func ReadRecording(r io.Reader) ([]RecordedMessage, error) {
+	var messages []RecordedMessage
+	
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		
+		parts := strings.SplitN(line, " ", 3)
+		if len(parts) != 3 || (parts[1] != recordOutbound && parts[1] != recordInbound) {
+			return nil, fmt.Errorf("recording line %d: malformed entry", lineNum)
+		}
+		
+		timestamp, err := time.Parse(time.RFC3339Nano, parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("recording line %d: %w", lineNum, err)
+		}
+		
+		messages = append(messages, RecordedMessage{
+			Time:     timestamp,
+			Outbound: parts[1] == recordOutbound,
+			Raw:      parts[2],
+		})
+	}
+	
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// rawMessage is a pre-built frame sent through SendRaw.
+type rawMessage struct {
+	raw       string
+	delimiter string
+	clock     clock
+}
+
+// SendRaw sends a pre-built FIX message such as one taken from a recording.
+// MsgSeqNum (34) and SendingTime (52) are replaced with the session's current
+// values and BodyLength (9) and CheckSum (10) are recomputed; all other fields
+// are sent as given.
+func (c *Client) SendRaw(raw string) error {
+	if NewResponseMessage(raw, c.delimiter).GetMessageType() == "" {
+		return fmt.Errorf("raw message has no MsgType (35)")
+	}
+	return c.Send(&rawMessage{raw: raw, delimiter: c.delimiter})
+}
+
+// SendRawVerbatim writes raw exactly as given, only appending the delimiter
+// if raw does not end with one, to replay captured traffic byte for byte.
+// Unlike SendRaw nothing is restamped or recomputed and the outbound sequence
+// is left alone, so raw must carry a MsgSeqNum the server expects.
+func (c *Client) SendRawVerbatim(raw string) error {
+	if NewResponseMessage(raw, c.delimiter).GetMessageType() == "" {
+		return fmt.Errorf("raw message has no MsgType (35)")
+	}
+	if !strings.HasSuffix(raw, c.delimiter) {
+		raw += c.delimiter
+	}
+	
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	if !c.isConnected {
+		return ErrNotConnected
+	}
+	return c.writeRaw(raw)
+}
+
+func (rm *rawMessage) GetMessage(sequenceNumber int) string {
+	return rm.restamp(sequenceNumber, rm.delimiter)
+}
+
+// restamp rebuilds raw with the given sequence number, the current sending
+// time and a recomputed body length and checksum.
+func (rm *rawMessage) restamp(sequenceNumber int, delimiter string) string {
+	var beginString string
+	var body []string
+	hasSeqNum := false
+	
+	for _, field := range NewResponseMessage(rm.raw, delimiter).Fields() {
+		switch field.Tag {
+		case 8:
+			beginString = field.Value
+		case 9, 10:
+		case 34:
+			body = append(body, "34="+strconv.Itoa(sequenceNumber))
+			hasSeqNum = true
+		case 52:
+			body = append(body, "52="+rm.clock.format(rm.clock.now()))
+		default:
+			body = append(body, fmt.Sprintf("%d=%s", field.Tag, field.Value))
+		}
+	}
+	
+	if !hasSeqNum {
+		for i, field := range body {
+			if strings.HasPrefix(field, "35=") {
+				body = append(body[:i+1], append([]string{"34=" + strconv.Itoa(sequenceNumber)}, body[i+1:]...)...)
+				break
+			}
+		}
+	}
+	
+	bodyString := strings.Join(body, delimiter) + delimiter
+	headerAndBody := fmt.Sprintf("8=%s%s9=%d%s%s", beginString, delimiter, len(bodyString), delimiter, bodyString)
+	checksum := NewProtocol(delimiter).calculateChecksum(headerAndBody)
+	return fmt.Sprintf("%s10=%03d%s", headerAndBody, checksum, delimiter)
+}
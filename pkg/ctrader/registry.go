@@ -0,0 +1,79 @@
+package ctrader
+
+import (
+	"fmt"
+	"sort"
+)
+
+// builders maps each outbound MsgType to the constructor of its request type.
+var builders = map[string]func(*Config) Message{
+	"A":  func(config *Config) Message { return NewLogonRequest(config) },
+	"0":  func(config *Config) Message { return NewHeartbeat(config) },
+	"1":  func(config *Config) Message { return NewTestRequest(config) },
+	"2":  func(config *Config) Message { return NewResendRequest(config) },
+	"4":  func(config *Config) Message { return NewSequenceReset(config) },
+	"5":  func(config *Config) Message { return NewLogoutRequest(config) },
+	"D":  func(config *Config) Message { return NewOrderMsg(config) },
+	"F":  func(config *Config) Message { return NewOrderCancelRequest(config) },
+	"G":  func(config *Config) Message { return NewOrderCancelReplaceRequest(config) },
+	"H":  func(config *Config) Message { return NewOrderStatusRequest(config) },
+	"V":  func(config *Config) Message { return NewMarketDataRequest(config) },
+	"x":  func(config *Config) Message { return NewSecurityListRequest(config) },
+	"g":  func(config *Config) Message { return NewTradingSessionStatusRequest(config) },
+	"AD": func(config *Config) Message { return NewTradeCaptureReportRequest(config) },
+	"AF": func(config *Config) Message { return NewOrderMassStatusRequest(config) },
+	"AN": func(config *Config) Message { return NewRequestForPositions(config) },
+}
+
+// parsers maps each inbound MsgType to the function producing its typed form.
+var parsers = map[string]func(*ResponseMessage) interface{}{
+	"3":  func(msg *ResponseMessage) interface{} { return ParseReject(msg) },
+	"8":  func(msg *ResponseMessage) interface{} { return newExecutionReport(msg) },
+	"h":  func(msg *ResponseMessage) interface{} { return newTradingSession(msg) },
+	"j":  func(msg *ResponseMessage) interface{} { return ParseBusinessReject(msg) },
+	"y":  func(msg *ResponseMessage) interface{} { return ParseSecurityList(msg) },
+	"Y":  func(msg *ResponseMessage) interface{} { return newMarketDataRequestReject(msg) },
+	"W":  func(msg *ResponseMessage) interface{} { return newMarketDataSnapshot(msg) },
+	"X":  func(msg *ResponseMessage) interface{} { return newMarketDataIncrementalRefresh(msg) },
+	"AO": func(msg *ResponseMessage) interface{} { return newRequestForPositionsAck(msg) },
+	"AP": func(msg *ResponseMessage) interface{} { report := newPositionReport(msg); return &report },
+}
+
+// NewMessage returns a new, empty request of msgType.
+func NewMessage(msgType string, config *Config) (Message, error) {
+	builder, exists := builders[msgType]
+	if !exists {
+		return nil, fmt.Errorf("no builder for message type %q", msgType)
+	}
+	return builder(config), nil
+}
+
+// Parse converts msg into its typed representation, such as an
+// *ExecutionReport for 35=8.
+func Parse(msg *ResponseMessage) (interface{}, error) {
+	parser, exists := parsers[msg.GetMessageType()]
+	if !exists {
+		return nil, fmt.Errorf("no parser for message type %q", msg.GetMessageType())
+	}
+	return parser(msg), nil
+}
+
+// SupportedOutboundTypes lists the MsgTypes the library can build.
+func SupportedOutboundTypes() []string {
+	types := make([]string, 0, len(builders))
+	for msgType := range builders {
+		types = append(types, msgType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// SupportedParsers lists the MsgTypes the library can parse into structs.
+func SupportedParsers() []string {
+	types := make([]string, 0, len(parsers))
+	for msgType := range parsers {
+		types = append(types, msgType)
+	}
+	sort.Strings(types)
+	return types
+}
@@ -0,0 +1,207 @@
+package ctrader
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// sessionRejectReasons describes the SessionRejectReason (373) codes.
+var sessionRejectReasons = map[string]string{
+	"0":  "invalid tag number",
+	"1":  "required tag missing",
+	"2":  "tag not defined for this message type",
+	"3":  "undefined tag",
+	"4":  "tag specified without a value",
+	"5":  "value is incorrect (out of range) for this tag",
+	"6":  "incorrect data format for value",
+	"7":  "decryption problem",
+	"8":  "signature problem",
+	"9":  "CompID problem",
+	"10": "SendingTime accuracy problem",
+	"11": "invalid MsgType",
+	"12": "XML validation error",
+	"13": "tag appears more than once",
+	"14": "tag specified out of required order",
+	"15": "repeating group fields out of order",
+	"16": "incorrect NumInGroup count for repeating group",
+	"17": "non-data value includes field delimiter",
+	"99": "other",
+}
+
+// businessRejectReasons describes the BusinessRejectReason (380) codes.
+var businessRejectReasons = map[string]string{
+	"0": "other",
+	"1": "unknown ID",
+	"2": "unknown security",
+	"3": "unsupported message type",
+	"4": "application not available",
+	"5": "conditionally required field missing",
+	"6": "not authorized",
+	"7": "DeliverTo firm not available at this time",
+}
+
+// ordRejReasons describes the OrdRejReason (103) codes of rejected orders.
+var ordRejReasons = map[string]string{
+	"0":  "broker option",
+	"1":  "unknown symbol",
+	"2":  "exchange closed",
+	"3":  "order exceeds limit",
+	"4":  "too late to enter",
+	"5":  "unknown order",
+	"6":  "duplicate order",
+	"7":  "duplicate of a verbally communicated order",
+	"8":  "stale order",
+	"9":  "trade along required",
+	"10": "invalid investor ID",
+	"11": "unsupported order characteristic",
+	"12": "surveillance option",
+	"13": "incorrect quantity",
+	"14": "incorrect allocated quantity",
+	"15": "unknown account",
+	"18": "invalid price increment",
+	"99": "other",
+}
+
+// cxlRejReasons describes the CxlRejReason (102) codes of rejected cancel
+// and replace requests.
+var cxlRejReasons = map[string]string{
+	"0":  "too late to cancel",
+	"1":  "unknown order",
+	"2":  "broker option",
+	"3":  "order already pending cancel or replace",
+	"4":  "unable to process order mass cancel request",
+	"5":  "OrigOrdModTime does not match the order's last TransactTime",
+	"6":  "duplicate ClOrdID",
+	"99": "other",
+}
+
+// OrdRejReasonText describes an OrdRejReason (103) code of an execution
+// report rejecting an order, e.g. "unknown symbol" for "1". Unknown codes are
+// returned as they are.
+func OrdRejReasonText(code string) string {
+	if text, exists := ordRejReasons[code]; exists {
+		return text
+	}
+	return code
+}
+
+// CxlRejReasonText describes a CxlRejReason (102) code of an order cancel
+// reject (35=9), e.g. "too late to cancel" for "0". Unknown codes are
+// returned as they are.
+func CxlRejReasonText(code string) string {
+	if text, exists := cxlRejReasons[code]; exists {
+		return text
+	}
+	return code
+}
+
+// Reject is a parsed session-level reject (35=3): the server could not
+// process a message at the FIX level, e.g. because of a missing or unknown
+// tag. RefTagID names the tag the server objected to, which is usually all it
+// takes to fix a request. It matches ErrRequestRejected with errors.Is.
+type Reject struct {
+	RefSeqNum           int    // MsgSeqNum of the rejected message (45)
+	RefTagID            int    // RefTagID (371), 0 if not sent
+	SessionRejectReason string // SessionRejectReason (373)
+	Text                string
+}
+
+// ParseReject parses a session-level reject (35=3).
+func ParseReject(msg *ResponseMessage) *Reject {
+	reject := &Reject{
+		SessionRejectReason: msg.first(373),
+		Text:                msg.first(58),
+	}
+	reject.RefSeqNum, _ = strconv.Atoi(msg.first(45))
+	reject.RefTagID, _ = strconv.Atoi(msg.first(371))
+	return reject
+}
+
+// ReasonText describes SessionRejectReason, e.g. "required tag missing" for 1.
+func (r *Reject) ReasonText() string {
+	if text, exists := sessionRejectReasons[r.SessionRejectReason]; exists {
+		return text
+	}
+	return r.SessionRejectReason
+}
+
+func (r *Reject) Error() string {
+	msg := fmt.Sprintf("message %d rejected", r.RefSeqNum)
+	if r.SessionRejectReason != "" {
+		msg += fmt.Sprintf(": %s (373=%s)", r.ReasonText(), r.SessionRejectReason)
+	}
+	if r.RefTagID != 0 {
+		name, exists := NewProtocol("").GetFieldNames()[r.RefTagID]
+		if !exists {
+			name = "tag"
+		}
+		msg += fmt.Sprintf(" on %s (%d)", name, r.RefTagID)
+	}
+	if r.Text != "" {
+		msg += ": " + r.Text
+	}
+	return msg
+}
+
+func (r *Reject) Is(target error) bool {
+	return target == ErrRequestRejected
+}
+
+// BusinessReject is a parsed business message reject (35=j): the message was
+// valid FIX but the application refused it, e.g. an unsupported message type
+// or an unknown request ID. Unlike a Reject it references the request by
+// its ID (379) and type (372) rather than by sequence number. It matches
+// ErrRequestRejected with errors.Is.
+type BusinessReject struct {
+	RefMsgType           string // RefMsgType (372)
+	BusinessRejectReason string // BusinessRejectReason (380)
+	BusinessRejectRefID  string // BusinessRejectRefID (379)
+	Text                 string
+}
+
+// ParseBusinessReject parses a business message reject (35=j).
+func ParseBusinessReject(msg *ResponseMessage) *BusinessReject {
+	return &BusinessReject{
+		RefMsgType:           msg.first(372),
+		BusinessRejectReason: msg.first(380),
+		BusinessRejectRefID:  msg.first(379),
+		Text:                 msg.first(58),
+	}
+}
+
+// ReasonText describes BusinessRejectReason, e.g. "unknown security" for 2.
+func (r *BusinessReject) ReasonText() string {
+	if text, exists := businessRejectReasons[r.BusinessRejectReason]; exists {
+		return text
+	}
+	return r.BusinessRejectReason
+}
+
+func (r *BusinessReject) Error() string {
+	name, exists := NewProtocol("").GetMessageTypeName()[r.RefMsgType]
+	if !exists {
+		name = "message"
+	}
+	if r.BusinessRejectRefID != "" {
+		name += " " + r.BusinessRejectRefID
+	}
+	msg := name + " rejected"
+	if r.BusinessRejectReason != "" {
+		msg += fmt.Sprintf(": %s (380=%s)", r.ReasonText(), r.BusinessRejectReason)
+	}
+	if r.Text != "" {
+		msg += ": " + r.Text
+	}
+	return msg
+}
+
+func (r *BusinessReject) Is(target error) bool {
+	return target == ErrRequestRejected
+}
+
+// SetRejectCallback registers callback to be called with every session-level
+// reject (35=3) the server sends, in addition to its delivery on Messages.
+// During integration these tell you exactly which tag the server disliked.
+func (c *Client) SetRejectCallback(callback func(*Reject)) {
+	c.onReject = callback
+}
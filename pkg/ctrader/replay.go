@@ -0,0 +1,130 @@
+package ctrader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Replayer re-sends the outbound frames of a recording through a connected
+// client and compares the live responses with the recorded ones.
+type Replayer struct {
+	client   *Client
+	messages []RecordedMessage
+	
+	// Speed scales the recorded delays between outbound frames; 2 replays
+	// twice as fast and 0 sends without any delay.
+	Speed float64
+	
+	// Timeout bounds the wait for each recorded response.
+	Timeout time.Duration
+	
+	// CompareTags are compared between recorded and live responses in
+	// addition to MsgType. Tags absent from the recorded response are skipped.
+	CompareTags []int
+}
+
+// Divergence describes a live response that does not match the recording.
+// Actual is nil when no response arrived in time.
+type Divergence struct {
+	Index    int
+	Expected *ResponseMessage
+	Actual   *ResponseMessage
+	Reason   string
+}
+
+// ReplayResult summarizes a replay.
+type ReplayResult struct {
+	Matched     int
+	Divergences []Divergence
+}
+
+func NewReplayer(client *Client, messages []RecordedMessage) *Replayer {
+	return &Replayer{
+		client:      client,
+		messages:    messages,
+		Speed:       1,
+		Timeout:     5 * time.Second,
+		CompareTags: []int{39, 150},
+	}
+}
+
+// isHeartbeatTraffic reports whether msgType is timing-dependent session
+// traffic that replays do not compare.
+func isHeartbeatTraffic(msgType string) bool {
+	return msgType == "0" || msgType == "1"
+}
+
+// Run replays the recording in order. Heartbeats and test requests are not
+// compared since their timing differs between runs.
+func (r *Replayer) Run(ctx context.Context) (*ReplayResult, error) {
+	w := r.client.addWaiter(func(msg *ResponseMessage) bool {
+		return !isHeartbeatTraffic(msg.GetMessageType())
+	})
+	defer r.client.removeWaiter(w)
+	
+	result := &ReplayResult{}
+	var lastSent time.Time
+	
+	for i, recorded := range r.messages {
+		if recorded.Outbound {
+			if r.Speed > 0 && !lastSent.IsZero() {
+				delay := time.Duration(float64(recorded.Time.Sub(lastSent)) / r.Speed)
+				select {
+				case <-time.After(delay):
+				case <-ctx.Done():
+					return result, ctx.Err()
+				}
+			}
+			lastSent = recorded.Time
+			
+			if err := r.client.SendRaw(recorded.Raw); err != nil {
+				return result, fmt.Errorf("replaying message %d: %w", i, err)
+			}
+			continue
+		}
+		
+		expected := NewResponseMessage(recorded.Raw, r.client.delimiter)
+		if isHeartbeatTraffic(expected.GetMessageType()) {
+			continue
+		}
+		
+		waitCtx, cancel := context.WithTimeout(ctx, r.Timeout)
+		actual, err := w.receive(waitCtx)
+		cancel()
+		switch {
+		case err == nil:
+			if reason := r.compare(expected, actual); reason != "" {
+				result.Divergences = append(result.Divergences, Divergence{Index: i, Expected: expected, Actual: actual, Reason: reason})
+			} else {
+				result.Matched++
+			}
+		case ctx.Err() != nil:
+			return result, ctx.Err()
+		case errors.Is(err, context.DeadlineExceeded):
+			result.Divergences = append(result.Divergences, Divergence{Index: i, Expected: expected, Reason: "no response received"})
+		default:
+			return result, fmt.Errorf("replaying message %d: %w", i, err)
+		}
+	}
+	
+	return result, nil
+}
+
+// compare returns why actual differs from expected, or "" if they match.
+func (r *Replayer) compare(expected, actual *ResponseMessage) string {
+	if expected.GetMessageType() != actual.GetMessageType() {
+		return fmt.Sprintf("MsgType is %s, expected %s", actual.GetMessageType(), expected.GetMessageType())
+	}
+	for _, tag := range r.CompareTags {
+		want := expected.first(tag)
+		if want == "" {
+			continue
+		}
+		if got := actual.first(tag); got != want {
+			return fmt.Sprintf("tag %d is %q, expected %q", tag, got, want)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,259 @@
+package ctrader
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// ExecutionReport is a parsed execution report (35=8).
+type ExecutionReport struct {
+	ClOrdID       string
+	OrderID       string
+	ExecType      string
+	OrdStatus     string
+	Symbol        string
+	Side          string
+	OrdType       string
+	OrderQty      float64
+	Price         float64
+	StopPx        float64
+	CumQty        float64
+	LeavesQty     float64
+	LastQty       float64
+	AvgPx         float64
+	LastPx        float64
+	PosMaintRptID string
+	Designation   string
+	Text          string
+	OrdRejReason  string // OrdRejReason (103) of a rejected order; see OrdRejReasonText
+	
+	// MassStatusReqID, TotNumReports and LastRptRequested are set on
+	// reports answering an OrderMassStatusRequest.
+	MassStatusReqID  string
+	TotNumReports    int
+	LastRptRequested bool
+}
+
+func newExecutionReport(msg *ResponseMessage) *ExecutionReport {
+	report := &ExecutionReport{
+		ClOrdID:       msg.first(11),
+		OrderID:       msg.first(37),
+		ExecType:      msg.first(150),
+		OrdStatus:     msg.first(39),
+		Symbol:        msg.first(55),
+		Side:          msg.first(54),
+		OrdType:       msg.first(40),
+		PosMaintRptID: msg.first(721),
+		Designation:   msg.first(494),
+		Text:          msg.first(58),
+		OrdRejReason:  msg.first(103),
+	}
+	report.MassStatusReqID = msg.first(584)
+	report.TotNumReports, _ = strconv.Atoi(msg.first(911))
+	report.LastRptRequested = msg.first(912) == "Y"
+	report.OrderQty, _ = strconv.ParseFloat(msg.first(38), 64)
+	report.Price, _ = strconv.ParseFloat(msg.first(44), 64)
+	report.StopPx, _ = strconv.ParseFloat(msg.first(99), 64)
+	report.CumQty, _ = strconv.ParseFloat(msg.first(14), 64)
+	report.LeavesQty, _ = strconv.ParseFloat(msg.first(151), 64)
+	report.LastQty, _ = strconv.ParseFloat(msg.first(32), 64)
+	report.AvgPx, _ = strconv.ParseFloat(msg.first(6), 64)
+	report.LastPx, _ = strconv.ParseFloat(msg.first(31), 64)
+	return report
+}
+
+// ParseExecutionReport parses an execution report (35=8). Absent numeric
+// fields are zero.
+func ParseExecutionReport(msg *ResponseMessage) (*ExecutionReport, error) {
+	if msgType := msg.GetMessageType(); msgType != "8" {
+		return nil, fmt.Errorf("expected an execution report (35=8), got MsgType %q", msgType)
+	}
+	return newExecutionReport(msg), nil
+}
+
+// terminalStatuses are the OrdStatus (39) values after which an order never
+// changes again.
+var terminalStatuses = map[string]bool{
+	"2": true, // Filled
+	"3": true, // Done for day
+	"4": true, // Canceled
+	"8": true, // Rejected
+	"C": true, // Expired
+}
+
+// IsTerminal reports whether the order is done: filled, canceled, rejected,
+// expired or done for the day.
+func (r *ExecutionReport) IsTerminal() bool {
+	return terminalStatuses[r.OrdStatus]
+}
+
+// IsWorking reports whether the order is still live on the server: new,
+// partially filled, or pending a new, cancel or replace.
+func (r *ExecutionReport) IsWorking() bool {
+	return workingStatuses[r.OrdStatus]
+}
+
+// PositionReport is a parsed position report (35=AP) received in response to
+// a RequestForPositions.
+type PositionReport struct {
+	PosReqID           string
+	PosMaintRptID      string
+	Symbol             string
+	Designation        string
+	LongQty            float64
+	ShortQty           float64
+	SettlPrice         float64
+	TotalNumPosReports int
+	PosReqResult       string
+	LastRptRequested   bool // LastRptRequested (912): the last report of the request
+	
+	// Positions holds every entry of the NoPositions (702) group; LongQty
+	// and ShortQty are those of the first.
+	Positions []PositionQty
+}
+
+// PositionQty is one entry of a position report's NoPositions (702) group.
+type PositionQty struct {
+	PosType  string // PosType (703), e.g. "TQ" for transaction quantity
+	LongQty  float64
+	ShortQty float64
+}
+
+func newPositionReport(msg *ResponseMessage) PositionReport {
+	report := PositionReport{
+		PosReqID:      msg.first(710),
+		PosMaintRptID: msg.first(721),
+		Symbol:        msg.first(55),
+		Designation:   msg.first(494),
+		PosReqResult:  msg.first(728),
+	}
+	report.LongQty, _ = strconv.ParseFloat(msg.first(704), 64)
+	report.ShortQty, _ = strconv.ParseFloat(msg.first(705), 64)
+	report.SettlPrice, _ = strconv.ParseFloat(msg.first(730), 64)
+	report.TotalNumPosReports, _ = strconv.Atoi(msg.first(727))
+	report.LastRptRequested = msg.first(912) == "Y"
+	
+	for i, field := range msg.Fields() {
+		if field.Tag != 702 {
+			continue
+		}
+		for _, instance := range msg.groupInstances(i) {
+			var position PositionQty
+			for _, member := range instance {
+				switch member.Tag {
+				case 703:
+					position.PosType = member.Value
+				case 704:
+					position.LongQty, _ = strconv.ParseFloat(member.Value, 64)
+				case 705:
+					position.ShortQty, _ = strconv.ParseFloat(member.Value, 64)
+				}
+			}
+			report.Positions = append(report.Positions, position)
+		}
+		break
+	}
+	
+	return report
+}
+
+// ParsePositionReport parses a PositionReport (35=AP). Absent numeric fields
+// are zero.
+func ParsePositionReport(msg *ResponseMessage) (*PositionReport, error) {
+	if !isPositionReport(msg) {
+		return nil, fmt.Errorf("expected a position report (35=AP), got MsgType %q", msg.GetMessageType())
+	}
+	report := newPositionReport(msg)
+	return &report, nil
+}
+
+// isPositionReport reports whether msg is a PositionReport.
+func isPositionReport(msg *ResponseMessage) bool {
+	return msg.GetMessageType() == "AP"
+}
+
+// RequestForPositionsAck is a parsed RequestForPositionsAck (35=AO) telling
+// how many position reports will follow a RequestForPositions.
+type RequestForPositionsAck struct {
+	PosReqID           string
+	PosMaintRptID      string
+	TotalNumPosReports int
+	PosReqResult       string // PosReqResult (728): 0 valid, 2 no positions found
+	PosReqStatus       string // PosReqStatus (729): 0 completed, 2 rejected
+	Text               string
+}
+
+// ParseRequestForPositionsAck parses a RequestForPositionsAck (35=AO).
+func ParseRequestForPositionsAck(msg *ResponseMessage) (*RequestForPositionsAck, error) {
+	if !isPositionAck(msg) {
+		return nil, fmt.Errorf("expected a request for positions ack (35=AO), got MsgType %q", msg.GetMessageType())
+	}
+	return newRequestForPositionsAck(msg), nil
+}
+
+func newRequestForPositionsAck(msg *ResponseMessage) *RequestForPositionsAck {
+	ack := &RequestForPositionsAck{
+		PosReqID:      msg.first(710),
+		PosMaintRptID: msg.first(721),
+		PosReqResult:  msg.first(728),
+		PosReqStatus:  msg.first(729),
+		Text:          msg.first(58),
+	}
+	ack.TotalNumPosReports, _ = strconv.Atoi(msg.first(727))
+	return ack
+}
+
+// isPositionAck reports whether msg is a RequestForPositionsAck, which always
+// carries PosReqResult (728) or TotalNumPosReports (727).
+func isPositionAck(msg *ResponseMessage) bool {
+	return msg.GetMessageType() == "AO" && (msg.first(728) != "" || msg.first(727) != "")
+}
+
+// RequestPositions requests all open positions and collects the position
+// reports. It returns once the report flagged LastRptRequested (912=Y) or
+// TotalNumPosReports (727), as announced by the RequestForPositionsAck or the
+// reports themselves, have arrived, when the server reports that no positions
+// exist (PosReqResult 728=2), or when ctx is done. Without either it keeps
+// collecting until ctx is done.
+func (c *Client) RequestPositions(ctx context.Context) ([]*PositionReport, error) {
+	request := NewRequestForPositions(c.config)
+	request.PosReqID = c.nextRequestID("POS")
+	
+	var reports []*PositionReport
+	total := -1
+	err := c.sendAndCollect(ctx, request, func(msg *ResponseMessage) bool {
+		return (isPositionAck(msg) || isPositionReport(msg)) && msg.first(710) == request.PosReqID
+	}, func(msg *ResponseMessage) (bool, error) {
+		switch result := msg.first(728); result {
+		case "", "0":
+		case "2": // No positions found that match criteria
+			return true, nil
+		default:
+			return true, fmt.Errorf("%w: position request result %s", ErrRequestRejected, result)
+		}
+		
+		// total stays -1 while TotalNumPosReports (727) is unknown.
+		if isPositionAck(msg) {
+			if msg.first(727) != "" {
+				total = newRequestForPositionsAck(msg).TotalNumPosReports
+			}
+			return total == 0, nil
+		}
+		
+		report := newPositionReport(msg)
+		reports = append(reports, &report)
+		if report.LastRptRequested {
+			return true, nil
+		}
+		if total < 0 && msg.first(727) != "" {
+			total = report.TotalNumPosReports
+		}
+		return total > 0 && len(reports) >= total, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	return reports, nil
+}
@@ -0,0 +1,181 @@
+package ctrader
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// adminMsgTypes are the session-level message types. They are never resent;
+// a ResendRequest covering them is answered with a gap fill.
+var adminMsgTypes = map[string]bool{
+	"0": true, "1": true, "2": true, "3": true, "4": true, "5": true, "A": true,
+}
+
+// sentMessage is a message kept for resending.
+type sentMessage struct {
+	seqNum int
+	raw    string
+}
+
+// sendBuffer keeps the most recently sent messages in a ring indexed by
+// sequence number.
+type sendBuffer struct {
+	entries []sentMessage
+}
+
+func newSendBuffer(size int) *sendBuffer {
+	return &sendBuffer{entries: make([]sentMessage, size)}
+}
+
+func (b *sendBuffer) add(seqNum int, raw string) {
+	b.entries[seqNum%len(b.entries)] = sentMessage{seqNum: seqNum, raw: raw}
+}
+
+// get returns the message sent as seqNum, if it is still buffered.
+func (b *sendBuffer) get(seqNum int) (string, bool) {
+	entry := b.entries[seqNum%len(b.entries)]
+	return entry.raw, entry.seqNum == seqNum && entry.raw != ""
+}
+
+func (b *sendBuffer) clear() {
+	for i := range b.entries {
+		b.entries[i] = sentMessage{}
+	}
+}
+
+// WithSendBuffer keeps the last size messages sent so that a
+// ResendRequest (35=2) from the server can be answered by re-transmitting
+// them with PossDupFlag (43=Y) and their original SendingTime in
+// OrigSendingTime (122). Admin messages and messages no longer buffered are
+// covered by a gap fill (35=4, 123=Y). Without a send buffer every
+// ResendRequest is answered with a gap fill.
+func WithSendBuffer(size int) ClientOption {
+	return func(c *Client) {
+		if size > 0 {
+			c.sendBuffer = newSendBuffer(size)
+		}
+	}
+}
+
+// clearSendBuffer forgets all sent messages after the outbound sequence
+// restarted. Callers must hold c.mu.
+func (c *Client) clearSendBuffer() {
+	if c.sendBuffer != nil {
+		c.sendBuffer.clear()
+	}
+}
+
+// handleResendRequest re-transmits the range requested by a ResendRequest,
+// gap filling whatever cannot or must not be resent. Nothing else is sent
+// while it runs.
+func (c *Client) handleResendRequest(msg *ResponseMessage) {
+	beginSeqNo, err := strconv.Atoi(msg.first(7))
+	if err != nil || beginSeqNo < 1 {
+		c.reportError(fmt.Errorf("%w: ResendRequest with BeginSeqNo %q", ErrMalformedMessage, msg.first(7)))
+		return
+	}
+	endSeqNo, _ := strconv.Atoi(msg.first(16))
+	
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	if !c.isConnected {
+		return
+	}
+	if endSeqNo == 0 || endSeqNo > c.messageSequenceNum {
+		endSeqNo = c.messageSequenceNum
+	}
+	
+	gapStart := 0
+	for seqNum := beginSeqNo; seqNum <= endSeqNo; seqNum++ {
+		var raw string
+		var ok bool
+		if c.sendBuffer != nil {
+			raw, ok = c.sendBuffer.get(seqNum)
+		}
+		if ok && adminMsgTypes[NewResponseMessage(raw, c.delimiter).GetMessageType()] {
+			ok = false
+		}
+		
+		if !ok {
+			if gapStart == 0 {
+				gapStart = seqNum
+			}
+			continue
+		}
+		if gapStart != 0 {
+			if err := c.resendGapFill(gapStart, seqNum); err != nil {
+				c.reportError(err)
+				return
+			}
+			gapStart = 0
+		}
+		if err := c.writeRaw(possDupCopy(raw, c.delimiter, c.clock())); err != nil {
+			c.reportError(err)
+			return
+		}
+	}
+	if gapStart != 0 {
+		if err := c.resendGapFill(gapStart, endSeqNo+1); err != nil {
+			c.reportError(err)
+		}
+	}
+}
+
+// resendGapFill sends a gap fill as seqNum telling the server to expect
+// newSeqNo next. Callers must hold c.mu.
+func (c *Client) resendGapFill(seqNum, newSeqNo int) error {
+	gapFill := NewSequenceReset(c.config)
+	gapFill.GapFillFlag = true
+	gapFill.NewSeqNo = newSeqNo
+	gapFill.delimiter = c.delimiter
+	return c.writeRaw(possDupCopy(gapFill.GetMessage(seqNum), c.delimiter, c.clock()))
+}
+
+// writeRaw writes raw as is, for resent messages and SendRawVerbatim, without
+// touching the outbound sequence. Callers must hold c.mu.
+func (c *Client) writeRaw(raw string) error {
+	if c.writeTimeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	}
+	if _, err := c.conn.Write([]byte(raw)); err != nil {
+		return fmt.Errorf("failed to write message: %w", wrapConnError("write", err))
+	}
+	c.lastOutbound = time.Now()
+	c.record(recordOutbound, raw)
+	c.logger.LogOutbound(raw)
+	c.metrics.countSent(NewResponseMessage(raw, c.delimiter).GetMessageType())
+	c.echo(raw)
+	return nil
+}
+
+// possDupCopy returns raw marked as a possible duplicate: PossDupFlag (43=Y)
+// and OrigSendingTime (122) carrying its SendingTime (52) are added, 52 is
+// set to the time of clk and BodyLength (9) and CheckSum (10) are recomputed.
+func possDupCopy(raw, delimiter string, clk clock) string {
+	var beginString string
+	var body []string
+	
+	for _, field := range NewResponseMessage(raw, delimiter).Fields() {
+		switch field.Tag {
+		case 8:
+			beginString = field.Value
+		case 9, 10, 43, 122:
+		case 52:
+			body = append(body,
+				"43=Y",
+				"52="+clk.format(clk.now()),
+				"122="+field.Value,
+			)
+		default:
+			body = append(body, fmt.Sprintf("%d=%s", field.Tag, field.Value))
+		}
+	}
+	
+	bodyString := strings.Join(body, delimiter) + delimiter
+	headerAndBody := fmt.Sprintf("8=%s%s9=%d%s%s", beginString, delimiter, len(bodyString), delimiter, bodyString)
+	checksum := NewProtocol(delimiter).calculateChecksum(headerAndBody)
+	return fmt.Sprintf("%s10=%03d%s", headerAndBody, checksum, delimiter)
+}
@@ -0,0 +1,127 @@
+package ctrader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SequenceStore persists the outbound and inbound MsgSeqNum of a session so
+// a client can resume it after a reconnect or restart without resetting
+// sequence numbers.
+type SequenceStore interface {
+	LoadOutbound() int
+	SaveOutbound(seqNum int) error
+	LoadInbound() int
+	SaveInbound(seqNum int) error
+}
+
+// WithSequenceStore makes the client resume from the sequence numbers in
+// store on every connect instead of starting from zero, and save them as
+// messages are sent and received. A logon with ResetSeqNum set zeroes them.
+func WithSequenceStore(store SequenceStore) ClientOption {
+	return func(c *Client) {
+		c.seqStore = store
+	}
+}
+
+// saveOutbound persists the outbound sequence number. Callers must hold c.mu.
+func (c *Client) saveOutbound() {
+	if c.seqStore == nil {
+		return
+	}
+	if err := c.seqStore.SaveOutbound(c.messageSequenceNum); err != nil {
+		c.reportError(fmt.Errorf("failed to save outbound sequence number: %w", err))
+	}
+}
+
+// saveInbound persists the inbound sequence number. Callers must hold c.mu.
+func (c *Client) saveInbound() {
+	if c.seqStore == nil {
+		return
+	}
+	if err := c.seqStore.SaveInbound(c.inboundSeqNum); err != nil {
+		c.reportError(fmt.Errorf("failed to save inbound sequence number: %w", err))
+	}
+}
+
+// FileSequenceStore is a SequenceStore kept in a file as "<outbound> <inbound>".
+// Every save replaces the file atomically, so a crash never leaves it torn.
+type FileSequenceStore struct {
+	path     string
+	mu       sync.Mutex
+	outbound int
+	inbound  int
+}
+
+// NewFileSequenceStore opens the store at path, starting from zero if the
+// file does not exist yet.
+func NewFileSequenceStore(path string) (*FileSequenceStore, error) {
+	store := &FileSequenceStore{path: path}
+	
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sequence store: %w", err)
+	}
+	
+	if _, err := fmt.Sscanf(strings.TrimSpace(string(data)), "%d %d", &store.outbound, &store.inbound); err != nil {
+		return nil, fmt.Errorf("invalid sequence store %s: %w", path, err)
+	}
+	return store, nil
+}
+
+func (s *FileSequenceStore) LoadOutbound() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.outbound
+}
+
+func (s *FileSequenceStore) SaveOutbound(seqNum int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	s.outbound = seqNum
+	return s.write()
+}
+
+func (s *FileSequenceStore) LoadInbound() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inbound
+}
+
+func (s *FileSequenceStore) SaveInbound(seqNum int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	s.inbound = seqNum
+	return s.write()
+}
+
+// write replaces the file through a temporary file and a rename. Callers must
+// hold s.mu.
+func (s *FileSequenceStore) write() error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	
+	if _, err := fmt.Fprintf(tmp, "%d %d\n", s.outbound, s.inbound); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
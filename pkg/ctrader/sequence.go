@@ -0,0 +1,136 @@
+package ctrader
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// seqStatus classifies an inbound MsgSeqNum against the expected one.
+type seqStatus int
+
+const (
+	seqOK seqStatus = iota
+	seqTooLow
+	seqGap
+	seqDuplicate
+)
+
+// seqRange is an inclusive range of inbound sequence numbers.
+type seqRange struct {
+	from, to int
+}
+
+// trackInbound records the arrival time and sequence number of the latest
+// inbound message and checks it against the expected sequence number.
+// Sequence resets set the expectation rather than being checked, as does a
+// logon answering our ResetSeqNum with a MsgSeqNum other than 1; any other
+// logon is checked like a regular message.
+// Possible duplicates (43=Y) below it are accepted if they fill a gap and
+// reported as seqDuplicate if the sequence number was already processed.
+func (c *Client) trackInbound(message *ResponseMessage) (status seqStatus, expected, received int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.saveInbound()
+	
+	c.lastInbound = time.Now()
+	
+	seqNum, err := strconv.Atoi(message.first(34))
+	if err != nil {
+		return seqOK, 0, 0
+	}
+	
+	switch message.GetMessageType() {
+	case "A":
+		// Some brokers ignore 141=Y and keep their own numbering; adopt the
+		// server's sequence so later messages are not mistaken for a gap.
+		if c.lastLogon != nil && c.lastLogon.ResetSeqNum && seqNum != 1 {
+			c.inboundSeqNum = seqNum
+			c.missingSeqNums = nil
+			return seqOK, 0, seqNum
+		}
+	case "4":
+		if newSeqNo, err := strconv.Atoi(message.first(36)); err == nil {
+			// A gap fill only skips ahead; resent messages may already have
+			// moved the expectation past it. A reset applies as is.
+			if message.first(123) != "Y" {
+				c.inboundSeqNum = newSeqNo - 1
+				c.missingSeqNums = nil
+				return seqOK, 0, seqNum
+			}
+			if newSeqNo-1 > c.inboundSeqNum {
+				c.inboundSeqNum = newSeqNo - 1
+			}
+			c.takeMissing(seqNum, newSeqNo-1)
+			return seqOK, 0, seqNum
+		}
+	}
+	
+	expected = c.inboundSeqNum + 1
+	switch {
+	case c.inboundSeqNum == 0 || seqNum == expected:
+		c.inboundSeqNum = seqNum
+		return seqOK, expected, seqNum
+	case seqNum < expected:
+		if !message.PossDup() {
+			return seqTooLow, expected, seqNum
+		}
+		if c.takeMissing(seqNum, seqNum) {
+			return seqOK, expected, seqNum
+		}
+		return seqDuplicate, expected, seqNum
+	default:
+		c.missingSeqNums = append(c.missingSeqNums, seqRange{from: expected, to: seqNum - 1})
+		c.inboundSeqNum = seqNum
+		return seqGap, expected, seqNum
+	}
+}
+
+// takeMissing removes from to to from the sequence numbers skipped by gaps and
+// reports whether any of them was missing. The caller must hold c.mu.
+func (c *Client) takeMissing(from, to int) bool {
+	var missing []seqRange
+	taken := false
+	
+	for _, r := range c.missingSeqNums {
+		if to < r.from || from > r.to {
+			missing = append(missing, r)
+			continue
+		}
+		taken = true
+		if r.from < from {
+			missing = append(missing, seqRange{from: r.from, to: from - 1})
+		}
+		if to < r.to {
+			missing = append(missing, seqRange{from: to + 1, to: r.to})
+		}
+	}
+	
+	c.missingSeqNums = missing
+	return taken
+}
+
+// rejectSeqTooLow ends the session after the server sent a MsgSeqNum below
+// the expected one without PossDupFlag, which FIX treats as unrecoverable.
+func (c *Client) rejectSeqTooLow(expected, received int) {
+	text := fmt.Sprintf("MsgSeqNum too low, expecting %d but received %d", expected, received)
+	c.reportError(fmt.Errorf("%w: %s", ErrSeqNumTooLow, text))
+	
+	logout := NewLogoutRequest(c.config)
+	logout.Text = text
+	if err := c.Send(logout); err != nil {
+		c.reportError(err)
+	}
+	c.Disconnect()
+}
+
+// requestResend asks the server to resend everything from beginSeqNo on after
+// a gap in the inbound sequence.
+func (c *Client) requestResend(beginSeqNo int) {
+	resend := NewResendRequest(c.config)
+	resend.BeginSeqNo = beginSeqNo
+	resend.EndSeqNo = 0 // Up to the latest message
+	if err := c.Send(resend); err != nil {
+		c.reportError(err)
+	}
+}
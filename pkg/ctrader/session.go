@@ -0,0 +1,134 @@
+package ctrader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// SessionInfo holds the session parameters announced by the server in its
+// logon response. Zero values mean the server did not advertise the setting.
+type SessionInfo struct {
+	HeartBtInt     int
+	MaxMessageSize int
+	ThrottleLimit  int
+	ThrottleWindow time.Duration
+}
+
+// throttleTimeUnits maps ThrottleTimeUnit (1688) codes to durations.
+var throttleTimeUnits = map[string]time.Duration{
+	"0":  time.Second,
+	"1":  100 * time.Millisecond,
+	"2":  10 * time.Millisecond,
+	"3":  time.Millisecond,
+	"4":  time.Microsecond,
+	"5":  time.Nanosecond,
+	"10": time.Minute,
+	"11": time.Hour,
+}
+
+func newSessionInfo(msg *ResponseMessage) SessionInfo {
+	var info SessionInfo
+	info.HeartBtInt, _ = strconv.Atoi(msg.first(108))
+	info.MaxMessageSize, _ = strconv.Atoi(msg.first(383))
+	info.ThrottleLimit, _ = strconv.Atoi(msg.first(1686))
+	
+	if interval, err := strconv.Atoi(msg.first(1687)); err == nil {
+		unit, exists := throttleTimeUnits[msg.first(1688)]
+		if !exists {
+			unit = time.Second
+		}
+		info.ThrottleWindow = time.Duration(interval) * unit
+	}
+	
+	return info
+}
+
+// handleLogon records the server's session parameters, lets advertised limits
+// override the configured ones and reports a ResetSeqNum the server did not
+// honor.
+func (c *Client) handleLogon(msg *ResponseMessage) {
+	info := newSessionInfo(msg)
+	c.measureClockSkew(msg)
+	
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	// trackInbound has already adopted the server's sequence.
+	if seqNum, err := strconv.Atoi(msg.first(34)); err == nil {
+		if c.lastLogon != nil && c.lastLogon.ResetSeqNum && seqNum != 1 {
+			c.reportError(fmt.Errorf("%w: logon response has MsgSeqNum %d, continuing from it", ErrSeqResetIgnored, seqNum))
+		}
+	}
+	
+	c.sessionInfo = info
+	if info.MaxMessageSize > 0 {
+		c.maxMessageSize = info.MaxMessageSize
+	}
+	if info.ThrottleLimit > 0 && info.ThrottleWindow > 0 {
+		c.limiter = newRateLimiter(info.ThrottleLimit, info.ThrottleWindow)
+	}
+}
+
+// SessionInfo returns the parameters announced in the server's last logon
+// response.
+func (c *Client) SessionInfo() SessionInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sessionInfo
+}
+
+// RateLimit returns the effective outbound limit of maxMessages per window,
+// or zero values if sending is not throttled.
+func (c *Client) RateLimit() (maxMessages int, per time.Duration) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	
+	if c.limiter == nil {
+		return 0, 0
+	}
+	return c.limiter.max, c.limiter.per
+}
+
+// WithPostLogonHooks runs hooks in order after every successful logon,
+// including the logon that follows a reconnect, so startup steps such as
+// loading symbols and subscribing to market data are repeated for every
+// session. The chain stops at the first hook that fails and its error is
+// reported on the error channel.
+func WithPostLogonHooks(hooks ...func(*Client) error) ClientOption {
+	return func(c *Client) {
+		c.postLogonHooks = append(c.postLogonHooks, hooks...)
+	}
+}
+
+// runPostLogonHooks runs the post-logon hooks; it is started in its own
+// goroutine so hooks can wait for responses.
+func (c *Client) runPostLogonHooks() {
+	for i, hook := range c.postLogonHooks {
+		if err := hook(c); err != nil {
+			c.reportError(fmt.Errorf("post-logon hook %d: %w", i+1, err))
+			return
+		}
+	}
+}
+
+// Logout ends the session gracefully: it sends a Logout (35=5), waits up to
+// timeout for the server to answer with its own Logout and then closes the
+// connection. The connection is closed even if the server does not answer in
+// time, in which case an error wrapping context.DeadlineExceeded is returned.
+func (c *Client) Logout(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	
+	_, err := c.SendAndWait(ctx, NewLogoutRequest(c.config), func(msg *ResponseMessage) bool {
+		return msg.GetMessageType() == "5"
+	})
+	c.Disconnect()
+	
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("logout not acknowledged within %s: %w", timeout, err)
+	}
+	return err
+}
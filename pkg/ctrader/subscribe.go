@@ -0,0 +1,190 @@
+package ctrader
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// subscriptionBuffer is the number of updates a subscription channel holds
+// before further updates are dropped.
+const subscriptionBuffer = 64
+
+// mdSubscription routes the market data of one MDReqID to its channel.
+type mdSubscription struct {
+	symbolID string
+	book     *OrderBook
+	updates  chan *MarketDataSnapshot
+	rptSeq   int // last RptSeq (83) received; 0 before the first
+}
+
+// MarketDataGapError is reported on the error channel when an incremental
+// refresh of a subscription does not carry the next RptSeq (83): an update
+// was lost or arrived out of order and the subscription's book may be stale.
+// Subscribing again fetches a fresh snapshot. It matches ErrMarketDataGap
+// with errors.Is.
+type MarketDataGapError struct {
+	MDReqID  string
+	Symbol   string
+	Expected int
+	Received int
+}
+
+func (e *MarketDataGapError) Error() string {
+	return fmt.Sprintf("market data gap on %s (symbol %s): expected RptSeq %d, got %d", e.MDReqID, e.Symbol, e.Expected, e.Received)
+}
+
+func (e *MarketDataGapError) Is(target error) bool {
+	return target == ErrMarketDataGap
+}
+
+// SubscribeMarketData subscribes to snapshots and updates of symbolID with
+// the given market depth (0 for the full book, 1 for top of book). Every
+// snapshot (35=W) and incremental refresh (35=X) for the subscription is
+// applied to a book and the resulting state is delivered on the returned
+// channel; updates are dropped while the channel is full. The returned func
+// unsubscribes and closes the channel, which is also closed when the session
+// ends or the server rejects the request; a reject is reported on the error
+// channel as a *MarketDataRequestReject. An incremental refresh that skips or
+// repeats a RptSeq (83) is reported there as a *MarketDataGapError.
+func (c *Client) SubscribeMarketData(symbolID string, depth int) (<-chan *MarketDataSnapshot, func() error, error) {
+	request := NewMarketDataRequest(c.config)
+	request.MDReqID = c.nextRequestID("MD")
+	request.SubscriptionRequestType = "1" // Snapshot + Updates
+	request.MarketDepth = depth
+	request.MDEntryTypes = []string{string(MDEntryBid), string(MDEntryOffer)}
+	request.Symbol = symbolID
+	
+	subscription := &mdSubscription{
+		symbolID: symbolID,
+		book:     NewOrderBook(symbolID),
+		updates:  make(chan *MarketDataSnapshot, subscriptionBuffer),
+	}
+	
+	// Register first so the initial snapshot cannot be missed.
+	c.mu.Lock()
+	if c.subscriptions == nil {
+		c.subscriptions = make(map[string]*mdSubscription)
+	}
+	c.subscriptions[request.MDReqID] = subscription
+	c.mu.Unlock()
+	
+	if err := c.Send(request); err != nil {
+		c.mu.Lock()
+		c.removeSubscription(request.MDReqID)
+		c.mu.Unlock()
+		return nil, nil, err
+	}
+	
+	unsubscribe := func() error {
+		c.mu.Lock()
+		_, active := c.subscriptions[request.MDReqID]
+		c.removeSubscription(request.MDReqID)
+		c.mu.Unlock()
+		if !active {
+			return nil
+		}
+		
+		cancel := NewMarketDataRequest(c.config)
+		cancel.MDReqID = request.MDReqID
+		cancel.SubscriptionRequestType = "2" // Disable previous snapshot + updates
+		cancel.MarketDepth = depth
+		cancel.MDEntryTypes = request.MDEntryTypes
+		cancel.Symbol = symbolID
+		return c.Send(cancel)
+	}
+	
+	return subscription.updates, unsubscribe, nil
+}
+
+// removeSubscription unregisters mdReqID and closes its channel. The caller
+// must hold c.mu.
+func (c *Client) removeSubscription(mdReqID string) {
+	if subscription, exists := c.subscriptions[mdReqID]; exists {
+		delete(c.subscriptions, mdReqID)
+		close(subscription.updates)
+	}
+}
+
+// closeSubscriptions ends every subscription; the caller must hold c.mu.
+func (c *Client) closeSubscriptions() {
+	for mdReqID := range c.subscriptions {
+		c.removeSubscription(mdReqID)
+	}
+}
+
+// routeMarketData delivers a market data message (35=W/X) or reject (35=Y)
+// to the subscription named by its MDReqID (262).
+func (c *Client) routeMarketData(msg *ResponseMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	mdReqID := msg.first(262)
+	subscription, exists := c.subscriptions[mdReqID]
+	if !exists {
+		return
+	}
+	
+	switch msg.GetMessageType() {
+	case "W":
+		snapshot := newMarketDataSnapshot(msg)
+		if snapshot.Symbol == "" {
+			snapshot.Symbol = subscription.symbolID
+		}
+		subscription.book.ApplySnapshot(snapshot)
+		// A snapshot starts the sequence afresh.
+		subscription.rptSeq = 0
+		for _, seq := range rptSeqs(msg) {
+			subscription.rptSeq = seq
+		}
+	case "X":
+		c.checkRptSeq(mdReqID, subscription, msg)
+		subscription.book.ApplyIncrement(newMarketDataIncrementalRefresh(msg))
+	case "Y":
+		c.removeSubscription(mdReqID)
+		c.reportError(newMarketDataRequestReject(msg))
+		return
+	}
+	
+	update := &MarketDataSnapshot{
+		MDReqID: mdReqID,
+		Symbol:  subscription.symbolID,
+		Entries: append(subscription.book.Bids(), subscription.book.Asks()...),
+	}
+	select {
+	case subscription.updates <- update:
+	default:
+	}
+}
+
+// checkRptSeq reports every RptSeq (83) of an incremental refresh that does
+// not follow the last one of the subscription. The caller must hold c.mu.
+func (c *Client) checkRptSeq(mdReqID string, subscription *mdSubscription, msg *ResponseMessage) {
+	for _, seq := range rptSeqs(msg) {
+		if subscription.rptSeq > 0 && seq != subscription.rptSeq+1 {
+			c.reportError(&MarketDataGapError{
+				MDReqID:  mdReqID,
+				Symbol:   subscription.symbolID,
+				Expected: subscription.rptSeq + 1,
+				Received: seq,
+			})
+		}
+		if seq > subscription.rptSeq {
+			subscription.rptSeq = seq
+		}
+	}
+}
+
+// rptSeqs returns the RptSeq (83) values of msg in wire order, whether sent
+// once for the message or for each entry.
+func rptSeqs(msg *ResponseMessage) []int {
+	var seqs []int
+	for _, field := range msg.Fields() {
+		if field.Tag != 83 {
+			continue
+		}
+		if seq, err := strconv.Atoi(field.Value); err == nil {
+			seqs = append(seqs, seq)
+		}
+	}
+	return seqs
+}
@@ -0,0 +1,277 @@
+package ctrader
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Security is the trading metadata of a symbol.
+type Security struct {
+	SymbolID   string
+	SymbolName string
+	Digits     int
+	TickSize   float64 // MinPriceIncrement (969)
+	MinQty     float64 // MinTradeVol (562)
+	
+	// ContractSize is the number of units per lot, from ContractMultiplier
+	// (231); zero when the server does not send it.
+	ContractSize float64
+	
+	// QtyStep is the smallest quantity increment, taken from RoundLot (561);
+	// an integral step means quantities are sent as whole units.
+	QtyStep float64
+}
+
+// SetSecurity records metadata for a symbol so orders for it are formatted
+// accordingly.
+func (c *Client) SetSecurity(security Security) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	if c.securities == nil {
+		c.securities = make(map[string]Security)
+	}
+	c.securities[security.SymbolID] = security
+	c.symbols.record(security)
+}
+
+// symbolCache maps symbol names to numeric IDs and back. The clients of a
+// SessionManager share one.
+type symbolCache struct {
+	mu    sync.RWMutex
+	ids   map[string]string
+	names map[string]string
+}
+
+func newSymbolCache() *symbolCache {
+	return &symbolCache{
+		ids:   make(map[string]string),
+		names: make(map[string]string),
+	}
+}
+
+// record adds the name and ID of security.
+func (sc *symbolCache) record(security Security) {
+	if security.SymbolID == "" || security.SymbolName == "" {
+		return
+	}
+	
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.ids[security.SymbolName] = security.SymbolID
+	sc.names[security.SymbolID] = security.SymbolName
+}
+
+// merge adds every symbol known to other.
+func (sc *symbolCache) merge(other *symbolCache) {
+	if other == sc {
+		return
+	}
+	
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+	
+	for name, id := range other.ids {
+		sc.record(Security{SymbolID: id, SymbolName: name})
+	}
+}
+
+func (sc *symbolCache) id(name string) (string, bool) {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	id, exists := sc.ids[name]
+	return id, exists
+}
+
+func (sc *symbolCache) name(id string) (string, bool) {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	name, exists := sc.names[id]
+	return name, exists
+}
+
+// trackSymbols records the names and IDs of the securities in a security list
+// (35=y) received from the server once all of its pages have arrived. Pages
+// are buffered per SecurityReqID (320) until then; a rejected list is dropped.
+func (c *Client) trackSymbols(msg *ResponseMessage) {
+	reqID := msg.first(320)
+	
+	c.mu.Lock()
+	pages := append(c.securityPages[reqID], ParseSecurityList(msg)...)
+	rejected := msg.first(560) != "" && msg.first(560) != "0"
+	complete := rejected || securityListComplete(msg, len(pages))
+	if complete {
+		delete(c.securityPages, reqID)
+	} else {
+		if c.securityPages == nil {
+			c.securityPages = make(map[string][]Security)
+		}
+		c.securityPages[reqID] = pages
+	}
+	c.mu.Unlock()
+	
+	if !complete || rejected {
+		return
+	}
+	for _, security := range pages {
+		c.symbols.record(security)
+	}
+}
+
+// securityListComplete reports whether page ends its security list, given
+// that received securities have arrived so far: it is flagged LastFragment
+// (893=Y), TotNoRelatedSym (393) securities have arrived or the list is not
+// paginated.
+func securityListComplete(page *ResponseMessage, received int) bool {
+	if lastFragment := page.first(893); lastFragment != "" {
+		return lastFragment == "Y"
+	}
+	total, err := strconv.Atoi(page.first(393))
+	if err != nil {
+		return true // Not paginated
+	}
+	return received >= total
+}
+
+// ResolveSymbolID returns the numeric symbol ID of name, e.g. "1" for
+// "EURUSD", as learned from security lists received so far.
+func (c *Client) ResolveSymbolID(name string) (string, bool) {
+	return c.symbols.id(name)
+}
+
+// ResolveSymbolName returns the name of the symbol with numeric ID id, as
+// learned from security lists received so far.
+func (c *Client) ResolveSymbolName(id string) (string, bool) {
+	return c.symbols.name(id)
+}
+
+// RequestAllSymbols sends a request for the full security list without
+// waiting for it. Once its last page arrives, ResolveSymbolID and
+// ResolveSymbolName know every symbol; use RequestSecurityList to wait for it.
+func (c *Client) RequestAllSymbols() error {
+	request := NewSecurityListRequest(c.config)
+	request.SecurityReqID = c.nextRequestID("SEC")
+	request.SecurityListRequestType = "4" // All securities
+	return c.Send(request)
+}
+
+// Security returns the metadata recorded for symbolID.
+func (c *Client) Security(symbolID string) (Security, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	
+	security, exists := c.securities[symbolID]
+	return security, exists
+}
+
+// maxDecimals bounds the precision of formatted quantities and prices so
+// float noise such as 0.30000000000000004 is not sent.
+const maxDecimals = 8
+
+// formatQuantity formats qty for tag 38 with the precision of step: whole
+// units for an integral step, otherwise the step's decimals. Without a step
+// at least two decimals are used.
+func formatQuantity(qty, step float64) string {
+	if step <= 0 {
+		return formatDecimal(qty, 2)
+	}
+	
+	decimals := 0
+	stepString := strconv.FormatFloat(step, 'f', -1, 64)
+	if dot := strings.IndexByte(stepString, '.'); dot != -1 {
+		decimals = len(stepString) - dot - 1
+	}
+	return strconv.FormatFloat(qty, 'f', decimals, 64)
+}
+
+// formatPrice formats a price for tags 44 and 99 with at least five decimals.
+func formatPrice(price float64) string {
+	return formatDecimal(price, 5)
+}
+
+// formatDecimal formats value in plain decimal notation, never exponential,
+// with at least minDecimals and at most maxDecimals decimals, so tiny crypto
+// quantities like 1e-5 are sent as 0.00001 rather than rounded to zero.
+func formatDecimal(value float64, minDecimals int) string {
+	formatted := strconv.FormatFloat(value, 'f', maxDecimals, 64)
+	formatted = strings.TrimRight(formatted, "0")
+	if decimals := len(formatted) - strings.IndexByte(formatted, '.') - 1; decimals < minDecimals {
+		formatted += strings.Repeat("0", minDecimals-decimals)
+	}
+	return formatted
+}
+
+// ParseSecurityList returns every security of the NoRelatedSym (146) group of
+// a security list (35=y), in wire order. Metadata the server does not send is
+// left zero.
+func ParseSecurityList(msg *ResponseMessage) []Security {
+	var securities []Security
+	
+	for i, field := range msg.Fields() {
+		if field.Tag != 146 {
+			continue
+		}
+		for _, instance := range msg.groupInstances(i) {
+			var security Security
+			for _, member := range instance {
+				switch member.Tag {
+				case 55:
+					security.SymbolID = member.Value
+				case 1007:
+					security.SymbolName = member.Value
+				case 1008:
+					security.Digits, _ = strconv.Atoi(member.Value)
+				case 969:
+					security.TickSize, _ = strconv.ParseFloat(member.Value, 64)
+				case 561:
+					security.QtyStep, _ = strconv.ParseFloat(member.Value, 64)
+				case 562:
+					security.MinQty, _ = strconv.ParseFloat(member.Value, 64)
+				case 231:
+					security.ContractSize, _ = strconv.ParseFloat(member.Value, 64)
+				}
+			}
+			securities = append(securities, security)
+		}
+		break
+	}
+	
+	return securities
+}
+
+// RequestSecurityList requests the full security list and collects its pages
+// until TotNoRelatedSym (393, formerly TotalNumSecurities) entries or the
+// LastFragment (893) page have arrived. The securities are recorded with
+// SetSecurity only once the list is complete.
+func (c *Client) RequestSecurityList(ctx context.Context) ([]Security, error) {
+	request := NewSecurityListRequest(c.config)
+	request.SecurityReqID = c.nextRequestID("SEC")
+	request.SecurityListRequestType = "4" // All securities
+	
+	var securities []Security
+	err := c.sendAndCollect(ctx, request, func(msg *ResponseMessage) bool {
+		return msg.GetMessageType() == "y" && msg.first(320) == request.SecurityReqID
+	}, func(msg *ResponseMessage) (bool, error) {
+		if result := msg.first(560); result != "" && result != "0" {
+			return true, fmt.Errorf("%w: security request result %s", ErrRequestRejected, result)
+		}
+		
+		securities = append(securities, ParseSecurityList(msg)...)
+		return securityListComplete(msg, len(securities)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	for _, security := range securities {
+		if existing, exists := c.Security(security.SymbolID); exists && security.QtyStep == 0 {
+			security.QtyStep = existing.QtyStep
+		}
+		c.SetSecurity(security)
+	}
+	
+	return securities, nil
+}
@@ -0,0 +1,499 @@
+// Package testserver provides an in-memory FIX acceptor for testing clients
+// of the ctrader package without a live cTrader server. It listens on a local
+// TCP or TLS socket, answers logons, test requests and logouts like cServer
+// and can be scripted to send canned responses, drop the connection or
+// withhold heartbeats, so session behavior such as reconnects, heartbeats and
+// resends can be exercised deterministically.
+//
+//	server, err := testserver.New()
+//	...
+//	defer server.Close()
+//	client := ctrader.NewClient(server.Host(), server.Port(), config)
+//	client.Connect()
+//	session, err := server.Accept(time.Second)
+//	session.Handle("V", func(s *testserver.Session, msg *testserver.Message) {
+//		s.Send("W", "262="+msg.Get(262), "55=1", "268=1", "269=0", "270=1.1")
+//	})
+package testserver
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrTimeout is returned by Accept and Session.Next when nothing arrives in time.
+var ErrTimeout = errors.New("testserver: timed out")
+
+// ErrClosed is returned by Session.Next once the connection is closed and all
+// received messages have been read.
+var ErrClosed = errors.New("testserver: connection closed")
+
+// Handler answers a message received from the client.
+type Handler func(s *Session, msg *Message)
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithTLS makes the server accept TLS connections using config.
+func WithTLS(config *tls.Config) Option {
+	return func(s *Server) {
+		s.tlsConfig = config
+	}
+}
+
+// WithDelimiter sets the field delimiter; the default is SOH.
+func WithDelimiter(delimiter string) Option {
+	return func(s *Server) {
+		s.delimiter = delimiter
+	}
+}
+
+// WithHeartbeatInterval makes sessions send a Heartbeat (35=0) whenever they
+// have been idle for d after the logon, instead of using the HeartBtInt (108)
+// of the client's logon.
+func WithHeartbeatInterval(d time.Duration) Option {
+	return func(s *Server) {
+		s.heartbeatInterval = d
+	}
+}
+
+// WithHandler installs h for messages of msgType on every session, replacing
+// the default answer, if any.
+func WithHandler(msgType string, h Handler) Option {
+	return func(s *Server) {
+		s.handlers[msgType] = h
+	}
+}
+
+// Server is a scripted FIX acceptor on a local socket.
+type Server struct {
+	ln                net.Listener
+	tlsConfig         *tls.Config
+	delimiter         string
+	heartbeatInterval time.Duration
+	handlers          map[string]Handler
+	sessions          chan *Session
+	done              chan struct{}
+	closeOnce         sync.Once
+}
+
+// New starts a server listening on a free port of 127.0.0.1. By default every
+// session answers a Logon (35=A) with a Logon, a TestRequest (35=1) with a
+// Heartbeat and a Logout (35=5) with a Logout before closing the connection.
+func New(opts ...Option) (*Server, error) {
+	s := &Server{
+		delimiter: "\x01",
+		handlers: map[string]Handler{
+			"A": answerLogon,
+			"1": answerTestRequest,
+			"5": answerLogout,
+		},
+		sessions: make(chan *Session, 16),
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("testserver: failed to listen: %w", err)
+	}
+	if s.tlsConfig != nil {
+		ln = tls.NewListener(ln, s.tlsConfig)
+	}
+	s.ln = ln
+	
+	go s.acceptLoop()
+	return s, nil
+}
+
+func (s *Server) acceptLoop() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		session := newSession(s, conn)
+		select {
+		case s.sessions <- session:
+		case <-s.done:
+			conn.Close()
+			return
+		}
+	}
+}
+
+// Host returns the host clients connect to.
+func (s *Server) Host() string {
+	return "127.0.0.1"
+}
+
+// Port returns the port the server listens on.
+func (s *Server) Port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+// Addr returns the "host:port" address of the server.
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Accept returns the next client connection, waiting up to timeout.
+func (s *Server) Accept(timeout time.Duration) (*Session, error) {
+	select {
+	case session := <-s.sessions:
+		return session, nil
+	case <-time.After(timeout):
+		return nil, ErrTimeout
+	case <-s.done:
+		return nil, ErrClosed
+	}
+}
+
+// Close stops listening. Sessions already accepted stay open until they are
+// dropped or the client disconnects.
+func (s *Server) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		err = s.ln.Close()
+	})
+	return err
+}
+
+// Session is one client connection.
+type Session struct {
+	conn      net.Conn
+	delimiter string
+	received  chan *Message
+	
+	mu                 sync.Mutex
+	handlers           map[string]Handler
+	seqNum             int
+	senderCompID       string
+	targetCompID       string
+	senderSubID        string
+	targetSubID        string
+	heartbeatInterval  time.Duration
+	withholdHeartbeats bool
+	heartbeating       bool
+	lastSent           time.Time
+	closed             chan struct{}
+	closeOnce          sync.Once
+}
+
+func newSession(server *Server, conn net.Conn) *Session {
+	s := &Session{
+		conn:              conn,
+		delimiter:         server.delimiter,
+		received:          make(chan *Message, 1024),
+		handlers:          make(map[string]Handler, len(server.handlers)),
+		senderCompID:      "cServer",
+		heartbeatInterval: server.heartbeatInterval,
+		closed:            make(chan struct{}),
+	}
+	for msgType, h := range server.handlers {
+		s.handlers[msgType] = h
+	}
+	go s.readLoop()
+	return s
+}
+
+// Handle installs h for messages of msgType on this session, replacing the
+// current handler. A nil h removes it.
+func (s *Session) Handle(msgType string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	if h == nil {
+		delete(s.handlers, msgType)
+		return
+	}
+	s.handlers[msgType] = h
+}
+
+// Next returns the next message sent by the client, waiting up to timeout.
+// Every message is returned, including those answered by a handler.
+func (s *Session) Next(timeout time.Duration) (*Message, error) {
+	select {
+	case msg, ok := <-s.received:
+		if !ok {
+			return nil, ErrClosed
+		}
+		return msg, nil
+	case <-time.After(timeout):
+		return nil, ErrTimeout
+	}
+}
+
+// Expect returns the next message sent by the client and fails unless it is
+// of msgType.
+func (s *Session) Expect(msgType string, timeout time.Duration) (*Message, error) {
+	msg, err := s.Next(timeout)
+	if err != nil {
+		return nil, fmt.Errorf("waiting for MsgType %s: %w", msgType, err)
+	}
+	if msg.MsgType() != msgType {
+		return msg, fmt.Errorf("testserver: expected MsgType %s, got %s", msgType, msg.MsgType())
+	}
+	return msg, nil
+}
+
+// Send sends a message of msgType with the given "tag=value" body fields,
+// adding the header, the next MsgSeqNum and the trailer.
+func (s *Session) Send(msgType string, fields ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	s.seqNum++
+	return s.write(s.build(msgType, s.seqNum, fields))
+}
+
+// SendWithSeqNum is like Send but uses seqNum as MsgSeqNum without advancing
+// the session's sequence, e.g. to replay a message as a possible duplicate.
+func (s *Session) SendWithSeqNum(seqNum int, msgType string, fields ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	return s.write(s.build(msgType, seqNum, fields))
+}
+
+// SendRaw writes raw to the connection as is, e.g. to send a corrupted frame.
+func (s *Session) SendRaw(raw string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	return s.write(raw)
+}
+
+// SetSeqNum sets the MsgSeqNum of the next message sent to seqNum.
+func (s *Session) SetSeqNum(seqNum int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seqNum = seqNum - 1
+}
+
+// WithholdHeartbeats stops the session from sending heartbeats and from
+// answering test requests while enabled, as a hung server would.
+func (s *Session) WithholdHeartbeats(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.withholdHeartbeats = enabled
+}
+
+// Drop closes the connection without a logout, as a network failure would.
+func (s *Session) Drop() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		err = s.conn.Close()
+	})
+	return err
+}
+
+// Closed returns a channel that is closed when the connection ends.
+func (s *Session) Closed() <-chan struct{} {
+	return s.closed
+}
+
+// write sends raw; the caller must hold s.mu.
+func (s *Session) write(raw string) error {
+	s.lastSent = time.Now()
+	if _, err := s.conn.Write([]byte(raw)); err != nil {
+		return fmt.Errorf("testserver: failed to write: %w", err)
+	}
+	return nil
+}
+
+// build frames a message; the caller must hold s.mu.
+func (s *Session) build(msgType string, seqNum int, fields []string) string {
+	header := []string{
+		"35=" + msgType,
+		"49=" + s.senderCompID,
+	}
+	if s.senderSubID != "" {
+		header = append(header, "50="+s.senderSubID)
+	}
+	if s.targetCompID != "" {
+		header = append(header, "56="+s.targetCompID)
+	}
+	if s.targetSubID != "" {
+		header = append(header, "57="+s.targetSubID)
+	}
+	header = append(header,
+		"34="+strconv.Itoa(seqNum),
+		"52="+time.Now().UTC().Format("20060102-15:04:05.000"),
+	)
+	
+	body := strings.Join(append(header, fields...), s.delimiter) + s.delimiter
+	message := fmt.Sprintf("8=FIX.4.4%s9=%d%s%s", s.delimiter, len(body), s.delimiter, body)
+	checksum := 0
+	for _, b := range []byte(message) {
+		checksum += int(b)
+	}
+	return fmt.Sprintf("%s10=%03d%s", message, checksum%256, s.delimiter)
+}
+
+func (s *Session) readLoop() {
+	defer close(s.received)
+	defer s.Drop()
+	
+	var buffer []byte
+	chunk := make([]byte, 4096)
+	trailer := []byte(s.delimiter + "10=")
+	
+	for {
+		n, err := s.conn.Read(chunk)
+		if err != nil {
+			return
+		}
+		buffer = append(buffer, chunk[:n]...)
+		
+		for {
+			start := bytes.Index(buffer, trailer)
+			if start == -1 {
+				break
+			}
+			end := bytes.Index(buffer[start+len(trailer):], []byte(s.delimiter))
+			if end == -1 {
+				break
+			}
+			end += start + len(trailer) + len(s.delimiter)
+			
+			msg := parseMessage(string(buffer[:end]), s.delimiter)
+			buffer = buffer[end:]
+			
+			select {
+			case s.received <- msg:
+			default: // Nobody is reading; keep answering.
+			}
+			
+			s.mu.Lock()
+			h := s.handlers[msg.MsgType()]
+			s.mu.Unlock()
+			if h != nil {
+				h(s, msg)
+			}
+		}
+	}
+}
+
+// heartbeatLoop sends heartbeats while the session is idle.
+func (s *Session) heartbeatLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval / 4)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-s.closed:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			due := !s.withholdHeartbeats && time.Since(s.lastSent) >= interval
+			s.mu.Unlock()
+			if due {
+				s.Send("0")
+			}
+		}
+	}
+}
+
+// answerLogon replies to a logon, taking the CompIDs from it and restarting
+// the sequence if ResetSeqNumFlag (141) is set.
+func answerLogon(s *Session, msg *Message) {
+	s.mu.Lock()
+	s.targetCompID = msg.Get(49)
+	s.targetSubID = msg.Get(50)
+	if compID := msg.Get(56); compID != "" {
+		s.senderCompID = compID
+	}
+	s.senderSubID = msg.Get(57)
+	if msg.Get(141) == "Y" {
+		s.seqNum = 0
+	}
+	interval := s.heartbeatInterval
+	if interval == 0 {
+		seconds, _ := strconv.Atoi(msg.Get(108))
+		interval = time.Duration(seconds) * time.Second
+	}
+	startHeartbeats := interval > 0 && !s.heartbeating
+	s.heartbeating = s.heartbeating || startHeartbeats
+	s.mu.Unlock()
+	
+	s.Send("A", "98=0", "108="+msg.Get(108))
+	if startHeartbeats {
+		go s.heartbeatLoop(interval)
+	}
+}
+
+// answerTestRequest replies to a test request with a heartbeat echoing its
+// TestReqID (112) unless heartbeats are withheld.
+func answerTestRequest(s *Session, msg *Message) {
+	s.mu.Lock()
+	withhold := s.withholdHeartbeats
+	s.mu.Unlock()
+	if !withhold {
+		s.Send("0", "112="+msg.Get(112))
+	}
+}
+
+// answerLogout confirms a logout and closes the connection.
+func answerLogout(s *Session, msg *Message) {
+	s.Send("5")
+	s.Drop()
+}
+
+// Field is a single tag=value pair of a Message.
+type Field struct {
+	Tag   int
+	Value string
+}
+
+// Message is a message received from the client.
+type Message struct {
+	Raw    string
+	Fields []Field
+}
+
+func parseMessage(raw, delimiter string) *Message {
+	msg := &Message{Raw: raw}
+	for _, part := range strings.Split(raw, delimiter) {
+		tag, value, found := strings.Cut(part, "=")
+		if !found {
+			continue
+		}
+		if n, err := strconv.Atoi(tag); err == nil {
+			msg.Fields = append(msg.Fields, Field{Tag: n, Value: value})
+		}
+	}
+	return msg
+}
+
+// Get returns the first value of tag, or "" if it is absent.
+func (m *Message) Get(tag int) string {
+	for _, field := range m.Fields {
+		if field.Tag == tag {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// MsgType returns the MsgType (35) of the message.
+func (m *Message) MsgType() string {
+	return m.Get(35)
+}
+
+// SeqNum returns the MsgSeqNum (34) of the message.
+func (m *Message) SeqNum() int {
+	seqNum, _ := strconv.Atoi(m.Get(34))
+	return seqNum
+}
@@ -0,0 +1,113 @@
+package ctrader
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// reconcileTimeout bounds the re-logon and mass status exchange performed
+// after a reconnect.
+const reconcileTimeout = 30 * time.Second
+
+// workingStatuses are the OrdStatus (39) values of orders still live on the
+// server.
+var workingStatuses = map[string]bool{
+	"0": true, // New
+	"1": true, // Partially filled
+	"5": true, // Replaced
+	"6": true, // Pending cancel
+	"A": true, // Pending new
+	"E": true, // Pending replace
+}
+
+// WithReconcileOnReconnect makes the client re-sync its working orders with an
+// OrderMassStatusRequest after every reconnect, since fills and cancels that
+// happened while it was offline are otherwise never seen.
+func WithReconcileOnReconnect(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.reconcileOnReconnect = enabled
+	}
+}
+
+// trackOrder updates the working-order view from an execution report.
+func (c *Client) trackOrder(msg *ResponseMessage) {
+	report := newExecutionReport(msg)
+	if report.ClOrdID == "" {
+		return
+	}
+	
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	if !report.IsWorking() {
+		delete(c.workingOrders, report.ClOrdID)
+		return
+	}
+	if c.workingOrders == nil {
+		c.workingOrders = make(map[string]*ExecutionReport)
+	}
+	c.workingOrders[report.ClOrdID] = report
+}
+
+// WorkingOrders returns the latest execution report of every order still
+// working, ordered by ClOrdID.
+func (c *Client) WorkingOrders() []*ExecutionReport {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	
+	orders := make([]*ExecutionReport, 0, len(c.workingOrders))
+	for _, report := range c.workingOrders {
+		orders = append(orders, report)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].ClOrdID < orders[j].ClOrdID
+	})
+	return orders
+}
+
+// ReconcileOrders requests the status of all orders and replaces the
+// working-order view with the server's answer. It finishes on the report
+// flagged LastRptRequested (912=Y) or once TotNumReports (911) reports have
+// arrived, when the server sends that count.
+func (c *Client) ReconcileOrders(ctx context.Context) error {
+	request := NewOrderMassStatusRequest(c.config)
+	request.MassStatusReqID = c.nextRequestID("MASS")
+	
+	orders := make(map[string]*ExecutionReport)
+	received := 0
+	err := c.sendAndCollect(ctx, request, func(msg *ResponseMessage) bool {
+		return msg.GetMessageType() == "8" && msg.first(584) == request.MassStatusReqID
+	}, func(msg *ResponseMessage) (bool, error) {
+		report := newExecutionReport(msg)
+		received++
+		if report.ClOrdID != "" && report.IsWorking() {
+			orders[report.ClOrdID] = report
+		}
+		return report.LastRptRequested || (report.TotNumReports > 0 && received >= report.TotNumReports), nil
+	})
+	if err != nil {
+		return err
+	}
+	
+	c.mu.Lock()
+	c.workingOrders = orders
+	c.mu.Unlock()
+	return nil
+}
+
+// logonAndReconcile re-sends logon, waits for the server to accept it and
+// then reconciles the working orders.
+func (c *Client) logonAndReconcile(logon *LogonRequest) error {
+	ctx, cancel := context.WithTimeout(context.Background(), reconcileTimeout)
+	defer cancel()
+	
+	_, err := c.SendAndWait(ctx, logon, func(msg *ResponseMessage) bool {
+		return msg.GetMessageType() == "A"
+	})
+	if err != nil {
+		return err
+	}
+	
+	return c.ReconcileOrders(ctx)
+}
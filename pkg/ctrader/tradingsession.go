@@ -0,0 +1,149 @@
+package ctrader
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Trading session statuses (TradSesStatus, 340).
+const (
+	TradSesStatusHalted   = "1"
+	TradSesStatusOpen     = "2"
+	TradSesStatusClosed   = "3"
+	TradSesStatusPreOpen  = "4"
+	TradSesStatusPreClose = "5"
+)
+
+// TradingSession is a parsed trading session status (35=h). Symbol is empty
+// when the status applies to the whole session rather than one symbol.
+type TradingSession struct {
+	TradSesReqID     string
+	TradingSessionID string
+	Symbol           string
+	Status           string
+	StartTime        time.Time
+	OpenTime         time.Time
+	CloseTime        time.Time
+	Text             string
+}
+
+func newTradingSession(msg *ResponseMessage) TradingSession {
+	session := TradingSession{
+		TradSesReqID:     msg.first(335),
+		TradingSessionID: msg.first(336),
+		Symbol:           msg.first(55),
+		Status:           msg.first(340),
+		Text:             msg.first(58),
+	}
+	session.StartTime = parseUTCTimestamp(msg.first(341))
+	session.OpenTime = parseUTCTimestamp(msg.first(342))
+	session.CloseTime = parseUTCTimestamp(msg.first(344))
+	return session
+}
+
+// parseUTCTimestamp parses a FIX UTCTimestamp with or without milliseconds,
+// returning the zero time for an empty or malformed value.
+func parseUTCTimestamp(value string) time.Time {
+	for _, layout := range []string{"20060102-15:04:05.000", "20060102-15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// IsOpen reports whether the session accepts orders at now. An open status
+// is trusted unless a known schedule places now outside of it.
+func (ts TradingSession) IsOpen(now time.Time) bool {
+	if ts.Status != TradSesStatusOpen {
+		return false
+	}
+	if !ts.OpenTime.IsZero() && now.Before(ts.OpenTime) {
+		return false
+	}
+	if !ts.CloseTime.IsZero() && !now.Before(ts.CloseTime) {
+		return false
+	}
+	return true
+}
+
+type TradingSessionStatusRequest struct {
+	*RequestMessage
+	TradSesReqID            string
+	TradingSessionID        string
+	SubscriptionRequestType string
+}
+
+func NewTradingSessionStatusRequest(config *Config) *TradingSessionStatusRequest {
+	tsr := &TradingSessionStatusRequest{
+		RequestMessage:          NewRequestMessage("g", config),
+		SubscriptionRequestType: "0",
+	}
+	tsr.body = tsr.GetBody
+	return tsr
+}
+
+func (tsr *TradingSessionStatusRequest) GetBody() string {
+	var fields []string
+	fields = append(fields, fmt.Sprintf("335=%s", tsr.TradSesReqID))
+	if tsr.TradingSessionID != "" {
+		fields = append(fields, fmt.Sprintf("336=%s", tsr.TradingSessionID))
+	}
+	fields = append(fields, fmt.Sprintf("263=%s", tsr.SubscriptionRequestType))
+	return strings.Join(fields, tsr.delimiter)
+}
+
+// handleTradingSessionStatus caches a trading session status by symbol.
+func (c *Client) handleTradingSessionStatus(msg *ResponseMessage) {
+	session := newTradingSession(msg)
+	
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	if c.tradingSessions == nil {
+		c.tradingSessions = make(map[string]TradingSession)
+	}
+	c.tradingSessions[session.Symbol] = session
+}
+
+// RequestTradingSessionStatus requests the current trading session status
+// and returns the server's reply, which is also cached for IsSymbolTradable.
+func (c *Client) RequestTradingSessionStatus(ctx context.Context) (TradingSession, error) {
+	request := NewTradingSessionStatusRequest(c.config)
+	request.TradSesReqID = c.nextRequestID("TSS")
+	
+	msg, err := c.SendAndWait(ctx, request, func(msg *ResponseMessage) bool {
+		return msg.GetMessageType() == "h" && msg.first(335) == request.TradSesReqID
+	})
+	if err != nil {
+		return TradingSession{}, err
+	}
+	
+	return newTradingSession(msg), nil
+}
+
+// TradingSession returns the cached session status for symbol, falling back
+// to the status of the whole session.
+func (c *Client) TradingSession(symbol string) (TradingSession, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	
+	if session, exists := c.tradingSessions[symbol]; exists {
+		return session, true
+	}
+	session, exists := c.tradingSessions[""]
+	return session, exists
+}
+
+// IsSymbolTradable reports whether the cached session status allows trading
+// symbol now. Without any status received it returns true, since cServer
+// only sends session status on request.
+func (c *Client) IsSymbolTradable(symbol string) bool {
+	session, exists := c.TradingSession(symbol)
+	if !exists {
+		return true
+	}
+	return session.IsOpen(time.Now().UTC())
+}
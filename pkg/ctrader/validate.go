@@ -0,0 +1,131 @@
+package ctrader
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// defaultMaxIDLength and defaultIDPattern are cServer's limits on
+// client-assigned IDs, used when the Config does not set its own.
+const defaultMaxIDLength = 50
+
+var defaultIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]+$`)
+
+// validateID checks a client-assigned ID against the limits of config.
+func validateID(config *Config, name, id string) error {
+	maxLength, pattern := defaultMaxIDLength, defaultIDPattern
+	if config != nil && config.MaxIDLength > 0 {
+		maxLength = config.MaxIDLength
+	}
+	if config != nil && config.IDPattern != nil {
+		pattern = config.IDPattern
+	}
+	
+	if id == "" {
+		return fmt.Errorf("%s is required", name)
+	}
+	if len(id) > maxLength {
+		return fmt.Errorf("%s %q exceeds %d characters", name, id, maxLength)
+	}
+	if !pattern.MatchString(id) {
+		return fmt.Errorf("%s %q contains characters outside %s", name, id, pattern)
+	}
+	return nil
+}
+
+// validateIDs validates each name/ID pair in turn.
+func validateIDs(config *Config, pairs ...string) error {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if err := validateID(config, pairs[i], pairs[i+1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks that the fields every logon needs are set, so a missing
+// credential is reported by name instead of as an opaque logon reject.
+func (c *Config) Validate() error {
+	if c == nil {
+		return fmt.Errorf("%w: config is nil", ErrInvalidConfig)
+	}
+	
+	required := []struct{ name, value string }{
+		{"BeginString", c.BeginString},
+		{"SenderCompID", c.SenderCompID},
+		{"TargetCompID", c.TargetCompID},
+		{"Username", c.Username},
+		{"Password", c.Password},
+	}
+	for _, field := range required {
+		if strings.TrimSpace(field.value) == "" {
+			return fmt.Errorf("%w: %s is empty", ErrInvalidConfig, field.name)
+		}
+	}
+	if c.HeartBeat <= 0 {
+		return fmt.Errorf("%w: HeartBeat must be positive, got %d", ErrInvalidConfig, c.HeartBeat)
+	}
+	return nil
+}
+
+func (ocr *OrderCancelRequest) Validate() error {
+	return validateIDs(ocr.config, "ClOrdID", ocr.ClOrdID, "OrigClOrdID", ocr.OrigClOrdID)
+}
+
+func (ocrr *OrderCancelReplaceRequest) Validate() error {
+	return validateIDs(ocrr.config, "ClOrdID", ocrr.ClOrdID, "OrigClOrdID", ocrr.OrigClOrdID)
+}
+
+func (osr *OrderStatusRequest) Validate() error {
+	return validateIDs(osr.config, "ClOrdID", osr.ClOrdID)
+}
+
+func (omsr *OrderMassStatusRequest) Validate() error {
+	return validateIDs(omsr.config, "MassStatusReqID", omsr.MassStatusReqID)
+}
+
+func (mdr *MarketDataRequest) Validate() error {
+	return validateIDs(mdr.config, "MDReqID", mdr.MDReqID)
+}
+
+func (slr *SecurityListRequest) Validate() error {
+	return validateIDs(slr.config, "SecurityReqID", slr.SecurityReqID)
+}
+
+func (rfp *RequestForPositions) Validate() error {
+	return validateIDs(rfp.config, "PosReqID", rfp.PosReqID)
+}
+
+func (tsr *TradingSessionStatusRequest) Validate() error {
+	return validateIDs(tsr.config, "TradSesReqID", tsr.TradSesReqID)
+}
+
+func (tcrr *TradeCaptureReportRequest) Validate() error {
+	if err := validateID(tcrr.config, "TradeRequestID", tcrr.TradeRequestID); err != nil {
+		return err
+	}
+	if !tcrr.StartTime.IsZero() && !tcrr.EndTime.IsZero() && tcrr.EndTime.Before(tcrr.StartTime) {
+		return fmt.Errorf("trade capture end time %s is before start time %s", tcrr.EndTime, tcrr.StartTime)
+	}
+	return nil
+}
+
+// sessionTags are generated for every request and cannot be set on a
+// GenericMessage.
+var sessionTags = map[int]bool{8: true, 9: true, 10: true, 34: true, 35: true, 49: true, 50: true, 52: true, 56: true, 57: true}
+
+func (gm *GenericMessage) Validate() error {
+	if gm.messageType == "" {
+		return fmt.Errorf("generic message requires a MsgType")
+	}
+	for _, field := range gm.fields {
+		if sessionTags[field.Tag] {
+			return fmt.Errorf("tag %d is set by the session and cannot be set on a generic message", field.Tag)
+		}
+		if strings.Contains(field.Value, gm.delimiter) {
+			return fmt.Errorf("value of tag %d contains the field delimiter", field.Tag)
+		}
+	}
+	return nil
+}